@@ -25,13 +25,14 @@ func createUserEndpoint(svc admin.Service) endpoint.Endpoint {
 			return nil, err
 		}
 		user := admin.User{
-			Name:     req.Name,
-			Username: req.Username,
-			Password: req.Password,
-			Email:    req.Email,
-			Phone:    req.Phone,
-			Role:     req.Role,
-			Status:   req.Status,
+			Name:         req.Name,
+			Username:     req.Username,
+			Password:     req.Password,
+			Email:        req.Email,
+			Phone:        req.Phone,
+			Role:         req.Role,
+			Status:       req.Status,
+			EnterpriseID: req.EnterpriseID,
 		}
 		if err := svc.CreateUser(ctx, user); err != nil {
 			return nil, err
@@ -47,14 +48,15 @@ func updateUserEndpoint(svc admin.Service) endpoint.Endpoint {
 			return nil, err
 		}
 		user := admin.User{
-			ID:       req.ID,
-			Name:     req.Name,
-			Username: req.Username,
-			Password: req.Password,
-			Email:    req.Email,
-			Phone:    req.Phone,
-			Role:     req.Role,
-			Status:   req.Status,
+			ID:           req.ID,
+			Name:         req.Name,
+			Username:     req.Username,
+			Password:     req.Password,
+			Email:        req.Email,
+			Phone:        req.Phone,
+			Role:         req.Role,
+			Status:       req.Status,
+			EnterpriseID: req.EnterpriseID,
 		}
 		if err := svc.UpdateUser(ctx, user); err != nil {
 			return nil, err
@@ -316,6 +318,214 @@ func loginEndpoint(svc admin.Service) endpoint.Endpoint {
 	}
 }
 
+func registerEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(registerRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		registration, err := svc.Register(ctx, req.user(), req.Channel)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(registration), nil
+	}
+}
+
+func verifyRegistrationEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(verifyRegistrationRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.VerifyRegistration(ctx, req.Contact, req.Code); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func resendVerificationEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(resendVerificationRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.ResendVerification(ctx, req.Contact, req.Channel); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func forgotPasswordEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(forgotPasswordRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.ForgotPassword(ctx, req.Contact, req.Channel); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func resetPasswordEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(resetPasswordRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.ResetPassword(ctx, req.Token, req.Password); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func loginMFAEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(mfaLoginRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		token, err := svc.LoginMFA(ctx, req.MFAToken, req.Code)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(token), nil
+	}
+}
+
+func enrollMFALoginEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(mfaChallengeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		enrollment, err := svc.EnrollMFALogin(ctx, req.MFAToken)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(enrollment), nil
+	}
+}
+
+func enrollMFAEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		enrollment, err := svc.EnrollMFA(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(enrollment), nil
+	}
+}
+
+func confirmMFAEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(mfaCodeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		codes, err := svc.ConfirmMFA(ctx, req.Code)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(map[string][]string{"recovery_codes": codes}), nil
+	}
+}
+
+func disableMFAEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(disableMFARequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.DisableMFA(ctx, req.Code); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func regenerateRecoveryCodesEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(mfaCodeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		codes, err := svc.RegenerateRecoveryCodes(ctx, req.Code)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(map[string][]string{"recovery_codes": codes}), nil
+	}
+}
+
+func getMFAPoliciesEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		policies, err := svc.GetMFAPolicies(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(policies), nil
+	}
+}
+
+func setMFAPolicyEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(mfaPolicyRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.SetMFAPolicy(ctx, req.Role, *req.Enforced); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func refreshTokenEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(refreshTokenRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		token, err := svc.RefreshToken(ctx, req.RefreshToken)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(token), nil
+	}
+}
+
+func logoutEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(logoutRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.Logout(ctx, req.AccessToken); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func logoutAllEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(logoutRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.LogoutAll(ctx, req.AccessToken); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
 func registerEnterpriseEndpoint(svc admin.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(enterpriseRequest)
@@ -368,6 +578,17 @@ func updateEnterpriseInfoEndpoint(svc admin.Service) endpoint.Endpoint {
 
 func getAllEventsEndpoint(svc admin.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(listEventsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if !req.Start.IsZero() {
+			events, err := svc.GetEventByTime(ctx, req.Start, req.End)
+			if err != nil {
+				return nil, err
+			}
+			return common.SuccessRes(events), nil
+		}
 		events, err := svc.GetAllEvents(ctx)
 		if err != nil {
 			return nil, err
@@ -390,7 +611,6 @@ func getEventByIDEndpoint(svc admin.Service) endpoint.Endpoint {
 	}
 }
 
-
 func createEventEndpoint(svc admin.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(createEventRequest)
@@ -436,6 +656,72 @@ func updateEventEndpoint(svc admin.Service) endpoint.Endpoint {
 	}
 }
 
+func submitEventEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(eventStatusRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.SubmitEvent(ctx, req.ID); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func cancelEventEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(eventStatusRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.CancelEvent(ctx, req.ID, req.Reason); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func getEventsByStatusEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(listEventsByStatusRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		events, err := svc.GetEventsByStatus(ctx, req.Status)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(events), nil
+	}
+}
+
+func approveEventEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(eventStatusRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.ApproveEvent(ctx, req.ID); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func rejectEventEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(rejectEventRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.RejectEvent(ctx, req.ID, req.Reason); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
 func getAllVouchersByEventIDEndpoint(svc admin.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(getEventIDRequest)
@@ -472,13 +758,14 @@ func createVoucherEndpoint(svc admin.Service) endpoint.Endpoint {
 		}
 		voucher := admin.Voucher{
 			Code:        req.Code,
-			Qrcode:      req.Qrcode,
+			Qrcode:      req.Code,
 			Images:      req.Images,
 			Value:       req.Value,
 			Description: req.Description,
 			ExpiredTime: req.ExpiredTime,
 			Status:      req.Status,
 			EventID:     req.EventID,
+			Rule:        req.Rule,
 		}
 		if err := svc.CreateVoucher(ctx, voucher); err != nil {
 			return nil, err
@@ -496,13 +783,14 @@ func updateVoucherEndpoint(svc admin.Service) endpoint.Endpoint {
 		voucher := admin.Voucher{
 			ID:          req.ID,
 			Code:        req.Code,
-			Qrcode:      req.Qrcode,
+			Qrcode:      req.Code,
 			Images:      req.Images,
 			Value:       req.Value,
 			Description: req.Description,
 			ExpiredTime: req.ExpiredTime,
 			Status:      req.Status,
 			EventID:     req.EventID,
+			Rule:        req.Rule,
 		}
 		if err := svc.UpdateVoucher(ctx, voucher); err != nil {
 			return nil, err
@@ -522,4 +810,1082 @@ func deleteVoucherEndpoint(svc admin.Service) endpoint.Endpoint {
 		}
 		return common.SuccessRes(nil), nil
 	}
-}
\ No newline at end of file
+}
+
+func getVoucherTemplatesEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getEventIDRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		templates, err := svc.GetVoucherTemplates(ctx, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(templates), nil
+	}
+}
+
+func createVoucherTemplateEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(createVoucherTemplateRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		template := admin.VoucherTemplate{
+			EventID:     req.EventID,
+			Name:        req.Name,
+			Images:      req.Images,
+			Value:       req.Value,
+			Description: req.Description,
+			ExpiredTime: req.ExpiredTime,
+			Quantity:    req.Quantity,
+			Rule:        req.Rule,
+		}
+		id, err := svc.CreateVoucherTemplate(ctx, template)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(map[string]string{"id": id}), nil
+	}
+}
+
+func updateVoucherTemplateEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(updateVoucherTemplateRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.UpdateVoucherTemplateQuantity(ctx, req.ID, req.EventID, *req.Quantity); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func issueVoucherEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(issueVoucherRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		voucher, err := svc.IssueVoucher(ctx, req.TemplateID, req.EventID, req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(voucher), nil
+	}
+}
+
+func generateVouchersEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(generateVouchersRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		batch := admin.VoucherBatch{
+			EventID:     req.EventID,
+			Count:       req.Count,
+			Prefix:      req.Format.Prefix,
+			Length:      req.Format.Length,
+			Charset:     req.Format.Charset,
+			Checksum:    req.Format.Checksum,
+			Images:      req.Images,
+			Value:       req.Value,
+			Description: req.Description,
+			ExpiredTime: req.ExpiredTime,
+			Rule:        req.Rule,
+		}
+		id, err := svc.GenerateVouchers(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(map[string]string{"id": id}), nil
+	}
+}
+
+func getVoucherBatchEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getVoucherBatchRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		batch, err := svc.GetVoucherBatch(ctx, req.ID, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(batch), nil
+	}
+}
+
+func getVoucherQRCodeEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getVoucherQRCodeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		image, err := svc.GetVoucherQRCode(ctx, req.ID, req.EventID, req.Format, req.Size)
+		if err != nil {
+			return nil, err
+		}
+		contentType := "image/png"
+		if req.Format == admin.QRCodeSVG {
+			contentType = "image/svg+xml"
+		}
+		return imageRes{contentType: contentType, body: image}, nil
+	}
+}
+
+func getVoucherPublicKeyEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return common.SuccessRes(voucherKeyRes{
+			Alg:       "Ed25519",
+			PublicKey: svc.VoucherPublicKey(ctx),
+		}), nil
+	}
+}
+
+func redeemVoucherEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(redeemVoucherRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		redeem := admin.RedeemRequest{
+			Code:    req.Code,
+			Payload: req.Qrcode,
+			StoreID: req.StoreID,
+			OwnerID: req.UserID,
+		}
+		if req.Basket != nil {
+			basket := *req.Basket
+			basket.StoreID = req.StoreID
+			redeem.Basket = &basket
+		}
+		voucher, err := svc.RedeemVoucher(ctx, redeem)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(voucher), nil
+	}
+}
+
+func getVoucherRedemptionsEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getVoucherByIDRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		redemptions, err := svc.GetVoucherRedemptions(ctx, req.ID, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(redemptions), nil
+	}
+}
+
+func quoteVoucherEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(quoteVoucherRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		quote, err := svc.QuoteVoucher(ctx, req.ID, req.Basket)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(quote), nil
+	}
+}
+
+func listMyVouchersEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(listMyVouchersRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		filter := admin.WalletFilter{
+			Status:        req.Status,
+			EventID:       req.EventID,
+			ExpiresAfter:  req.ExpiresAfter,
+			ExpiresBefore: req.ExpiresBefore,
+			Cursor:        req.Cursor,
+			Limit:         req.Limit,
+		}
+		page, err := svc.GetMyVouchers(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(page), nil
+	}
+}
+
+func getMyVoucherEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(myVoucherRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		voucher, err := svc.GetMyVoucher(ctx, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(voucher), nil
+	}
+}
+
+func getMyVoucherQRCodeEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(myVoucherQRCodeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		image, err := svc.GetMyVoucherQRCode(ctx, req.ID, req.Format, req.Size)
+		if err != nil {
+			return nil, err
+		}
+		contentType := "image/png"
+		if req.Format == admin.QRCodeSVG {
+			contentType = "image/svg+xml"
+		}
+		return imageRes{contentType: contentType, body: image}, nil
+	}
+}
+
+func getMyVoucherRedemptionsEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(myVoucherRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		redemptions, err := svc.GetMyVoucherRedemptions(ctx, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]myRedemptionRes, 0, len(redemptions))
+		for _, r := range redemptions {
+			res = append(res, myRedemptionRes{
+				CreatedAt: r.CreatedAt,
+				StoreID:   r.StoreID,
+				Outcome:   r.Outcome,
+			})
+		}
+		return common.SuccessRes(res), nil
+	}
+}
+
+func offerVoucherEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(offerVoucherRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		transfer, err := svc.OfferVoucher(ctx, req.VoucherID, req.Recipient)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(transfer), nil
+	}
+}
+
+func acceptTransferEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(transferRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		transfer, err := svc.AcceptTransfer(ctx, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(transfer), nil
+	}
+}
+
+func claimTransferEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(claimTransferRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		transfer, err := svc.ClaimTransfer(ctx, req.Token)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(transfer), nil
+	}
+}
+
+func declineTransferEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(transferRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.DeclineTransfer(ctx, req.ID); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func cancelTransferEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(transferRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.CancelTransfer(ctx, req.ID); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func getMyTransfersEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		transfers, err := svc.GetMyTransfers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(transfers), nil
+	}
+}
+
+func getMyVoucherTransfersEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(myVoucherRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		transfers, err := svc.GetMyVoucherTransfers(ctx, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(transfers), nil
+	}
+}
+
+func getQuestionSetsEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		sets, err := svc.GetQuestionSets(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(sets), nil
+	}
+}
+
+func getQuestionSetEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(questionSetRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		set, err := svc.GetQuestionSet(ctx, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(set), nil
+	}
+}
+
+func createQuestionSetEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(createQuestionSetRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		set := admin.QuestionSet{
+			Name:        req.Name,
+			Description: req.Description,
+		}
+		id, err := svc.CreateQuestionSet(ctx, set)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(map[string]string{"id": id}), nil
+	}
+}
+
+func updateQuestionSetEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(updateQuestionSetRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		set := admin.QuestionSet{
+			ID:          req.ID,
+			Name:        req.Name,
+			Description: req.Description,
+		}
+		if err := svc.UpdateQuestionSet(ctx, set); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func createQuestionEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(questionRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		id, err := svc.CreateQuestion(ctx, req.question())
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(map[string]string{"id": id}), nil
+	}
+}
+
+func updateQuestionEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(questionRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.UpdateQuestion(ctx, req.question()); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func deleteQuestionEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(deleteQuestionRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.DeleteQuestion(ctx, req.ID, req.SetID); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func attachQuestionSetEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(attachQuestionSetRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.AttachQuestionSet(ctx, req.EventID, req.QuestionSetID, req.Mode, req.Prizes); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func startQuizEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(quizRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		state, err := svc.StartQuiz(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(state), nil
+	}
+}
+
+func getQuizEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(quizRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		state, err := svc.GetQuiz(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(state), nil
+	}
+}
+
+func answerQuizEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(answerQuizRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		state, err := svc.AnswerQuiz(ctx, req.EventID, req.QuestionID, req.Choices)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(state), nil
+	}
+}
+
+func getQuizAnswersEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(quizRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		answers, err := svc.GetQuizAnswers(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(answers), nil
+	}
+}
+
+func getLiveLeaderboardEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(quizRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		board, err := svc.GetLiveLeaderboard(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(board), nil
+	}
+}
+
+func getPrizeTableEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(shakeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		table, err := svc.GetPrizeTable(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(table), nil
+	}
+}
+
+func setPrizeTableEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(setPrizeTableRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		table := admin.PrizeTable{
+			EventID: req.EventID,
+			Turns:   req.Turns,
+			Prizes:  req.Prizes,
+		}
+		if err := svc.SetPrizeTable(ctx, table); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func getDrawsEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(shakeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		draws, err := svc.GetDraws(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(draws), nil
+	}
+}
+
+func auditDrawsEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(shakeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		audit, err := svc.AuditDraws(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(audit), nil
+	}
+}
+
+func shakeEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(shakeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		result, err := svc.Shake(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(result), nil
+	}
+}
+
+func getShakeEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(shakeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		state, err := svc.GetShake(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(state), nil
+	}
+}
+
+func getMyTurnsEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(turnsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		balance, err := svc.GetMyTurns(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(balance), nil
+	}
+}
+
+func getMyTurnHistoryEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(turnsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		entries, err := svc.GetMyTurnHistory(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(entries), nil
+	}
+}
+
+func spendTurnEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(spendTurnRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		entry, err := svc.SpendTurn(ctx, req.EventID, req.Ref)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(entry), nil
+	}
+}
+
+func shareEventEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(turnsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		entry, err := svc.ShareEvent(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(entry), nil
+	}
+}
+
+func getInviteCodeEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(turnsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		code, err := svc.GetInviteCode(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(code), nil
+	}
+}
+
+func claimInviteEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(claimInviteRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.ClaimInvite(ctx, req.EventID, req.Code); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func requestTurnEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(requestTurnRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		turnRequest, err := svc.RequestTurn(ctx, req.EventID, req.Friend)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(turnRequest), nil
+	}
+}
+
+func getMyTurnRequestsEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(turnsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		requests, err := svc.GetMyTurnRequests(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(requests), nil
+	}
+}
+
+func acceptTurnRequestEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(turnRequestRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		turnRequest, err := svc.AcceptTurnRequest(ctx, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(turnRequest), nil
+	}
+}
+
+func declineTurnRequestEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(turnRequestRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.DeclineTurnRequest(ctx, req.ID); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func cancelTurnRequestEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(turnRequestRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.CancelTurnRequest(ctx, req.ID); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func getItemsEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(itemsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		items, err := svc.GetItems(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(items), nil
+	}
+}
+
+func createItemEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(createItemRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		item := admin.Item{
+			EventID: req.EventID,
+			Code:    req.Code,
+			Name:    req.Name,
+			Image:   req.Image,
+			Rarity:  req.Rarity,
+		}
+		id, err := svc.CreateItem(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(map[string]string{"id": id}), nil
+	}
+}
+
+func updateItemEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(updateItemRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		item := admin.Item{
+			ID:      req.ItemID,
+			EventID: req.EventID,
+			Name:    req.Name,
+			Image:   req.Image,
+			Rarity:  req.Rarity,
+		}
+		if err := svc.UpdateItem(ctx, item); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func getRecipesEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(itemsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		recipes, err := svc.GetRecipes(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(recipes), nil
+	}
+}
+
+func createRecipeEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(recipeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		id, err := svc.CreateRecipe(ctx, req.recipe())
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(map[string]string{"id": id}), nil
+	}
+}
+
+func updateRecipeEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(recipeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.UpdateRecipe(ctx, req.recipe()); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func deleteRecipeEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(recipeIDRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.DeleteRecipe(ctx, req.RecipeID, req.EventID); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func getItemCatalogEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(itemsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		catalog, err := svc.GetItemCatalog(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(catalog), nil
+	}
+}
+
+func getMyItemsEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(itemsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		items, err := svc.GetMyItems(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(items), nil
+	}
+}
+
+func exchangeRecipeEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(recipeIDRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		voucher, err := svc.ExchangeRecipe(ctx, req.EventID, req.RecipeID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(voucher), nil
+	}
+}
+
+func giftItemsEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(giftItemsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.GiftItems(ctx, req.EventID, req.Friend, req.Items); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func offerItemTradeEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(offerItemTradeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		trade, err := svc.OfferItemTrade(ctx, req.EventID, req.Friend, req.Give, req.Want)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(trade), nil
+	}
+}
+
+func getMyItemTradesEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(itemsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		trades, err := svc.GetMyItemTrades(ctx, req.EventID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(trades), nil
+	}
+}
+
+func acceptItemTradeEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(itemTradeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		trade, err := svc.AcceptItemTrade(ctx, req.ID)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(trade), nil
+	}
+}
+
+func declineItemTradeEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(itemTradeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.DeclineItemTrade(ctx, req.ID); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func cancelItemTradeEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(itemTradeRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		if err := svc.CancelItemTrade(ctx, req.ID); err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(nil), nil
+	}
+}
+
+func getLeaderboardEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(leaderboardRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		board, err := svc.GetLeaderboard(ctx, req.query())
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(board), nil
+	}
+}
+
+func getMyRankEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(leaderboardRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		board, err := svc.GetMyRank(ctx, req.query())
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(board), nil
+	}
+}
+
+func getStandingsEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(standingsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		standings, err := svc.GetStandings(ctx, req.EventID, req.Limit, req.Offset)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(standings), nil
+	}
+}
+
+func getEventLeaderboardEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(leaderboardRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		board, err := svc.GetEventLeaderboard(ctx, req.query())
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(board), nil
+	}
+}
+
+func getEventStandingsEndpoint(svc admin.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(standingsRequest)
+		if err := req.validate(); err != nil {
+			return nil, err
+		}
+		standings, err := svc.GetEventStandings(ctx, req.EventID, req.Limit, req.Offset)
+		if err != nil {
+			return nil, err
+		}
+		return common.SuccessRes(standings), nil
+	}
+}
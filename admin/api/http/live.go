@@ -0,0 +1,200 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"github.com/go-zoo/bone"
+	"github.com/gorilla/websocket"
+	"github.com/resrrdttrt/VOU/admin"
+)
+
+const (
+	liveWriteWait  = 10 * time.Second
+	livePongWait   = 60 * time.Second
+	livePingPeriod = livePongWait * 9 / 10
+	liveMaxMessage = 1024
+
+	// liveForwardedHeader marks requests forwarded by another replica, which
+	// are served where they land so they are never forwarded in a loop.
+	liveForwardedHeader = "X-Vou-Live-Forwarded"
+)
+
+// Access tokens travel in the handshake rather than in cookies, so another
+// site cannot open a connection on a player's behalf and any origin may
+// connect.
+var liveUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// liveClientMessage is what players send over the socket. The only type is
+// "answer".
+type liveClientMessage struct {
+	Type    string          `json:"type"`
+	Round   int             `json:"round"`
+	Choices admin.ChoiceIDs `json:"choices"`
+}
+
+// forwardLiveQuiz passes requests for a live session hosted by another
+// replica on to it, WebSocket upgrades included, and serves the others with
+// next.
+func forwardLiveQuiz(svc admin.Service, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if r.Header.Get(liveForwardedHeader) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		req := quizRequest{
+			EventID: bone.GetValue(r, "id"),
+		}
+		if err := req.validate(); err != nil {
+			encodeError(ctx, err, w)
+			return
+		}
+		host, err := svc.LiveQuizHost(ctx, req.EventID)
+		if err != nil {
+			encodeError(ctx, err, w)
+			return
+		}
+		if host == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		target, err := url.Parse(host)
+		if err != nil {
+			encodeError(ctx, admin.ErrLiveHostUnreachable, w)
+			return
+		}
+		proxy := &httputil.ReverseProxy{
+			// Routers strip their prefix from the path, the request URI
+			// still holds the path the client asked for.
+			Rewrite: func(pr *httputil.ProxyRequest) {
+				if uri, err := url.ParseRequestURI(pr.In.RequestURI); err == nil {
+					pr.Out.URL.Path = uri.Path
+					pr.Out.URL.RawPath = uri.RawPath
+				}
+				pr.SetURL(target)
+				pr.SetXForwarded()
+				pr.Out.Header.Set(liveForwardedHeader, "1")
+			},
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				encodeError(r.Context(), admin.ErrLiveHostUnreachable, w)
+			},
+		}
+		proxy.ServeHTTP(w, r)
+	})
+}
+
+// serveLiveQuiz joins the caller to the live session of an event and relays
+// its messages over a WebSocket. Answers sent on the socket are graded and
+// the grade is sent back to the player alone. The socket is closed once the
+// session finishes.
+func serveLiveQuiz(svc admin.Service) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		req := quizRequest{
+			EventID: bone.GetValue(r, "id"),
+		}
+		if err := req.validate(); err != nil {
+			encodeError(ctx, err, w)
+			return
+		}
+		msgs, leave, err := svc.JoinLiveQuiz(ctx, req.EventID)
+		if err != nil {
+			encodeError(ctx, err, w)
+			return
+		}
+		defer leave()
+
+		conn, err := liveUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		replies := make(chan admin.LiveMessage)
+		closed := make(chan struct{})
+		stopped := make(chan struct{})
+		defer close(stopped)
+		go readLiveQuiz(ctx, svc, conn, req.EventID, replies, closed, stopped)
+		writeLiveQuiz(conn, msgs, replies, closed)
+	})
+}
+
+func readLiveQuiz(ctx context.Context, svc admin.Service, conn *websocket.Conn, eventID string, replies chan<- admin.LiveMessage, closed chan<- struct{}, stopped <-chan struct{}) {
+	defer close(closed)
+	conn.SetReadLimit(liveMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(livePongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(livePongWait))
+	})
+	for {
+		var in liveClientMessage
+		if err := conn.ReadJSON(&in); err != nil {
+			return
+		}
+		out := admin.LiveMessage{
+			Type:    admin.LiveMsgAnswer,
+			EventID: eventID,
+			Round:   in.Round,
+		}
+		if in.Type != "answer" {
+			out.Type = admin.LiveMsgError
+			out.Error = "unknown message type"
+		} else if answer, err := svc.AnswerLiveQuiz(ctx, eventID, in.Round, in.Choices); err != nil {
+			out.Type = admin.LiveMsgError
+			out.Error = err.Error()
+		} else {
+			out.Answer = &answer
+		}
+		select {
+		case replies <- out:
+		case <-stopped:
+			return
+		}
+	}
+}
+
+func writeLiveQuiz(conn *websocket.Conn, msgs <-chan admin.LiveMessage, replies <-chan admin.LiveMessage, closed <-chan struct{}) {
+	ticker := time.NewTicker(livePingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+			if msg.Type == admin.LiveMsgFinished {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "quiz finished"), time.Now().Add(liveWriteWait))
+				return
+			}
+			// The session was interrupted; players reconnect once it is
+			// hosted again.
+			if msg.Type == admin.LiveMsgError {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, msg.Error), time.Now().Add(liveWriteWait))
+				return
+			}
+		case msg := <-replies:
+			conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteWait)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -2,30 +2,74 @@ package http
 
 import (
 	"fmt"
+	"net/mail"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/auth"
 	"github.com/resrrdttrt/VOU/pkg/errors"
 )
 
 var (
-	ErrInvalidUUID      = errors.New("invalid uuid")
-	ErrInvalidRoleValue = errors.New("role must be enterprise, end_user or admin")
-	ErrInvalidStatus    = errors.New("status must be active or inactive")
+	ErrInvalidUUID        = errors.New("invalid uuid")
+	ErrInvalidRoleValue   = errors.New("role must be enterprise, enterprise_staff, end_user or admin")
+	ErrInvalidStatus      = errors.New("status must be active or inactive")
+	ErrInvalidTimeRange   = errors.New("end must not be before start")
+	ErrInvalidEventStatus = errors.New("status must be draft, pending_review, approved, running, ended or cancelled")
+	ErrInvalidQuantity    = errors.New("quantity must not be negative")
+	ErrInvalidBatchCount  = errors.New("count must be between 1 and 1000000")
+	ErrInvalidQRFormat    = errors.New("format must be png or svg")
+	ErrInvalidQRSize      = errors.New("size must be between 64 and 1024")
+	ErrCodeOrQRCode       = errors.New("exactly one of code and qrcode must be given")
+	ErrInvalidStoreID     = errors.New("store_id must be at most 64 characters")
+	ErrInvalidLimit       = errors.New("limit must be between 1 and 200")
+	ErrInvalidRecipient   = errors.New("recipient must be at most 254 characters")
+	ErrInvalidBasket      = errors.New("basket needs a currency and items with a sku, a positive quantity and a non-negative unit_price")
+	ErrInvalidPosition    = errors.New("position must not be negative")
+	ErrInvalidChoices     = errors.New("too many choices")
+	ErrInvalidQuizMode    = errors.New("mode must be self_paced or live")
+	ErrPrizesNeedLive     = errors.New("prizes are only handed out by live quizzes")
+	ErrInvalidTurns       = errors.New("turns_per_day must be between 1 and 1000")
+	ErrInvalidPrizeCount  = errors.New("a prize table needs 1 to 50 prizes")
+	ErrInvalidRef         = errors.New("ref must be at most 64 characters")
+	ErrInvalidContact     = errors.New("username, email or phone must be at most 254 characters")
+	ErrInvalidInviteCode  = errors.New("code must be 8 characters")
+	ErrNoItems            = errors.New("items must not be empty")
+	ErrEmptyTrade         = errors.New("a trade needs give or want items")
+	ErrInvalidPeriod      = errors.New("period must be daily, weekly, event or all_time")
+	ErrInvalidBoardLimit  = errors.New("limit must be between 1 and 100")
+	ErrInvalidRadius      = errors.New("radius must be between 1 and 25")
+	ErrInvalidPageLimit   = errors.New("limit must be between 1 and 1000")
+	ErrInvalidOffset      = errors.New("offset must not be negative")
+	ErrInvalidChannel     = errors.New("channel must be email or phone")
+	ErrInvalidEmail       = errors.New("email must be a valid address of at most 254 characters")
+	ErrInvalidPhone       = errors.New("phone must be 8 to 20 digits, optionally after a +")
+	ErrInvalidPassword    = errors.New("password must be 8 to 72 characters")
+	ErrInvalidMFARole     = errors.New("role must be admin, enterprise or enterprise_staff")
 )
 
+func validRole(role string) bool {
+	switch role {
+	case auth.RoleAdmin, auth.RoleEnterprise, auth.RoleEnterpriseStaff, auth.RoleEndUser:
+		return true
+	}
+	return false
+}
+
 func errMissing(field string) error {
 	return errors.Wrap(errors.ErrMalformedEntity, errors.New(fmt.Sprintf("missing field `%s`", field)))
 }
 
 type createUserRequest struct {
-	Name     string `json:"name"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Email    string `json:"email"`
-	Phone    string `json:"phone"`
-	Role     string `json:"role"`
-	Status   string `json:"status"`
+	Name         string `json:"name"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	Role         string `json:"role"`
+	Status       string `json:"status"`
+	EnterpriseID string `json:"enterprise_id"`
 }
 
 func (req createUserRequest) validate() error {
@@ -47,9 +91,17 @@ func (req createUserRequest) validate() error {
 	if req.Role == "" {
 		return errMissing("role")
 	}
-	if req.Role != "enterprise" && req.Role != "end_user" && req.Role != "admin" {
+	if !validRole(req.Role) {
 		return ErrInvalidRoleValue
 	}
+	if req.Role == auth.RoleEnterpriseStaff {
+		if req.EnterpriseID == "" {
+			return errMissing("enterprise_id")
+		}
+		if _, err := uuid.Parse(req.EnterpriseID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
 	if req.Status == "" {
 		return errMissing("status")
 	}
@@ -60,14 +112,15 @@ func (req createUserRequest) validate() error {
 }
 
 type updateUserRequest struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Username  string `json:"username"`
-	Password  string `json:"password"`
-	Email     string `json:"email"`
-	Phone     string `json:"phone"`
-	Role      string `json:"role"`
-	Status    string `json:"status"`
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	Role         string `json:"role"`
+	Status       string `json:"status"`
+	EnterpriseID string `json:"enterprise_id"`
 }
 
 func (req updateUserRequest) validate() error {
@@ -78,9 +131,14 @@ func (req updateUserRequest) validate() error {
 			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
 		}
 	}
-	if req.Role != "" && req.Role != "enterprise" && req.Role != "end_user" && req.Role != "admin" {
+	if req.Role != "" && !validRole(req.Role) {
 		return ErrInvalidRoleValue
 	}
+	if req.EnterpriseID != "" {
+		if _, err := uuid.Parse(req.EnterpriseID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
 	if req.Status != "" && req.Status != "active" && req.Status != "inactive" {
 		return ErrInvalidStatus
 	}
@@ -142,7 +200,7 @@ func (req createGameRequest) validate() error {
 }
 
 type updateGameRequest struct {
-	ID            string 
+	ID            string
 	Name          string `json:"name"`
 	Images        string `json:"images"`
 	Type          string `json:"type"`
@@ -191,6 +249,230 @@ func (req loginRequest) validate() error {
 	return nil
 }
 
+type registerRequest struct {
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	Channel  string `json:"channel"`
+}
+
+func (req registerRequest) validate() error {
+	if req.Name == "" {
+		return errMissing("name")
+	}
+	if req.Username == "" {
+		return errMissing("username")
+	}
+	if len(req.Name) > 254 || len(req.Username) > 254 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidContact)
+	}
+	if len(req.Password) < 8 || len(req.Password) > 72 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidPassword)
+	}
+	if req.Channel != admin.ChannelEmail && req.Channel != admin.ChannelPhone {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidChannel)
+	}
+	if req.Channel == admin.ChannelEmail && req.Email == "" {
+		return errMissing("email")
+	}
+	if req.Channel == admin.ChannelPhone && req.Phone == "" {
+		return errMissing("phone")
+	}
+	if req.Email != "" && !validEmail(req.Email) {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidEmail)
+	}
+	if req.Phone != "" && !validPhone(req.Phone) {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidPhone)
+	}
+	return nil
+}
+
+func (req registerRequest) user() admin.User {
+	return admin.User{
+		Name:     req.Name,
+		Username: req.Username,
+		Password: req.Password,
+		Email:    req.Email,
+		Phone:    req.Phone,
+	}
+}
+
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email && len(email) <= 254
+}
+
+func validPhone(phone string) bool {
+	digits := phone
+	if len(digits) > 0 && digits[0] == '+' {
+		digits = digits[1:]
+	}
+	if len(digits) < 8 || len(digits) > 19 {
+		return false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+type verifyRegistrationRequest struct {
+	Contact string `json:"contact"`
+	Code    string `json:"code"`
+}
+
+func (req verifyRegistrationRequest) validate() error {
+	if req.Contact == "" {
+		return errMissing("contact")
+	}
+	if len(req.Contact) > 254 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidContact)
+	}
+	if req.Code == "" {
+		return errMissing("code")
+	}
+	return nil
+}
+
+type resendVerificationRequest struct {
+	Contact string `json:"contact"`
+	Channel string `json:"channel"`
+}
+
+func (req resendVerificationRequest) validate() error {
+	if req.Contact == "" {
+		return errMissing("contact")
+	}
+	if len(req.Contact) > 254 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidContact)
+	}
+	if req.Channel != admin.ChannelEmail && req.Channel != admin.ChannelPhone {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidChannel)
+	}
+	return nil
+}
+
+type forgotPasswordRequest struct {
+	Contact string `json:"contact"`
+	Channel string `json:"channel"`
+}
+
+func (req forgotPasswordRequest) validate() error {
+	if req.Contact == "" {
+		return errMissing("contact")
+	}
+	if len(req.Contact) > 254 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidContact)
+	}
+	if req.Channel != admin.ChannelEmail && req.Channel != admin.ChannelPhone {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidChannel)
+	}
+	return nil
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (req resetPasswordRequest) validate() error {
+	if req.Token == "" {
+		return errMissing("token")
+	}
+	if len(req.Password) < 8 || len(req.Password) > 72 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidPassword)
+	}
+	return nil
+}
+
+type mfaLoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+func (req mfaLoginRequest) validate() error {
+	if req.MFAToken == "" {
+		return errMissing("mfa_token")
+	}
+	if req.Code == "" {
+		return errMissing("code")
+	}
+	return nil
+}
+
+type mfaChallengeRequest struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+func (req mfaChallengeRequest) validate() error {
+	if req.MFAToken == "" {
+		return errMissing("mfa_token")
+	}
+	return nil
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+func (req mfaCodeRequest) validate() error {
+	if req.Code == "" {
+		return errMissing("code")
+	}
+	return nil
+}
+
+// disableMFARequest leaves the code optional, since an enrollment that was
+// never confirmed can be dropped without one.
+type disableMFARequest struct {
+	Code string `json:"code"`
+}
+
+func (req disableMFARequest) validate() error {
+	return nil
+}
+
+type mfaPolicyRequest struct {
+	Role     string `json:"-"`
+	Enforced *bool  `json:"enforced"`
+}
+
+func (req mfaPolicyRequest) validate() error {
+	if !admin.MFARole(req.Role) {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidMFARole)
+	}
+	if req.Enforced == nil {
+		return errMissing("enforced")
+	}
+	return nil
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (req refreshTokenRequest) validate() error {
+	if req.RefreshToken == "" {
+		return errMissing("refresh_token")
+	}
+	return nil
+}
+
+type logoutRequest struct {
+	AccessToken string
+}
+
+func (req logoutRequest) validate() error {
+	if req.AccessToken == "" {
+		return errors.ErrUnauthorized
+	}
+	return nil
+}
+
 type enterpriseRequest struct {
 	Name     string `json:"name"`
 	Field    string `json:"field"`
@@ -206,8 +488,26 @@ func (req enterpriseRequest) validate() error {
 	return nil
 }
 
+type listEventsRequest struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (req listEventsRequest) validate() error {
+	if req.Start.IsZero() != req.End.IsZero() {
+		if req.Start.IsZero() {
+			return errMissing("start")
+		}
+		return errMissing("end")
+	}
+	if req.End.Before(req.Start) {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidTimeRange)
+	}
+	return nil
+}
+
 type getEventIDRequest struct {
-	ID string 
+	ID string
 }
 
 func (req getEventIDRequest) validate() error {
@@ -265,7 +565,7 @@ func (req createEventRequest) validate() error {
 }
 
 type updateEventRequest struct {
-	ID         string    
+	ID         string
 	Name       string    `json:"name"`
 	Images     string    `json:"images"`
 	VoucherNum int       `json:"voucher_num"`
@@ -286,6 +586,48 @@ func (req updateEventRequest) validate() error {
 	return nil
 }
 
+type eventStatusRequest struct {
+	ID     string
+	Reason string `json:"reason"`
+}
+
+func (req eventStatusRequest) validate() error {
+	if req.ID == "" {
+		return errMissing("event_id")
+	} else {
+		if _, err := uuid.Parse(req.ID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type rejectEventRequest struct {
+	eventStatusRequest
+}
+
+func (req rejectEventRequest) validate() error {
+	if err := req.eventStatusRequest.validate(); err != nil {
+		return err
+	}
+	if req.Reason == "" {
+		return errMissing("reason")
+	}
+	return nil
+}
+
+type listEventsByStatusRequest struct {
+	Status string
+}
+
+func (req listEventsByStatusRequest) validate() error {
+	switch req.Status {
+	case admin.EventDraft, admin.EventPendingReview, admin.EventApproved, admin.EventRunning, admin.EventEnded, admin.EventCancelled:
+		return nil
+	}
+	return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidEventStatus)
+}
+
 type getVoucherByIDRequest struct {
 	EventID string
 	ID      string
@@ -310,13 +652,13 @@ func (req getVoucherByIDRequest) validate() error {
 }
 
 type createVoucherRequest struct {
-	Code        string    `json:"code"`
-	Qrcode      string    `json:"qrcode"`
-	Images      string    `json:"images"`
-	Value       int       `json:"value"`
-	Description string    `json:"description"`
-	ExpiredTime time.Time `json:"expired_time"`
-	Status      string    `json:"status"`
+	Code        string              `json:"code"`
+	Images      string              `json:"images"`
+	Value       int                 `json:"value"`
+	Description string              `json:"description"`
+	ExpiredTime time.Time           `json:"expired_time"`
+	Status      string              `json:"status"`
+	Rule        *admin.DiscountRule `json:"rule"`
 	EventID     string
 }
 
@@ -324,9 +666,6 @@ func (req createVoucherRequest) validate() error {
 	if req.Code == "" {
 		return errMissing("code")
 	}
-	if req.Qrcode == "" {
-		return errMissing("qrcode")
-	}
 	if req.Images == "" {
 		return errMissing("images")
 	}
@@ -339,18 +678,21 @@ func (req createVoucherRequest) validate() error {
 	if req.Status == "" {
 		return errMissing("status")
 	}
+	if req.Rule != nil {
+		return req.Rule.Validate()
+	}
 	return nil
 }
 
 type updateVoucherRequest struct {
 	ID          string
-	Code        string    `json:"code"`
-	Qrcode      string    `json:"qrcode"`
-	Images      string    `json:"images"`
-	Value       int       `json:"value"`
-	Description string    `json:"description"`
-	ExpiredTime time.Time `json:"expired_time"`
-	Status      string    `json:"status"`
+	Code        string              `json:"code"`
+	Images      string              `json:"images"`
+	Value       int                 `json:"value"`
+	Description string              `json:"description"`
+	ExpiredTime time.Time           `json:"expired_time"`
+	Status      string              `json:"status"`
+	Rule        *admin.DiscountRule `json:"rule"`
 	EventID     string
 }
 
@@ -358,8 +700,45 @@ func (req updateVoucherRequest) validate() error {
 	if req.Code == "" {
 		return errMissing("code")
 	}
-	if req.Qrcode == "" {
-		return errMissing("qrcode")
+	if req.Images == "" {
+		return errMissing("images")
+	}
+	if req.Value == 0 {
+		return errMissing("value")
+	}
+	if req.ExpiredTime.IsZero() {
+		return errMissing("expired_time")
+	}
+	if req.Status != "" && req.Status != admin.VoucherActive && req.Status != admin.VoucherInactive {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidStatus)
+	}
+	if req.Rule != nil {
+		return req.Rule.Validate()
+	}
+	return nil
+}
+
+type createVoucherTemplateRequest struct {
+	Name        string              `json:"name"`
+	Images      string              `json:"images"`
+	Value       int                 `json:"value"`
+	Description string              `json:"description"`
+	ExpiredTime time.Time           `json:"expired_time"`
+	Quantity    int                 `json:"quantity"`
+	Rule        *admin.DiscountRule `json:"rule"`
+	EventID     string
+}
+
+func (req createVoucherTemplateRequest) validate() error {
+	if req.EventID == "" {
+		return errMissing("event_id")
+	} else {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.Name == "" {
+		return errMissing("name")
 	}
 	if req.Images == "" {
 		return errMissing("images")
@@ -370,5 +749,852 @@ func (req updateVoucherRequest) validate() error {
 	if req.ExpiredTime.IsZero() {
 		return errMissing("expired_time")
 	}
+	if req.Quantity < 0 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidQuantity)
+	}
+	if req.Rule != nil {
+		return req.Rule.Validate()
+	}
+	return nil
+}
+
+type updateVoucherTemplateRequest struct {
+	ID       string
+	EventID  string
+	Quantity *int `json:"quantity"`
+}
+
+func (req updateVoucherTemplateRequest) validate() error {
+	if req.EventID == "" {
+		return errMissing("event_id")
+	} else {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.ID == "" {
+		return errMissing("template_id")
+	} else {
+		if _, err := uuid.Parse(req.ID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.Quantity == nil {
+		return errMissing("quantity")
+	}
+	if *req.Quantity < 0 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidQuantity)
+	}
+	return nil
+}
+
+type issueVoucherRequest struct {
+	TemplateID string
+	EventID    string
+	UserID     string `json:"user_id"`
+}
+
+func (req issueVoucherRequest) validate() error {
+	if req.EventID == "" {
+		return errMissing("event_id")
+	} else {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.TemplateID == "" {
+		return errMissing("template_id")
+	} else {
+		if _, err := uuid.Parse(req.TemplateID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.UserID == "" {
+		return errMissing("user_id")
+	} else {
+		if _, err := uuid.Parse(req.UserID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+type generateVouchersRequest struct {
+	EventID     string
+	Count       int                 `json:"count"`
+	Format      admin.CodeFormat    `json:"format"`
+	Images      string              `json:"images"`
+	Value       int                 `json:"value"`
+	Description string              `json:"description"`
+	ExpiredTime time.Time           `json:"expired_time"`
+	Rule        *admin.DiscountRule `json:"rule"`
+}
+
+func (req generateVouchersRequest) validate() error {
+	if req.EventID == "" {
+		return errMissing("event_id")
+	} else {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.Count < 1 || req.Count > admin.MaxBatchSize {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidBatchCount)
+	}
+	if req.Images == "" {
+		return errMissing("images")
+	}
+	if req.Value == 0 {
+		return errMissing("value")
+	}
+	if req.ExpiredTime.IsZero() {
+		return errMissing("expired_time")
+	}
+	if req.Rule != nil {
+		if err := req.Rule.Validate(); err != nil {
+			return err
+		}
+	}
+	return req.Format.Validate()
+}
+
+type getVoucherBatchRequest struct {
+	ID      string
+	EventID string
+}
+
+func (req getVoucherBatchRequest) validate() error {
+	if req.EventID == "" {
+		return errMissing("event_id")
+	} else {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.ID == "" {
+		return errMissing("batch_id")
+	} else {
+		if _, err := uuid.Parse(req.ID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type getVoucherQRCodeRequest struct {
+	getVoucherByIDRequest
+	Format string
+	Size   int
+}
+
+func (req getVoucherQRCodeRequest) validate() error {
+	if err := req.getVoucherByIDRequest.validate(); err != nil {
+		return err
+	}
+	if req.Format != admin.QRCodePNG && req.Format != admin.QRCodeSVG {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidQRFormat)
+	}
+	if req.Size < 64 || req.Size > 1024 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidQRSize)
+	}
+	return nil
+}
+
+type redeemVoucherRequest struct {
+	Code    string        `json:"code"`
+	Qrcode  string        `json:"qrcode"`
+	StoreID string        `json:"store_id"`
+	UserID  string        `json:"user_id"`
+	Basket  *admin.Basket `json:"basket,omitempty"`
+}
+
+func (req redeemVoucherRequest) validate() error {
+	if (req.Code == "") == (req.Qrcode == "") {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrCodeOrQRCode)
+	}
+	if req.StoreID == "" {
+		return errMissing("store_id")
+	}
+	if len(req.StoreID) > 64 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidStoreID)
+	}
+	if req.UserID != "" {
+		if _, err := uuid.Parse(req.UserID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.Basket != nil {
+		return validateBasket(*req.Basket)
+	}
+	return nil
+}
+
+type quoteVoucherRequest struct {
+	ID     string
+	Basket admin.Basket `json:"basket"`
+}
+
+func (req quoteVoucherRequest) validate() error {
+	if req.ID == "" {
+		return errMissing("voucher_id")
+	} else {
+		if _, err := uuid.Parse(req.ID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return validateBasket(req.Basket)
+}
+
+func validateBasket(basket admin.Basket) error {
+	if basket.Currency == "" || len(basket.Items) == 0 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidBasket)
+	}
+	for _, item := range basket.Items {
+		if item.SKU == "" || item.Quantity < 1 || item.UnitPrice < 0 {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidBasket)
+		}
+	}
+	return nil
+}
+
+type listMyVouchersRequest struct {
+	Status        string
+	EventID       string
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+	Cursor        string
+	Limit         int
+}
+
+func (req listMyVouchersRequest) validate() error {
+	switch req.Status {
+	case "", admin.VoucherActive, admin.VoucherInactive, admin.VoucherRedeemed, admin.VoucherExpired:
+	default:
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidStatus)
+	}
+	if req.EventID != "" {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if !req.ExpiresAfter.IsZero() && !req.ExpiresBefore.IsZero() && req.ExpiresBefore.Before(req.ExpiresAfter) {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidTimeRange)
+	}
+	if req.Limit < 0 || req.Limit > admin.MaxWalletLimit {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidLimit)
+	}
+	return nil
+}
+
+type myVoucherRequest struct {
+	ID string
+}
+
+func (req myVoucherRequest) validate() error {
+	if req.ID == "" {
+		return errMissing("voucher_id")
+	} else {
+		if _, err := uuid.Parse(req.ID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type myVoucherQRCodeRequest struct {
+	myVoucherRequest
+	Format string
+	Size   int
+}
+
+func (req myVoucherQRCodeRequest) validate() error {
+	if err := req.myVoucherRequest.validate(); err != nil {
+		return err
+	}
+	if req.Format != admin.QRCodePNG && req.Format != admin.QRCodeSVG {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidQRFormat)
+	}
+	if req.Size < 64 || req.Size > 1024 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidQRSize)
+	}
+	return nil
+}
+
+type offerVoucherRequest struct {
+	VoucherID string
+	Recipient string `json:"recipient"`
+}
+
+func (req offerVoucherRequest) validate() error {
+	if req.VoucherID == "" {
+		return errMissing("voucher_id")
+	} else {
+		if _, err := uuid.Parse(req.VoucherID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if len(req.Recipient) > 254 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidRecipient)
+	}
+	return nil
+}
+
+type transferRequest struct {
+	ID string
+}
+
+func (req transferRequest) validate() error {
+	if req.ID == "" {
+		return errMissing("transfer_id")
+	} else {
+		if _, err := uuid.Parse(req.ID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type claimTransferRequest struct {
+	Token string `json:"token"`
+}
+
+func (req claimTransferRequest) validate() error {
+	if req.Token == "" {
+		return errMissing("token")
+	}
+	return nil
+}
+
+type createQuestionSetRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (req createQuestionSetRequest) validate() error {
+	if req.Name == "" {
+		return errMissing("name")
+	}
+	return nil
+}
+
+type questionSetRequest struct {
+	ID string
+}
+
+func (req questionSetRequest) validate() error {
+	if req.ID == "" {
+		return errMissing("question_set_id")
+	} else {
+		if _, err := uuid.Parse(req.ID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type updateQuestionSetRequest struct {
+	ID          string
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (req updateQuestionSetRequest) validate() error {
+	return questionSetRequest{ID: req.ID}.validate()
+}
+
+type questionRequest struct {
+	SetID      string
+	ID         string
+	Position   int             `json:"position"`
+	Text       string          `json:"text"`
+	Media      string          `json:"media"`
+	Choices    admin.Choices   `json:"choices"`
+	Correct    admin.ChoiceIDs `json:"correct"`
+	TimeLimit  int             `json:"time_limit"`
+	Difficulty string          `json:"difficulty"`
+}
+
+func (req questionRequest) validate() error {
+	if err := (questionSetRequest{ID: req.SetID}).validate(); err != nil {
+		return err
+	}
+	if req.ID != "" {
+		if _, err := uuid.Parse(req.ID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.Position < 0 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidPosition)
+	}
+	return req.question().Validate()
+}
+
+func (req questionRequest) question() admin.Question {
+	return admin.Question{
+		ID:         req.ID,
+		SetID:      req.SetID,
+		Position:   req.Position,
+		Text:       req.Text,
+		Media:      req.Media,
+		Choices:    req.Choices,
+		Correct:    req.Correct,
+		TimeLimit:  req.TimeLimit,
+		Difficulty: req.Difficulty,
+	}
+}
+
+type deleteQuestionRequest struct {
+	SetID string
+	ID    string
+}
+
+func (req deleteQuestionRequest) validate() error {
+	if err := (questionSetRequest{ID: req.SetID}).validate(); err != nil {
+		return err
+	}
+	if req.ID == "" {
+		return errMissing("question_id")
+	} else {
+		if _, err := uuid.Parse(req.ID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type attachQuestionSetRequest struct {
+	EventID       string
+	QuestionSetID string           `json:"question_set_id"`
+	Mode          string           `json:"mode"`
+	Prizes        admin.QuizPrizes `json:"prizes"`
+}
+
+func (req attachQuestionSetRequest) validate() error {
+	if req.EventID == "" {
+		return errMissing("event_id")
+	} else {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.QuestionSetID == "" {
+		return errMissing("question_set_id")
+	} else {
+		if _, err := uuid.Parse(req.QuestionSetID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.Mode != admin.QuizSelfPaced && req.Mode != admin.QuizLive {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidQuizMode)
+	}
+	if len(req.Prizes) > 0 && req.Mode != admin.QuizLive {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrPrizesNeedLive)
+	}
+	for _, id := range req.Prizes {
+		if _, err := uuid.Parse(id); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type quizRequest struct {
+	EventID string
+}
+
+func (req quizRequest) validate() error {
+	if req.EventID == "" {
+		return errMissing("event_id")
+	} else {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type answerQuizRequest struct {
+	EventID    string
+	QuestionID string          `json:"question_id"`
+	Choices    admin.ChoiceIDs `json:"choices"`
+}
+
+func (req answerQuizRequest) validate() error {
+	if err := (quizRequest{EventID: req.EventID}).validate(); err != nil {
+		return err
+	}
+	if req.QuestionID == "" {
+		return errMissing("question_id")
+	} else {
+		if _, err := uuid.Parse(req.QuestionID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if len(req.Choices) > admin.MaxChoices {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidChoices)
+	}
+	return nil
+}
+
+type setPrizeTableRequest struct {
+	EventID string
+	Turns   int           `json:"turns_per_day"`
+	Prizes  []admin.Prize `json:"prizes"`
+}
+
+func (req setPrizeTableRequest) validate() error {
+	if err := (shakeRequest{EventID: req.EventID}).validate(); err != nil {
+		return err
+	}
+	if req.Turns < 1 || req.Turns > admin.MaxPlayTurns {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidTurns)
+	}
+	if len(req.Prizes) == 0 || len(req.Prizes) > admin.MaxPrizes {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidPrizeCount)
+	}
+	for _, prize := range req.Prizes {
+		if err := prize.Validate(); err != nil {
+			return err
+		}
+		if prize.Kind == admin.PrizeVoucher {
+			if _, err := uuid.Parse(prize.TemplateID); err != nil {
+				return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+			}
+		}
+	}
+	return nil
+}
+
+type shakeRequest struct {
+	EventID string
+}
+
+func (req shakeRequest) validate() error {
+	if req.EventID == "" {
+		return errMissing("event_id")
+	} else {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type turnsRequest struct {
+	EventID string
+}
+
+func (req turnsRequest) validate() error {
+	if req.EventID == "" {
+		return errMissing("event_id")
+	} else {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type spendTurnRequest struct {
+	EventID string
+	Ref     string `json:"ref"`
+}
+
+func (req spendTurnRequest) validate() error {
+	if err := (turnsRequest{EventID: req.EventID}).validate(); err != nil {
+		return err
+	}
+	if len(req.Ref) > 64 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidRef)
+	}
+	return nil
+}
+
+type claimInviteRequest struct {
+	EventID string
+	Code    string `json:"code"`
+}
+
+func (req claimInviteRequest) validate() error {
+	if err := (turnsRequest{EventID: req.EventID}).validate(); err != nil {
+		return err
+	}
+	if req.Code == "" {
+		return errMissing("code")
+	}
+	if len(req.Code) != admin.InviteCodeLength {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidInviteCode)
+	}
+	return nil
+}
+
+type requestTurnRequest struct {
+	EventID string
+	Friend  string `json:"friend"`
+}
+
+func (req requestTurnRequest) validate() error {
+	if err := (turnsRequest{EventID: req.EventID}).validate(); err != nil {
+		return err
+	}
+	if req.Friend == "" {
+		return errMissing("friend")
+	}
+	if len(req.Friend) > 254 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidContact)
+	}
+	return nil
+}
+
+type turnRequestRequest struct {
+	ID string
+}
+
+func (req turnRequestRequest) validate() error {
+	if req.ID == "" {
+		return errMissing("turn_request_id")
+	} else {
+		if _, err := uuid.Parse(req.ID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type itemsRequest struct {
+	EventID string
+}
+
+func (req itemsRequest) validate() error {
+	if req.EventID == "" {
+		return errMissing("event_id")
+	} else {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type createItemRequest struct {
+	EventID string
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Image   string `json:"image"`
+	Rarity  string `json:"rarity"`
+}
+
+func (req createItemRequest) validate() error {
+	if err := (itemsRequest{EventID: req.EventID}).validate(); err != nil {
+		return err
+	}
+	if req.Code == "" {
+		return errMissing("code")
+	}
+	if req.Name == "" {
+		return errMissing("name")
+	}
+	if req.Rarity == "" {
+		return errMissing("rarity")
+	}
+	return admin.Item{Code: req.Code, Name: req.Name, Image: req.Image, Rarity: req.Rarity}.Validate()
+}
+
+type updateItemRequest struct {
+	EventID string
+	ItemID  string
+	Name    string `json:"name"`
+	Image   string `json:"image"`
+	Rarity  string `json:"rarity"`
+}
+
+func (req updateItemRequest) validate() error {
+	if err := (itemsRequest{EventID: req.EventID}).validate(); err != nil {
+		return err
+	}
+	if req.ItemID == "" {
+		return errMissing("item_id")
+	} else {
+		if _, err := uuid.Parse(req.ItemID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.Name == "" {
+		return errMissing("name")
+	}
+	if req.Rarity == "" {
+		return errMissing("rarity")
+	}
+	return admin.Item{Name: req.Name, Image: req.Image, Rarity: req.Rarity}.Validate()
+}
+
+type recipeRequest struct {
+	EventID     string
+	RecipeID    string
+	Name        string           `json:"name"`
+	TemplateID  string           `json:"template_id"`
+	Ingredients admin.ItemStacks `json:"ingredients"`
+}
+
+func (req recipeRequest) validate() error {
+	if err := (itemsRequest{EventID: req.EventID}).validate(); err != nil {
+		return err
+	}
+	if req.RecipeID != "" {
+		if _, err := uuid.Parse(req.RecipeID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.Name == "" {
+		return errMissing("name")
+	}
+	if req.TemplateID == "" {
+		return errMissing("template_id")
+	} else {
+		if _, err := uuid.Parse(req.TemplateID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return req.recipe().Validate()
+}
+
+func (req recipeRequest) recipe() admin.Recipe {
+	return admin.Recipe{
+		ID:          req.RecipeID,
+		EventID:     req.EventID,
+		Name:        req.Name,
+		TemplateID:  req.TemplateID,
+		Ingredients: req.Ingredients,
+	}
+}
+
+type recipeIDRequest struct {
+	EventID  string
+	RecipeID string
+}
+
+func (req recipeIDRequest) validate() error {
+	if err := (itemsRequest{EventID: req.EventID}).validate(); err != nil {
+		return err
+	}
+	if req.RecipeID == "" {
+		return errMissing("recipe_id")
+	} else {
+		if _, err := uuid.Parse(req.RecipeID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type giftItemsRequest struct {
+	EventID string
+	Friend  string           `json:"friend"`
+	Items   admin.ItemStacks `json:"items"`
+}
+
+func (req giftItemsRequest) validate() error {
+	if err := (requestTurnRequest{EventID: req.EventID, Friend: req.Friend}).validate(); err != nil {
+		return err
+	}
+	if len(req.Items) == 0 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrNoItems)
+	}
+	return req.Items.Validate(admin.MaxTradeStacks)
+}
+
+type offerItemTradeRequest struct {
+	EventID string
+	Friend  string           `json:"friend"`
+	Give    admin.ItemStacks `json:"give"`
+	Want    admin.ItemStacks `json:"want"`
+}
+
+func (req offerItemTradeRequest) validate() error {
+	if err := (requestTurnRequest{EventID: req.EventID, Friend: req.Friend}).validate(); err != nil {
+		return err
+	}
+	if len(req.Give) == 0 && len(req.Want) == 0 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrEmptyTrade)
+	}
+	if err := req.Give.Validate(admin.MaxTradeStacks); err != nil {
+		return err
+	}
+	return req.Want.Validate(admin.MaxTradeStacks)
+}
+
+type itemTradeRequest struct {
+	ID string
+}
+
+func (req itemTradeRequest) validate() error {
+	if req.ID == "" {
+		return errMissing("item_trade_id")
+	} else {
+		if _, err := uuid.Parse(req.ID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	return nil
+}
+
+type leaderboardRequest struct {
+	EventID string
+	Period  string
+	Date    time.Time
+	Limit   int
+	Radius  int
+}
+
+func (req leaderboardRequest) validate() error {
+	if req.EventID == "" {
+		return errMissing("event_id")
+	} else {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if !admin.ValidPeriod(req.Period) {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidPeriod)
+	}
+	if req.Limit < 0 || req.Limit > admin.MaxLeaderboardLimit {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidBoardLimit)
+	}
+	if req.Radius < 0 || req.Radius > admin.MaxLeaderboardRadius {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidRadius)
+	}
+	return nil
+}
+
+func (req leaderboardRequest) query() admin.LeaderboardQuery {
+	return admin.LeaderboardQuery{
+		EventID: req.EventID,
+		Period:  req.Period,
+		Date:    req.Date,
+		Limit:   req.Limit,
+		Radius:  req.Radius,
+	}
+}
+
+type standingsRequest struct {
+	EventID string
+	Limit   int
+	Offset  int
+}
+
+func (req standingsRequest) validate() error {
+	if req.EventID == "" {
+		return errMissing("event_id")
+	} else {
+		if _, err := uuid.Parse(req.EventID); err != nil {
+			return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidUUID)
+		}
+	}
+	if req.Limit < 0 || req.Limit > admin.MaxStandingsLimit {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidPageLimit)
+	}
+	if req.Offset < 0 {
+		return errors.Wrap(errors.ErrMalformedEntity, ErrInvalidOffset)
+	}
+	return nil
+}
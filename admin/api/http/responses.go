@@ -1,5 +1,7 @@
 package http
 
+import "time"
+
 type Response interface {
 	Code() int
 	Headers() map[string]string
@@ -11,3 +13,22 @@ type errorResponse struct {
 	Error   string `json:"error,omitempty"`
 	Code    int    `json:"code"`
 }
+
+// imageRes is a raw image body, written by encodeImageResponse.
+type imageRes struct {
+	contentType string
+	body        []byte
+}
+
+type voucherKeyRes struct {
+	Alg       string `json:"alg"`
+	PublicKey string `json:"public_key"`
+}
+
+// myRedemptionRes is a redemption attempt as shown to the voucher owner,
+// without the cashier and enterprise internals.
+type myRedemptionRes struct {
+	CreatedAt time.Time `json:"created_at"`
+	StoreID   string    `json:"store_id"`
+	Outcome   string    `json:"outcome"`
+}
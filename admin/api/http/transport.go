@@ -5,155 +5,188 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/resrrdttrt/VOU/admin"
 	"github.com/resrrdttrt/VOU/middlewares"
+	"github.com/resrrdttrt/VOU/pkg/auth"
 	"github.com/resrrdttrt/VOU/pkg/errors"
 
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/go-zoo/bone"
 )
 
-func MakeAdminHandler(svc admin.Service) http.Handler {
+func MakeAdminHandler(svc admin.Service, policy auth.Policy) http.Handler {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorEncoder(encodeError),
 	}
 
 	r := bone.New()
 
-	r.Get("/user", kithttp.NewServer(
+	r.Get("/user", middlewares.Authorize(policy, auth.UsersRead, kithttp.NewServer(
 		getAllUsersEndpoint(svc),
 		decodeNothingRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/user/:id", kithttp.NewServer(
+	)))
+	r.Get("/user/:id", middlewares.Authorize(policy, auth.UsersRead, kithttp.NewServer(
 		getUserEndpoint(svc),
 		decodeGetUserRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Post("/user", kithttp.NewServer(
+	)))
+	r.Post("/user", middlewares.Authorize(policy, auth.UsersWrite, kithttp.NewServer(
 		createUserEndpoint(svc),
 		decodeCreateUserRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Put("/user/:id", kithttp.NewServer(
+	)))
+	r.Put("/user/:id", middlewares.Authorize(policy, auth.UsersWrite, kithttp.NewServer(
 		updateUserEndpoint(svc),
 		decodeUpdateUserRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Delete("/user/:id", kithttp.NewServer(
+	)))
+	r.Delete("/user/:id", middlewares.Authorize(policy, auth.UsersDelete, kithttp.NewServer(
 		deleteUserEndpoint(svc),
 		decodeGetUserRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/user/active/:id", kithttp.NewServer(
+	)))
+	r.Get("/user/active/:id", middlewares.Authorize(policy, auth.UsersWrite, kithttp.NewServer(
 		activeUserEndpoint(svc),
 		decodeGetUserRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/user/deactive/:id", kithttp.NewServer(
+	)))
+	r.Get("/user/deactive/:id", middlewares.Authorize(policy, auth.UsersWrite, kithttp.NewServer(
 		deactiveUserEndpoint(svc),
 		decodeGetUserRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/game", kithttp.NewServer(
+	)))
+	r.Get("/game", middlewares.Authorize(policy, auth.GamesRead, kithttp.NewServer(
 		getAllGamesEndpoint(svc),
 		decodeNothingRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/game/:id", kithttp.NewServer(
+	)))
+	r.Get("/game/:id", middlewares.Authorize(policy, auth.GamesRead, kithttp.NewServer(
 		getGameEndpoint(svc),
 		decodeGetGameRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Post("/game", kithttp.NewServer(
+	)))
+	r.Post("/game", middlewares.Authorize(policy, auth.GamesWrite, kithttp.NewServer(
 		createGameEndpoint(svc),
 		decodeCreateGameRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Put("/game/:id", kithttp.NewServer(
+	)))
+	r.Put("/game/:id", middlewares.Authorize(policy, auth.GamesWrite, kithttp.NewServer(
 		updateGameEndpoint(svc),
 		decodeUpdateGameRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Delete("/game/:id", kithttp.NewServer(
+	)))
+	r.Delete("/game/:id", middlewares.Authorize(policy, auth.GamesDelete, kithttp.NewServer(
 		deleteGameEndpoint(svc),
 		decodeGetGameRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/statistic/total_users", kithttp.NewServer(
+	)))
+	r.Get("/statistic/total_users", middlewares.Authorize(policy, auth.StatisticsRead, kithttp.NewServer(
 		getTotalUsersEndpoint(svc),
 		decodeNothingRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/statistic/total_games", kithttp.NewServer(
+	)))
+	r.Get("/statistic/total_games", middlewares.Authorize(policy, auth.StatisticsRead, kithttp.NewServer(
 		getTotalGamesEndpoint(svc),
 		decodeNothingRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/statistic/total_enterprises", kithttp.NewServer(
+	)))
+	r.Get("/statistic/total_enterprises", middlewares.Authorize(policy, auth.StatisticsRead, kithttp.NewServer(
 		getTotalEnterprisesEndpoint(svc),
 		decodeNothingRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/statistic/total_end_users", kithttp.NewServer(
+	)))
+	r.Get("/statistic/total_end_users", middlewares.Authorize(policy, auth.StatisticsRead, kithttp.NewServer(
 		getTotalEndUserEndpoint(svc),
 		decodeNothingRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/statistic/total_active_end_users", kithttp.NewServer(
+	)))
+	r.Get("/statistic/total_active_end_users", middlewares.Authorize(policy, auth.StatisticsRead, kithttp.NewServer(
 		getTotalActiveEndUsersEndpoint(svc),
 		decodeNothingRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/statistic/total_active_enterprises", kithttp.NewServer(
+	)))
+	r.Get("/statistic/total_active_enterprises", middlewares.Authorize(policy, auth.StatisticsRead, kithttp.NewServer(
 		getTotalActiveEnterprisesEndpoint(svc),
 		decodeNothingRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/statistic/total_new_enterprises_in_time", kithttp.NewServer(
+	)))
+	r.Get("/statistic/total_new_enterprises_in_time", middlewares.Authorize(policy, auth.StatisticsRead, kithttp.NewServer(
 		getTotalNewEnterprisesInTimeEndpoint(svc),
 		decodeStatisticInTimeRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/statistic/total_new_end_users_in_time", kithttp.NewServer(
+	)))
+	r.Get("/statistic/total_new_end_users_in_time", middlewares.Authorize(policy, auth.StatisticsRead, kithttp.NewServer(
 		getTotalNewEndUsersInTimeEndpoint(svc),
 		decodeStatisticInTimeRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/statistic/total_new_end_users_in_week", kithttp.NewServer(
+	)))
+	r.Get("/statistic/total_new_end_users_in_week", middlewares.Authorize(policy, auth.StatisticsRead, kithttp.NewServer(
 		getTotalNewEndUsersInWeekEndpoint(svc),
 		decodeNothingRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/statistic/total_new_enterprises_in_week", kithttp.NewServer(
+	)))
+	r.Get("/statistic/total_new_enterprises_in_week", middlewares.Authorize(policy, auth.StatisticsRead, kithttp.NewServer(
 		getTotalNewEnterprisesInWeekEndpoint(svc),
 		decodeNothingRequest,
 		encodeResponse,
 		opts...,
-	))
-	handler := middlewares.VerifyAdminMiddleware(r)
+	)))
+	r.Get("/event", middlewares.Authorize(policy, auth.EventsReview, kithttp.NewServer(
+		getEventsByStatusEndpoint(svc),
+		decodeListEventsByStatusRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/event/:id/approve", middlewares.Authorize(policy, auth.EventsReview, kithttp.NewServer(
+		approveEventEndpoint(svc),
+		decodeEventStatusRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/event/:id/reject", middlewares.Authorize(policy, auth.EventsReview, kithttp.NewServer(
+		rejectEventEndpoint(svc),
+		decodeRejectEventRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/mfa/policies", middlewares.Authorize(policy, auth.SecurityRead, kithttp.NewServer(
+		getMFAPoliciesEndpoint(svc),
+		decodeNothingRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Put("/mfa/policies/:role", middlewares.Authorize(policy, auth.SecurityWrite, kithttp.NewServer(
+		setMFAPolicyEndpoint(svc),
+		decodeMFAPolicyRequest,
+		encodeResponse,
+		opts...,
+	)))
+	handler := middlewares.Authenticate(svc, r)
 	return handler
 }
 
@@ -171,12 +204,28 @@ func encodeResponse(_ context.Context, w http.ResponseWriter, response interface
 	return json.NewEncoder(w).Encode(response)
 }
 
+func encodeImageResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	res := response.(imageRes)
+	w.Header().Set("Content-Type", res.contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	_, err := w.Write(res.body)
+	return err
+}
+
 func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 	switch errorVal := err.(type) {
 	case errors.Error:
 		switch {
 		case errors.Contains(errorVal, errors.ErrNotFound):
 			w.WriteHeader(http.StatusNotFound)
+		case errors.Contains(errorVal, errors.ErrUnauthorized):
+			w.WriteHeader(http.StatusUnauthorized)
+		case errors.Contains(errorVal, errors.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Contains(errorVal, errors.ErrConflict):
+			w.WriteHeader(http.StatusConflict)
+		case errors.Contains(errorVal, errors.ErrTooManyRequests):
+			w.WriteHeader(http.StatusTooManyRequests)
 		case errors.Contains(errorVal, errors.ErrUnsupportedMediaType):
 			w.WriteHeader(http.StatusUnsupportedMediaType)
 		case errors.Contains(errorVal, errors.ErrMalformedEntity):
@@ -258,7 +307,6 @@ func decodeStatisticInTimeRequest(_ context.Context, r *http.Request) (interface
 	return req, nil
 }
 
-
 func MakeAuthHandler(svc admin.Service) http.Handler {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorEncoder(encodeError),
@@ -272,6 +320,66 @@ func MakeAuthHandler(svc admin.Service) http.Handler {
 		encodeResponse,
 		opts...,
 	))
+	r.Post("/login/mfa", kithttp.NewServer(
+		loginMFAEndpoint(svc),
+		decodeMFALoginRequest,
+		encodeResponse,
+		opts...,
+	))
+	r.Post("/login/mfa/enroll", kithttp.NewServer(
+		enrollMFALoginEndpoint(svc),
+		decodeMFAChallengeRequest,
+		encodeResponse,
+		opts...,
+	))
+	r.Post("/refresh", kithttp.NewServer(
+		refreshTokenEndpoint(svc),
+		decodeRefreshTokenRequest,
+		encodeResponse,
+		opts...,
+	))
+	r.Post("/logout", kithttp.NewServer(
+		logoutEndpoint(svc),
+		decodeLogoutRequest,
+		encodeResponse,
+		opts...,
+	))
+	r.Post("/logout/all", kithttp.NewServer(
+		logoutAllEndpoint(svc),
+		decodeLogoutRequest,
+		encodeResponse,
+		opts...,
+	))
+	r.Post("/register", kithttp.NewServer(
+		registerEndpoint(svc),
+		decodeRegisterRequest,
+		encodeResponse,
+		opts...,
+	))
+	r.Post("/register/verify", kithttp.NewServer(
+		verifyRegistrationEndpoint(svc),
+		decodeVerifyRegistrationRequest,
+		encodeResponse,
+		opts...,
+	))
+	r.Post("/register/resend", kithttp.NewServer(
+		resendVerificationEndpoint(svc),
+		decodeResendVerificationRequest,
+		encodeResponse,
+		opts...,
+	))
+	r.Post("/password/forgot", kithttp.NewServer(
+		forgotPasswordEndpoint(svc),
+		decodeForgotPasswordRequest,
+		encodeResponse,
+		opts...,
+	))
+	r.Post("/password/reset", kithttp.NewServer(
+		resetPasswordEndpoint(svc),
+		decodeResetPasswordRequest,
+		encodeResponse,
+		opts...,
+	))
 	return r
 }
 
@@ -283,33 +391,144 @@ func decodeLoginRequest(_ context.Context, r *http.Request) (interface{}, error)
 	return req, nil
 }
 
-func MakeEnterpriseHandler(svc admin.Service) http.Handler {
+func decodeRefreshTokenRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req refreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	return req, nil
+}
+
+func decodeRegisterRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req registerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	if req.Channel == "" {
+		req.Channel = admin.ChannelEmail
+	}
+	return req, nil
+}
+
+func decodeVerifyRegistrationRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req verifyRegistrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	return req, nil
+}
+
+func decodeResendVerificationRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req resendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	if req.Channel == "" {
+		req.Channel = admin.ChannelEmail
+	}
+	return req, nil
+}
+
+func decodeForgotPasswordRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req forgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	if req.Channel == "" {
+		req.Channel = admin.ChannelEmail
+	}
+	return req, nil
+}
+
+func decodeResetPasswordRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	return req, nil
+}
+
+func decodeMFALoginRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req mfaLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	return req, nil
+}
+
+func decodeMFAChallengeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req mfaChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	return req, nil
+}
+
+func decodeMFACodeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	return req, nil
+}
+
+func decodeDisableMFARequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req disableMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	return req, nil
+}
+
+func decodeMFAPolicyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req mfaPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.Role = bone.GetValue(r, "role")
+	return req, nil
+}
+
+func decodeLogoutRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := logoutRequest{
+		AccessToken: r.Header.Get("Authorization"),
+	}
+	return req, nil
+}
+
+func MakeEnterpriseHandler(svc admin.Service, policy auth.Policy) http.Handler {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorEncoder(encodeError),
 	}
 
 	r := bone.New()
 
-	r.Post("/", kithttp.NewServer(
+	r.Post("/", middlewares.Authorize(policy, auth.EnterpriseWrite, kithttp.NewServer(
 		registerEnterpriseEndpoint(svc),
 		decodeEnterpriseRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/", kithttp.NewServer(
+	)))
+	r.Get("/", middlewares.Authorize(policy, auth.EnterpriseRead, kithttp.NewServer(
 		getEnterpriseInfoEndpoint(svc),
 		decodeNothingRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Put("/", kithttp.NewServer(
+	)))
+	r.Put("/", middlewares.Authorize(policy, auth.EnterpriseWrite, kithttp.NewServer(
 		updateEnterpriseInfoEndpoint(svc),
 		decodeEnterpriseRequest,
 		encodeResponse,
 		opts...,
-	))
+	)))
+	r.Get("/voucher_key", middlewares.Authorize(policy, auth.EnterpriseRead, kithttp.NewServer(
+		getVoucherPublicKeyEndpoint(svc),
+		decodeNothingRequest,
+		encodeResponse,
+		opts...,
+	)))
 
-	handler := middlewares.VerifyRoleMiddleware(r)
+	handler := middlewares.Authenticate(svc, r)
 	return handler
 }
 
@@ -321,72 +540,235 @@ func decodeEnterpriseRequest(_ context.Context, r *http.Request) (interface{}, e
 	return req, nil
 }
 
-
-func MakeEventHandler(svc admin.Service) http.Handler {
+func MakeEventHandler(svc admin.Service, policy auth.Policy) http.Handler {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorEncoder(encodeError),
 	}
-	// TODO GetEventByTime
 	r := bone.New()
-	r.Get("/", kithttp.NewServer(
+	r.Get("/", middlewares.Authorize(policy, auth.EventsRead, kithttp.NewServer(
 		getAllEventsEndpoint(svc),
-		decodeNothingRequest,
+		decodeListEventsRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/:id", kithttp.NewServer(
+	)))
+	r.Get("/:id", middlewares.Authorize(policy, auth.EventsRead, kithttp.NewServer(
 		getEventByIDEndpoint(svc),
 		decodeGetEventIDRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Post("/", kithttp.NewServer(
+	)))
+	r.Post("/", middlewares.Authorize(policy, auth.EventsWrite, kithttp.NewServer(
 		createEventEndpoint(svc),
 		decodeCreateEventRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Put("/:id", kithttp.NewServer(
+	)))
+	r.Put("/:id", middlewares.Authorize(policy, auth.EventsWrite, kithttp.NewServer(
 		updateEventEndpoint(svc),
 		decodeUpdateEventRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/:id/voucher", kithttp.NewServer(
+	)))
+	r.Post("/:id/submit", middlewares.Authorize(policy, auth.EventsWrite, kithttp.NewServer(
+		submitEventEndpoint(svc),
+		decodeEventStatusRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/:id/cancel", middlewares.Authorize(policy, auth.EventsWrite, kithttp.NewServer(
+		cancelEventEndpoint(svc),
+		decodeEventStatusRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/:id/voucher", middlewares.Authorize(policy, auth.VouchersRead, kithttp.NewServer(
 		getAllVouchersByEventIDEndpoint(svc),
 		decodeGetEventIDRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Get("/:id/voucher/:voucher_id", kithttp.NewServer(
+	)))
+	r.Get("/:id/voucher/:voucher_id", middlewares.Authorize(policy, auth.VouchersRead, kithttp.NewServer(
 		getVoucherByIDEndpoint(svc),
 		decodeGetVoucherByIDRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Post("/:id/voucher", kithttp.NewServer(
+	)))
+	r.Post("/:id/voucher", middlewares.Authorize(policy, auth.VouchersWrite, kithttp.NewServer(
 		createVoucherEndpoint(svc),
 		decodeCreateVoucherRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Put("/:id/voucher/:voucher_id", kithttp.NewServer(
+	)))
+	r.Put("/:id/voucher/:voucher_id", middlewares.Authorize(policy, auth.VouchersWrite, kithttp.NewServer(
 		updateVoucherEndpoint(svc),
 		decodeUpdateVoucherRequest,
 		encodeResponse,
 		opts...,
-	))
-	r.Delete("/:id/voucher/:voucher_id", kithttp.NewServer(
+	)))
+	r.Delete("/:id/voucher/:voucher_id", middlewares.Authorize(policy, auth.VouchersDelete, kithttp.NewServer(
 		deleteVoucherEndpoint(svc),
 		decodeGetVoucherByIDRequest,
 		encodeResponse,
 		opts...,
-	))
-	// TODO: Verify eventID
-	handler := middlewares.VerifyRoleMiddleware(r)
+	)))
+	r.Get("/:id/voucher/:voucher_id/qrcode", middlewares.Authorize(policy, auth.VouchersRead, kithttp.NewServer(
+		getVoucherQRCodeEndpoint(svc),
+		decodeGetVoucherQRCodeRequest,
+		encodeImageResponse,
+		opts...,
+	)))
+	r.Get("/:id/voucher/:voucher_id/redemptions", middlewares.Authorize(policy, auth.VouchersRead, kithttp.NewServer(
+		getVoucherRedemptionsEndpoint(svc),
+		decodeGetVoucherByIDRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/:id/voucher_batch", middlewares.Authorize(policy, auth.VouchersWrite, kithttp.NewServer(
+		generateVouchersEndpoint(svc),
+		decodeGenerateVouchersRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/:id/voucher_batch/:batch_id", middlewares.Authorize(policy, auth.VouchersRead, kithttp.NewServer(
+		getVoucherBatchEndpoint(svc),
+		decodeGetVoucherBatchRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/:id/inventory", middlewares.Authorize(policy, auth.VouchersRead, kithttp.NewServer(
+		getVoucherTemplatesEndpoint(svc),
+		decodeGetEventIDRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/:id/inventory", middlewares.Authorize(policy, auth.VouchersWrite, kithttp.NewServer(
+		createVoucherTemplateEndpoint(svc),
+		decodeCreateVoucherTemplateRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Put("/:id/inventory/:template_id", middlewares.Authorize(policy, auth.VouchersWrite, kithttp.NewServer(
+		updateVoucherTemplateEndpoint(svc),
+		decodeUpdateVoucherTemplateRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/:id/inventory/:template_id/issue", middlewares.Authorize(policy, auth.VouchersWrite, kithttp.NewServer(
+		issueVoucherEndpoint(svc),
+		decodeIssueVoucherRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Put("/:id/quiz", middlewares.Authorize(policy, auth.QuizWrite, kithttp.NewServer(
+		attachQuestionSetEndpoint(svc),
+		decodeAttachQuestionSetRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/:id/prizes", middlewares.Authorize(policy, auth.EventsRead, kithttp.NewServer(
+		getPrizeTableEndpoint(svc),
+		decodeShakeRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Put("/:id/prizes", middlewares.Authorize(policy, auth.EventsWrite, kithttp.NewServer(
+		setPrizeTableEndpoint(svc),
+		decodeSetPrizeTableRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/:id/draws", middlewares.Authorize(policy, auth.EventsRead, kithttp.NewServer(
+		getDrawsEndpoint(svc),
+		decodeShakeRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/:id/draws/audit", middlewares.Authorize(policy, auth.EventsRead, kithttp.NewServer(
+		auditDrawsEndpoint(svc),
+		decodeShakeRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/:id/items", middlewares.Authorize(policy, auth.EventsRead, kithttp.NewServer(
+		getItemsEndpoint(svc),
+		decodeItemsRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/:id/items", middlewares.Authorize(policy, auth.EventsWrite, kithttp.NewServer(
+		createItemEndpoint(svc),
+		decodeCreateItemRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Put("/:id/items/:item_id", middlewares.Authorize(policy, auth.EventsWrite, kithttp.NewServer(
+		updateItemEndpoint(svc),
+		decodeUpdateItemRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/:id/recipes", middlewares.Authorize(policy, auth.EventsRead, kithttp.NewServer(
+		getRecipesEndpoint(svc),
+		decodeItemsRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/:id/recipes", middlewares.Authorize(policy, auth.EventsWrite, kithttp.NewServer(
+		createRecipeEndpoint(svc),
+		decodeRecipeRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Put("/:id/recipes/:recipe_id", middlewares.Authorize(policy, auth.EventsWrite, kithttp.NewServer(
+		updateRecipeEndpoint(svc),
+		decodeRecipeRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Delete("/:id/recipes/:recipe_id", middlewares.Authorize(policy, auth.EventsWrite, kithttp.NewServer(
+		deleteRecipeEndpoint(svc),
+		decodeRecipeIDRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/:id/leaderboard", middlewares.Authorize(policy, auth.EventsRead, kithttp.NewServer(
+		getEventLeaderboardEndpoint(svc),
+		decodeLeaderboardRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/:id/standings", middlewares.Authorize(policy, auth.EventsRead, kithttp.NewServer(
+		getEventStandingsEndpoint(svc),
+		decodeStandingsRequest,
+		encodeResponse,
+		opts...,
+	)))
+	handler := middlewares.Authenticate(svc, r)
 	return handler
 }
 
+// decodeListEventsRequest reads the optional `start` and `end` query
+// parameters (RFC 3339) that restrict the listing to a time window.
+func decodeListEventsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req listEventsRequest
+	q := r.URL.Query()
+	if v := q.Get("start"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+		req.Start = t
+	}
+	if v := q.Get("end"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+		req.End = t
+	}
+	return req, nil
+}
+
 func decodeGetEventIDRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	var req getEventIDRequest
 	id := bone.GetValue(r, "id")
@@ -399,8 +781,11 @@ func decodeCreateEventRequest(ctx context.Context, r *http.Request) (interface{}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
 	}
-	userID := ctx.Value("userID").(string)
-	req.UserID = userID
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	req.UserID = p.EnterpriseID
 	return req, nil
 }
 
@@ -411,8 +796,42 @@ func decodeUpdateEventRequest(ctx context.Context, r *http.Request) (interface{}
 	}
 	id := bone.GetValue(r, "id")
 	req.ID = id
-	userID := ctx.Value("userID").(string)
-	req.UserID = userID
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	req.UserID = p.EnterpriseID
+	return req, nil
+}
+
+// decodeEventStatusRequest reads an optional JSON body carrying a reason.
+func decodeEventStatusRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req eventStatusRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+	}
+	req.ID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+func decodeRejectEventRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req rejectEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.ID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+func decodeListEventsByStatusRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := listEventsByStatusRequest{
+		Status: r.URL.Query().Get("status"),
+	}
+	if req.Status == "" {
+		req.Status = admin.EventPendingReview
+	}
 	return req, nil
 }
 
@@ -447,16 +866,850 @@ func decodeUpdateVoucherRequest(ctx context.Context, r *http.Request) (interface
 	return req, nil
 }
 
+// decodeGenerateVouchersRequest fills in the default code format, ten
+// unambiguous alphanumerics without a checksum, for fields the body omits.
+// decodeGetVoucherQRCodeRequest reads the optional `format` (png or svg,
+// default png) and `size` (pixels, default 256) query parameters.
+func decodeGetVoucherQRCodeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := getVoucherQRCodeRequest{
+		Format: admin.QRCodePNG,
+		Size:   256,
+	}
+	req.ID = bone.GetValue(r, "voucher_id")
+	req.EventID = bone.GetValue(r, "id")
+	q := r.URL.Query()
+	if v := q.Get("format"); v != "" {
+		req.Format = v
+	}
+	if v := q.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+		req.Size = size
+	}
+	return req, nil
+}
+
+func decodeGenerateVouchersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := generateVouchersRequest{
+		Format: admin.CodeFormat{
+			Length:   10,
+			Charset:  admin.CharsetAlphanumeric,
+			Checksum: admin.ChecksumNone,
+		},
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
+}
 
+func decodeGetVoucherBatchRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req getVoucherBatchRequest
+	req.ID = bone.GetValue(r, "batch_id")
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
+}
 
-func MakeHandler (svc admin.Service) http.Handler {
-	r := bone.New()
-	adminHandler := MakeAdminHandler(svc)
-	authHandler := MakeAuthHandler(svc)
-	enterpriseHandler := MakeEnterpriseHandler(svc)
-	r.SubRoute("/enterprise", enterpriseHandler)
-	r.SubRoute("/admin", adminHandler)
-	r.SubRoute("/auth", authHandler)
-	return r
+func decodeCreateVoucherTemplateRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req createVoucherTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
 }
 
+func decodeUpdateVoucherTemplateRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req updateVoucherTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.ID = bone.GetValue(r, "template_id")
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+func decodeIssueVoucherRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req issueVoucherRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.TemplateID = bone.GetValue(r, "template_id")
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+// MakeWalletHandler serves the vouchers of the calling end user.
+func MakeWalletHandler(svc admin.Service, policy auth.Policy) http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	r := bone.New()
+
+	r.Get("/vouchers", middlewares.Authorize(policy, auth.WalletRead, kithttp.NewServer(
+		listMyVouchersEndpoint(svc),
+		decodeListMyVouchersRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/vouchers/:id", middlewares.Authorize(policy, auth.WalletRead, kithttp.NewServer(
+		getMyVoucherEndpoint(svc),
+		decodeMyVoucherRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/vouchers/:id/qrcode", middlewares.Authorize(policy, auth.WalletRead, kithttp.NewServer(
+		getMyVoucherQRCodeEndpoint(svc),
+		decodeMyVoucherQRCodeRequest,
+		encodeImageResponse,
+		opts...,
+	)))
+	r.Get("/vouchers/:id/redemptions", middlewares.Authorize(policy, auth.WalletRead, kithttp.NewServer(
+		getMyVoucherRedemptionsEndpoint(svc),
+		decodeMyVoucherRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/vouchers/:id/transfers", middlewares.Authorize(policy, auth.WalletRead, kithttp.NewServer(
+		getMyVoucherTransfersEndpoint(svc),
+		decodeMyVoucherRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/vouchers/:id/transfer", middlewares.Authorize(policy, auth.WalletWrite, kithttp.NewServer(
+		offerVoucherEndpoint(svc),
+		decodeOfferVoucherRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/transfers", middlewares.Authorize(policy, auth.WalletRead, kithttp.NewServer(
+		getMyTransfersEndpoint(svc),
+		decodeNothingRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/transfers/claim", middlewares.Authorize(policy, auth.WalletWrite, kithttp.NewServer(
+		claimTransferEndpoint(svc),
+		decodeClaimTransferRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/transfers/:id/accept", middlewares.Authorize(policy, auth.WalletWrite, kithttp.NewServer(
+		acceptTransferEndpoint(svc),
+		decodeTransferRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/transfers/:id/decline", middlewares.Authorize(policy, auth.WalletWrite, kithttp.NewServer(
+		declineTransferEndpoint(svc),
+		decodeTransferRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/transfers/:id/cancel", middlewares.Authorize(policy, auth.WalletWrite, kithttp.NewServer(
+		cancelTransferEndpoint(svc),
+		decodeTransferRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/mfa/enroll", middlewares.Authorize(policy, auth.AccountMFA, kithttp.NewServer(
+		enrollMFAEndpoint(svc),
+		decodeNothingRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/mfa/confirm", middlewares.Authorize(policy, auth.AccountMFA, kithttp.NewServer(
+		confirmMFAEndpoint(svc),
+		decodeMFACodeRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/mfa/disable", middlewares.Authorize(policy, auth.AccountMFA, kithttp.NewServer(
+		disableMFAEndpoint(svc),
+		decodeDisableMFARequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/mfa/recovery-codes", middlewares.Authorize(policy, auth.AccountMFA, kithttp.NewServer(
+		regenerateRecoveryCodesEndpoint(svc),
+		decodeMFACodeRequest,
+		encodeResponse,
+		opts...,
+	)))
+
+	handler := middlewares.Authenticate(svc, r)
+	return handler
+}
+
+// decodeListMyVouchersRequest reads the optional `status`, `event_id`,
+// `expires_after`, `expires_before` (RFC 3339), `cursor` and `limit` query
+// parameters.
+func decodeListMyVouchersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	req := listMyVouchersRequest{
+		Status:  q.Get("status"),
+		EventID: q.Get("event_id"),
+		Cursor:  q.Get("cursor"),
+	}
+	if v := q.Get("expires_after"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+		req.ExpiresAfter = t
+	}
+	if v := q.Get("expires_before"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+		req.ExpiresBefore = t
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+		req.Limit = limit
+	}
+	return req, nil
+}
+
+// decodeOfferVoucherRequest accepts an empty body, which offers the voucher
+// as a claim link.
+func decodeOfferVoucherRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req offerVoucherRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+	}
+	req.VoucherID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+func decodeTransferRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := transferRequest{
+		ID: bone.GetValue(r, "id"),
+	}
+	return req, nil
+}
+
+func decodeClaimTransferRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req claimTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	return req, nil
+}
+
+func decodeMyVoucherRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := myVoucherRequest{
+		ID: bone.GetValue(r, "id"),
+	}
+	return req, nil
+}
+
+func decodeMyVoucherQRCodeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := myVoucherQRCodeRequest{
+		Format: admin.QRCodePNG,
+		Size:   256,
+	}
+	req.ID = bone.GetValue(r, "id")
+	q := r.URL.Query()
+	if v := q.Get("format"); v != "" {
+		req.Format = v
+	}
+	if v := q.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+		req.Size = size
+	}
+	return req, nil
+}
+
+func decodeAttachQuestionSetRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req attachQuestionSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+// MakeQuizHandler serves the question banks of an enterprise.
+func MakeQuizHandler(svc admin.Service, policy auth.Policy) http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	r := bone.New()
+
+	r.Get("/sets", middlewares.Authorize(policy, auth.QuizRead, kithttp.NewServer(
+		getQuestionSetsEndpoint(svc),
+		decodeNothingRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/sets/:id", middlewares.Authorize(policy, auth.QuizRead, kithttp.NewServer(
+		getQuestionSetEndpoint(svc),
+		decodeQuestionSetRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/sets", middlewares.Authorize(policy, auth.QuizWrite, kithttp.NewServer(
+		createQuestionSetEndpoint(svc),
+		decodeCreateQuestionSetRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Put("/sets/:id", middlewares.Authorize(policy, auth.QuizWrite, kithttp.NewServer(
+		updateQuestionSetEndpoint(svc),
+		decodeUpdateQuestionSetRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/sets/:id/questions", middlewares.Authorize(policy, auth.QuizWrite, kithttp.NewServer(
+		createQuestionEndpoint(svc),
+		decodeQuestionRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Put("/sets/:id/questions/:question_id", middlewares.Authorize(policy, auth.QuizWrite, kithttp.NewServer(
+		updateQuestionEndpoint(svc),
+		decodeQuestionRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Delete("/sets/:id/questions/:question_id", middlewares.Authorize(policy, auth.QuizWrite, kithttp.NewServer(
+		deleteQuestionEndpoint(svc),
+		decodeDeleteQuestionRequest,
+		encodeResponse,
+		opts...,
+	)))
+
+	handler := middlewares.Authenticate(svc, r)
+	return handler
+}
+
+func decodeQuestionSetRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := questionSetRequest{
+		ID: bone.GetValue(r, "id"),
+	}
+	return req, nil
+}
+
+func decodeCreateQuestionSetRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req createQuestionSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	return req, nil
+}
+
+func decodeUpdateQuestionSetRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req updateQuestionSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.ID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+func decodeQuestionRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req questionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.SetID = bone.GetValue(r, "id")
+	req.ID = bone.GetValue(r, "question_id")
+	return req, nil
+}
+
+func decodeDeleteQuestionRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := deleteQuestionRequest{
+		SetID: bone.GetValue(r, "id"),
+		ID:    bone.GetValue(r, "question_id"),
+	}
+	return req, nil
+}
+
+// MakePlayHandler serves the games end users play in running events.
+func MakePlayHandler(svc admin.Service, policy auth.Policy) http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	r := bone.New()
+
+	r.Post("/events/:id/quiz", middlewares.Authorize(policy, auth.QuizPlay, kithttp.NewServer(
+		startQuizEndpoint(svc),
+		decodeQuizRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/quiz", middlewares.Authorize(policy, auth.QuizPlay, kithttp.NewServer(
+		getQuizEndpoint(svc),
+		decodeQuizRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/events/:id/quiz/answer", middlewares.Authorize(policy, auth.QuizPlay, kithttp.NewServer(
+		answerQuizEndpoint(svc),
+		decodeAnswerQuizRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/quiz/answers", middlewares.Authorize(policy, auth.QuizPlay, kithttp.NewServer(
+		getQuizAnswersEndpoint(svc),
+		decodeQuizRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/live", middlewares.Authorize(policy, auth.QuizPlay, forwardLiveQuiz(svc, serveLiveQuiz(svc))))
+	r.Get("/events/:id/live/leaderboard", middlewares.Authorize(policy, auth.QuizPlay, forwardLiveQuiz(svc, kithttp.NewServer(
+		getLiveLeaderboardEndpoint(svc),
+		decodeQuizRequest,
+		encodeResponse,
+		opts...,
+	))))
+	r.Post("/events/:id/shake", middlewares.Authorize(policy, auth.ShakePlay, kithttp.NewServer(
+		shakeEndpoint(svc),
+		decodeShakeRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/shake", middlewares.Authorize(policy, auth.ShakePlay, kithttp.NewServer(
+		getShakeEndpoint(svc),
+		decodeShakeRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/turns", middlewares.Authorize(policy, auth.TurnsRead, kithttp.NewServer(
+		getMyTurnsEndpoint(svc),
+		decodeTurnsRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/turns/history", middlewares.Authorize(policy, auth.TurnsRead, kithttp.NewServer(
+		getMyTurnHistoryEndpoint(svc),
+		decodeTurnsRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/events/:id/turns/spend", middlewares.Authorize(policy, auth.TurnsWrite, kithttp.NewServer(
+		spendTurnEndpoint(svc),
+		decodeSpendTurnRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/events/:id/turns/share", middlewares.Authorize(policy, auth.TurnsWrite, kithttp.NewServer(
+		shareEventEndpoint(svc),
+		decodeTurnsRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/turns/invite", middlewares.Authorize(policy, auth.TurnsRead, kithttp.NewServer(
+		getInviteCodeEndpoint(svc),
+		decodeTurnsRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/events/:id/turns/invite", middlewares.Authorize(policy, auth.TurnsWrite, kithttp.NewServer(
+		claimInviteEndpoint(svc),
+		decodeClaimInviteRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/events/:id/turns/requests", middlewares.Authorize(policy, auth.TurnsWrite, kithttp.NewServer(
+		requestTurnEndpoint(svc),
+		decodeRequestTurnRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/turns/requests", middlewares.Authorize(policy, auth.TurnsRead, kithttp.NewServer(
+		getMyTurnRequestsEndpoint(svc),
+		decodeTurnsRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/turn-requests/:id/accept", middlewares.Authorize(policy, auth.TurnsWrite, kithttp.NewServer(
+		acceptTurnRequestEndpoint(svc),
+		decodeTurnRequestRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/turn-requests/:id/decline", middlewares.Authorize(policy, auth.TurnsWrite, kithttp.NewServer(
+		declineTurnRequestEndpoint(svc),
+		decodeTurnRequestRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/turn-requests/:id/cancel", middlewares.Authorize(policy, auth.TurnsWrite, kithttp.NewServer(
+		cancelTurnRequestEndpoint(svc),
+		decodeTurnRequestRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/items", middlewares.Authorize(policy, auth.ItemsRead, kithttp.NewServer(
+		getItemCatalogEndpoint(svc),
+		decodeItemsRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/items/mine", middlewares.Authorize(policy, auth.ItemsRead, kithttp.NewServer(
+		getMyItemsEndpoint(svc),
+		decodeItemsRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/events/:id/items/gift", middlewares.Authorize(policy, auth.ItemsWrite, kithttp.NewServer(
+		giftItemsEndpoint(svc),
+		decodeGiftItemsRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/events/:id/items/trades", middlewares.Authorize(policy, auth.ItemsWrite, kithttp.NewServer(
+		offerItemTradeEndpoint(svc),
+		decodeOfferItemTradeRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/items/trades", middlewares.Authorize(policy, auth.ItemsRead, kithttp.NewServer(
+		getMyItemTradesEndpoint(svc),
+		decodeItemsRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/events/:id/recipes/:recipe_id/exchange", middlewares.Authorize(policy, auth.ItemsWrite, kithttp.NewServer(
+		exchangeRecipeEndpoint(svc),
+		decodeRecipeIDRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/item-trades/:id/accept", middlewares.Authorize(policy, auth.ItemsWrite, kithttp.NewServer(
+		acceptItemTradeEndpoint(svc),
+		decodeItemTradeRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/item-trades/:id/decline", middlewares.Authorize(policy, auth.ItemsWrite, kithttp.NewServer(
+		declineItemTradeEndpoint(svc),
+		decodeItemTradeRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Post("/item-trades/:id/cancel", middlewares.Authorize(policy, auth.ItemsWrite, kithttp.NewServer(
+		cancelItemTradeEndpoint(svc),
+		decodeItemTradeRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/leaderboard", middlewares.Authorize(policy, auth.LeaderboardRead, kithttp.NewServer(
+		getLeaderboardEndpoint(svc),
+		decodeLeaderboardRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/leaderboard/me", middlewares.Authorize(policy, auth.LeaderboardRead, kithttp.NewServer(
+		getMyRankEndpoint(svc),
+		decodeLeaderboardRequest,
+		encodeResponse,
+		opts...,
+	)))
+	r.Get("/events/:id/standings", middlewares.Authorize(policy, auth.LeaderboardRead, kithttp.NewServer(
+		getStandingsEndpoint(svc),
+		decodeStandingsRequest,
+		encodeResponse,
+		opts...,
+	)))
+
+	handler := middlewares.Authenticate(svc, r)
+	return middlewares.WebSocketToken(handler)
+}
+
+func decodeQuizRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := quizRequest{
+		EventID: bone.GetValue(r, "id"),
+	}
+	return req, nil
+}
+
+func decodeAnswerQuizRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req answerQuizRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+func decodeShakeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := shakeRequest{
+		EventID: bone.GetValue(r, "id"),
+	}
+	return req, nil
+}
+
+func decodeTurnsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := turnsRequest{
+		EventID: bone.GetValue(r, "id"),
+	}
+	return req, nil
+}
+
+// decodeSpendTurnRequest reads an optional JSON body carrying a ref.
+func decodeSpendTurnRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req spendTurnRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+	}
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+func decodeClaimInviteRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req claimInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+func decodeRequestTurnRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req requestTurnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+func decodeTurnRequestRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := turnRequestRequest{
+		ID: bone.GetValue(r, "id"),
+	}
+	return req, nil
+}
+
+func decodeSetPrizeTableRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req setPrizeTableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+func decodeItemsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := itemsRequest{
+		EventID: bone.GetValue(r, "id"),
+	}
+	return req, nil
+}
+
+func decodeCreateItemRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req createItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+func decodeUpdateItemRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req updateItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.EventID = bone.GetValue(r, "id")
+	req.ItemID = bone.GetValue(r, "item_id")
+	return req, nil
+}
+
+func decodeRecipeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req recipeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.EventID = bone.GetValue(r, "id")
+	req.RecipeID = bone.GetValue(r, "recipe_id")
+	return req, nil
+}
+
+func decodeRecipeIDRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := recipeIDRequest{
+		EventID:  bone.GetValue(r, "id"),
+		RecipeID: bone.GetValue(r, "recipe_id"),
+	}
+	return req, nil
+}
+
+func decodeGiftItemsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req giftItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+func decodeOfferItemTradeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req offerItemTradeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.EventID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+func decodeItemTradeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := itemTradeRequest{
+		ID: bone.GetValue(r, "id"),
+	}
+	return req, nil
+}
+
+// decodeLeaderboardRequest reads the `period` (event by default), `date`
+// (YYYY-MM-DD, today by default), `limit` and `radius` query parameters.
+func decodeLeaderboardRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	req := leaderboardRequest{
+		EventID: bone.GetValue(r, "id"),
+		Period:  q.Get("period"),
+	}
+	if req.Period == "" {
+		req.Period = admin.PeriodEvent
+	}
+	if v := q.Get("date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+		req.Date = t
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+		req.Limit = limit
+	}
+	if v := q.Get("radius"); v != "" {
+		radius, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+		req.Radius = radius
+	}
+	return req, nil
+}
+
+func decodeStandingsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	req := standingsRequest{
+		EventID: bone.GetValue(r, "id"),
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+		req.Limit = limit
+	}
+	if v := q.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+		}
+		req.Offset = offset
+	}
+	return req, nil
+}
+
+// MakeVoucherHandler serves voucher operations addressed by voucher ID alone.
+func MakeVoucherHandler(svc admin.Service, policy auth.Policy) http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	r := bone.New()
+
+	r.Post("/:id/quote", middlewares.Authorize(policy, auth.VouchersRedeem, kithttp.NewServer(
+		quoteVoucherEndpoint(svc),
+		decodeQuoteVoucherRequest,
+		encodeResponse,
+		opts...,
+	)))
+
+	handler := middlewares.Authenticate(svc, r)
+	return handler
+}
+
+func decodeQuoteVoucherRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req quoteVoucherRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	req.ID = bone.GetValue(r, "id")
+	return req, nil
+}
+
+// MakeRedemptionHandler serves the point-of-sale API used by cashiers.
+func MakeRedemptionHandler(svc admin.Service, policy auth.Policy) http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+
+	r := bone.New()
+
+	r.Post("/", middlewares.Authorize(policy, auth.VouchersRedeem, kithttp.NewServer(
+		redeemVoucherEndpoint(svc),
+		decodeRedeemVoucherRequest,
+		encodeResponse,
+		opts...,
+	)))
+
+	handler := middlewares.Authenticate(svc, r)
+	return handler
+}
+
+func decodeRedeemVoucherRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req redeemVoucherRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.Wrap(errors.ErrMalformedEntity, err)
+	}
+	return req, nil
+}
+
+func MakeHandler(svc admin.Service, policy auth.Policy) http.Handler {
+	r := bone.New()
+	adminHandler := MakeAdminHandler(svc, policy)
+	authHandler := MakeAuthHandler(svc)
+	enterpriseHandler := MakeEnterpriseHandler(svc, policy)
+	eventHandler := MakeEventHandler(svc, policy)
+	redemptionHandler := MakeRedemptionHandler(svc, policy)
+	voucherHandler := MakeVoucherHandler(svc, policy)
+	walletHandler := MakeWalletHandler(svc, policy)
+	quizHandler := MakeQuizHandler(svc, policy)
+	playHandler := MakePlayHandler(svc, policy)
+	r.SubRoute("/enterprise", enterpriseHandler)
+	r.SubRoute("/event", eventHandler)
+	r.SubRoute("/admin", adminHandler)
+	r.SubRoute("/auth", authHandler)
+	r.SubRoute("/redeem", redemptionHandler)
+	r.SubRoute("/vouchers", voucherHandler)
+	r.SubRoute("/me", walletHandler)
+	r.SubRoute("/quiz", quizHandler)
+	r.SubRoute("/play", playHandler)
+	return r
+}
@@ -0,0 +1,102 @@
+// Package argon2 provides a hasher implementation utilizing argon2id.
+package argon2
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	prefix = "$argon2id$"
+
+	DefTime    = 1
+	DefMemory  = 64 * 1024
+	DefThreads = 4
+	saltLen    = 16
+	keyLen     = 32
+)
+
+var (
+	errHashPassword    = errors.New("Generate hash from password failed")
+	errComparePassword = errors.New("Compare hash and password failed")
+	errMalformedHash   = errors.New("Malformed argon2id hash")
+)
+
+var _ admin.PasswordHasher = (*argon2Hasher)(nil)
+
+type argon2Hasher struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+// New instantiates an argon2id-based hasher implementation. Memory is
+// expressed in KiB.
+func New(time, memory uint32, threads uint8) admin.PasswordHasher {
+	if time == 0 {
+		time = DefTime
+	}
+	if memory == 0 {
+		memory = DefMemory
+	}
+	if threads == 0 {
+		threads = DefThreads
+	}
+	return &argon2Hasher{
+		time:    time,
+		memory:  memory,
+		threads: threads,
+	}
+}
+
+func (ah *argon2Hasher) Hash(pwd string) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.Wrap(errHashPassword, err)
+	}
+	key := argon2.IDKey([]byte(pwd), salt, ah.time, ah.memory, ah.threads, keyLen)
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", prefix, argon2.Version, ah.memory, ah.time, ah.threads, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+func (ah *argon2Hasher) Compare(plain, hashed string) error {
+	// $argon2id$v=19$m=65536,t=1,p=4$<salt>$<key>
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || !ah.Recognizes(hashed) {
+		return errors.Wrap(errComparePassword, errMalformedHash)
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return errors.Wrap(errComparePassword, errMalformedHash)
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return errors.Wrap(errComparePassword, errMalformedHash)
+	}
+	enc := base64.RawStdEncoding
+	salt, err := enc.DecodeString(parts[4])
+	if err != nil {
+		return errors.Wrap(errComparePassword, errMalformedHash)
+	}
+	key, err := enc.DecodeString(parts[5])
+	if err != nil {
+		return errors.Wrap(errComparePassword, errMalformedHash)
+	}
+	other := argon2.IDKey([]byte(plain), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return errComparePassword
+	}
+	return nil
+}
+
+func (ah *argon2Hasher) Recognizes(hashed string) bool {
+	return strings.HasPrefix(hashed, prefix)
+}
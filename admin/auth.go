@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/resrrdttrt/VOU/pkg/auth"
 	"github.com/resrrdttrt/VOU/pkg/common"
 )
 
@@ -29,7 +32,7 @@ func ConnectToPostgres() {
 
 func GetUserIDByAccessToken(accessToken string) (string, error) {
 	var userID string
-	query := `SELECT user_id FROM access_tokens WHERE token = $1`
+	query := `SELECT user_id FROM access_tokens WHERE token = $1 AND revoked_at IS NULL AND expires_at > NOW()`
 
 	err := DB.QueryRow(query, accessToken).Scan(&userID)
 	if err != nil {
@@ -73,12 +76,43 @@ func GetUserIDByEventID(eventID string) (string, error) {
 	return userID, nil
 }
 
+// Token is the result of a login. When the user must also enter a TOTP code,
+// only MFA is set, and the access token comes from answering the challenge.
+// RecoveryCodes is only set by the login that completes an enforced MFA
+// enrollment.
 type Token struct {
-	AccessToken string `json:"access_token"`
+	AccessToken   string        `json:"access_token,omitempty"`
+	RefreshToken  string        `json:"refresh_token,omitempty"`
+	ExpiresIn     int64         `json:"expires_in,omitempty"`
+	MFA           *MFAChallenge `json:"mfa,omitempty"`
+	RecoveryCodes []string      `json:"recovery_codes,omitempty"`
+}
+
+// TokenConfig holds the lifetimes of issued access and refresh tokens.
+type TokenConfig struct {
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
 }
 
 type AuthRepository interface {
-	Login(ctx context.Context, username, password string) (Token, error)
+	// CreateAccessToken issues a new access/refresh token pair for the user.
+	// mfa records whether the login passed two-factor authentication.
+	CreateAccessToken(ctx context.Context, userID string, mfa bool, cfg TokenConfig) (Token, error)
+	// RefreshAccessToken revokes the pair owning refreshToken and issues a new
+	// one with the same mfa flag, returning the ID of the user the pair
+	// belongs to. Nothing changes unless check accepts the user and flag of
+	// the pair.
+	RefreshAccessToken(ctx context.Context, refreshToken string, cfg TokenConfig, check func(userID string, mfa bool) error) (string, Token, error)
+	RevokeAccessToken(ctx context.Context, accessToken string) error
+	RevokeAllAccessTokens(ctx context.Context, userID string) error
+	// DeleteDeadAccessTokens removes pairs whose refresh token expired, and
+	// revoked pairs once their access token expired too.
+	DeleteDeadAccessTokens(ctx context.Context) (int64, error)
+	// GetRevokedAccessTokens returns the revoked access tokens that have not
+	// expired yet.
+	GetRevokedAccessTokens(ctx context.Context) ([]string, error)
+	// GetPrincipalByAccessToken resolves a live token together with its owner.
+	GetPrincipalByAccessToken(ctx context.Context, accessToken string) (auth.Principal, error)
 	GetUserIDByAccessToken(accessToken string) (string, error)
 	GetUserRoleByID(userID string) (string, error)
 }
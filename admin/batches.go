@@ -0,0 +1,69 @@
+package admin
+
+import (
+	"context"
+	"time"
+)
+
+// Voucher batch statuses.
+const (
+	BatchPending   = "pending"
+	BatchRunning   = "running"
+	BatchCompleted = "completed"
+	BatchFailed    = "failed"
+)
+
+// MaxBatchSize is the largest number of vouchers one batch may generate.
+const MaxBatchSize = 1000000
+
+// VoucherBatch is a background job that generates Count vouchers with codes
+// in one CodeFormat. Generated reports the progress of the job, and Attempt
+// counts how many times a stalled run of it was resumed.
+type VoucherBatch struct {
+	ID          string        `db:"id" json:"id,omitempty"`
+	EventID     string        `db:"event_id" json:"event_id"`
+	Count       int           `db:"count" json:"count"`
+	Generated   int           `db:"generated" json:"generated"`
+	Status      string        `db:"status" json:"status"`
+	Error       string        `db:"error" json:"error,omitempty"`
+	Prefix      string        `db:"prefix" json:"prefix"`
+	Length      int           `db:"code_length" json:"length"`
+	Charset     string        `db:"charset" json:"charset"`
+	Checksum    string        `db:"checksum" json:"checksum"`
+	Images      string        `db:"images" json:"images"`
+	Value       int           `db:"value" json:"value"`
+	Description string        `db:"description" json:"description"`
+	ExpiredTime time.Time     `db:"expired_time" json:"expired_time"`
+	Rule        *DiscountRule `db:"rule" json:"rule,omitempty"`
+	Attempt     int           `db:"attempt" json:"-"`
+	CreatedAt   time.Time     `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt   time.Time     `db:"updated_at" json:"updated_at,omitempty"`
+}
+
+// Format returns the code format of the batch.
+func (b VoucherBatch) Format() CodeFormat {
+	return CodeFormat{
+		Prefix:   b.Prefix,
+		Length:   b.Length,
+		Charset:  b.Charset,
+		Checksum: b.Checksum,
+	}
+}
+
+type VoucherBatchRepository interface {
+	// CreateBatch fails with ErrInventoryExceeded when the event has no room
+	// left in its voucher_num for the whole batch.
+	CreateBatch(ctx context.Context, batch VoucherBatch) (string, error)
+	GetBatch(ctx context.Context, id string, eventID string) (VoucherBatch, error)
+	// UpdateBatch records the progress of the run attempt of the batch. It
+	// fails with ErrBatchTakenOver once another run has resumed the batch.
+	UpdateBatch(ctx context.Context, id string, attempt int, status string, generated int, errMsg string) error
+	// ClaimStaleBatches hands over the pending and running batches not
+	// updated within timeout to a new run: it bumps their attempt and resets
+	// generated to the vouchers already stored.
+	ClaimStaleBatches(ctx context.Context, timeout time.Duration) ([]VoucherBatch, error)
+	// InsertBatchVouchers stores one voucher of the batch per code and returns
+	// how many were inserted. Codes that already exist are skipped. It fails
+	// with ErrInventoryExceeded when the batch would outgrow its count.
+	InsertBatchVouchers(ctx context.Context, batch VoucherBatch, codes []string) (int, error)
+}
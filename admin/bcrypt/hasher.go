@@ -0,0 +1,51 @@
+// Package bcrypt provides a hasher implementation utilizing bcrypt.
+package bcrypt
+
+import (
+	"strings"
+
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const DefCost = 10
+
+var (
+	errHashPassword    = errors.New("Generate hash from password failed")
+	errComparePassword = errors.New("Compare hash and password failed")
+)
+
+var _ admin.PasswordHasher = (*bcryptHasher)(nil)
+
+type bcryptHasher struct {
+	cost int
+}
+
+// New instantiates a bcrypt-based hasher implementation.
+func New(cost int) admin.PasswordHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = DefCost
+	}
+	return &bcryptHasher{cost: cost}
+}
+
+func (bh *bcryptHasher) Hash(pwd string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pwd), bh.cost)
+	if err != nil {
+		return "", errors.Wrap(errHashPassword, err)
+	}
+	return string(hash), nil
+}
+
+func (bh *bcryptHasher) Compare(plain, hashed string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(plain))
+	if err != nil {
+		return errors.Wrap(errComparePassword, err)
+	}
+	return nil
+}
+
+func (bh *bcryptHasher) Recognizes(hashed string) bool {
+	return strings.HasPrefix(hashed, "$2a$") || strings.HasPrefix(hashed, "$2b$") || strings.HasPrefix(hashed, "$2y$")
+}
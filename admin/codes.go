@@ -0,0 +1,153 @@
+package admin
+
+import (
+	"crypto/rand"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+// Character sets a generated voucher code can be drawn from.
+const (
+	CharsetAlphanumeric = "alphanumeric"
+	CharsetAlpha        = "alpha"
+	CharsetNumeric      = "numeric"
+)
+
+// Checksums that can be appended to a generated voucher code.
+const (
+	ChecksumNone = "none"
+	ChecksumLuhn = "luhn"
+)
+
+const (
+	// MinCodeLength and MaxCodeLength bound the random part of a code.
+	MinCodeLength = 4
+	MaxCodeLength = 32
+	// MaxCodePrefix bounds the length of the fixed code prefix.
+	MaxCodePrefix = 16
+)
+
+var (
+	// ErrInvalidCodeFormat indicates a code format that cannot be generated.
+	ErrInvalidCodeFormat = errors.Wrap(errors.ErrMalformedEntity, errors.New("code format needs a known charset and checksum, a length of 4 to 32 and a prefix of at most 16 letters, digits or dashes"))
+
+	// ErrCodeSpaceTooSmall indicates that a code format cannot produce as many
+	// distinct codes as were requested.
+	ErrCodeSpaceTooSmall = errors.Wrap(errors.ErrMalformedEntity, errors.New("code format has too few combinations for the requested count"))
+)
+
+var charsets = map[string]string{
+	// Letters and digits that are easily confused (0/O, 1/I/L) are left out.
+	CharsetAlphanumeric: "ABCDEFGHJKMNPQRSTUVWXYZ23456789",
+	CharsetAlpha:        "ABCDEFGHJKMNPQRSTUVWXYZ",
+	CharsetNumeric:      "0123456789",
+}
+
+// CodeFormat describes how voucher codes are generated: a fixed prefix, a
+// random part of Length characters from Charset and an optional check
+// character computed over the random part.
+type CodeFormat struct {
+	Prefix   string `json:"prefix"`
+	Length   int    `json:"length"`
+	Charset  string `json:"charset"`
+	Checksum string `json:"checksum"`
+}
+
+// Validate checks that the format can be generated.
+func (f CodeFormat) Validate() error {
+	if _, ok := charsets[f.Charset]; !ok {
+		return ErrInvalidCodeFormat
+	}
+	if f.Checksum != ChecksumNone && f.Checksum != ChecksumLuhn {
+		return ErrInvalidCodeFormat
+	}
+	if f.Length < MinCodeLength || f.Length > MaxCodeLength {
+		return ErrInvalidCodeFormat
+	}
+	if len(f.Prefix) > MaxCodePrefix {
+		return ErrInvalidCodeFormat
+	}
+	for _, c := range f.Prefix {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return ErrInvalidCodeFormat
+		}
+	}
+	return nil
+}
+
+// Capacity returns the number of distinct codes the format can produce,
+// capped at math.MaxInt64.
+func (f CodeFormat) Capacity() int64 {
+	space := math.Pow(float64(len(charsets[f.Charset])), float64(f.Length))
+	if space >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(space)
+}
+
+// Generate returns a random code in the format.
+func (f CodeFormat) Generate() (string, error) {
+	alphabet := charsets[f.Charset]
+	max := big.NewInt(int64(len(alphabet)))
+	body := make([]byte, f.Length)
+	for i := range body {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		body[i] = alphabet[n.Int64()]
+	}
+	code := f.Prefix + string(body)
+	if f.Checksum == ChecksumLuhn {
+		code += string(luhnCheck(string(body), alphabet))
+	}
+	return code, nil
+}
+
+// Matches reports whether code could have been generated by the format,
+// including a valid check character.
+func (f CodeFormat) Matches(code string) bool {
+	alphabet, ok := charsets[f.Charset]
+	if !ok || !strings.HasPrefix(code, f.Prefix) {
+		return false
+	}
+	body := code[len(f.Prefix):]
+	check := ""
+	if f.Checksum == ChecksumLuhn {
+		if len(body) == 0 {
+			return false
+		}
+		body, check = body[:len(body)-1], body[len(body)-1:]
+	}
+	if len(body) != f.Length {
+		return false
+	}
+	for i := 0; i < len(body); i++ {
+		if strings.IndexByte(alphabet, body[i]) < 0 {
+			return false
+		}
+	}
+	return check == "" || check[0] == luhnCheck(body, alphabet)
+}
+
+// luhnCheck computes the Luhn mod N check character of s over alphabet. For
+// the numeric charset this is the classic Luhn digit.
+func luhnCheck(s string, alphabet string) byte {
+	n := len(alphabet)
+	factor := 2
+	sum := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(alphabet, s[i])
+		addend = addend/n + addend%n
+		sum += addend
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+	}
+	return alphabet[(n-sum%n)%n]
+}
@@ -0,0 +1,247 @@
+package admin
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+// Discount rule types.
+const (
+	DiscountPercent  = "percent"
+	DiscountFixed    = "fixed"
+	DiscountBuyXGetY = "buy_x_get_y"
+	DiscountFreeItem = "free_item"
+)
+
+// maxDiscountStores bounds the store list of a rule.
+const maxDiscountStores = 100
+
+// DiscountRule says what a voucher is worth against a basket. Money amounts
+// are in minor units (cents) of Currency.
+type DiscountRule struct {
+	Type string `json:"type"`
+	// Percent off the basket subtotal, capped at Cap when Cap is set.
+	Percent int   `json:"percent,omitempty"`
+	Cap     int64 `json:"cap,omitempty"`
+	// Amount off the basket subtotal.
+	Amount   int64  `json:"amount,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	// SKU of the item given for free. For buy_x_get_y, GetQuantity of every
+	// BuyQuantity + GetQuantity units of SKU are free.
+	SKU         string `json:"sku,omitempty"`
+	BuyQuantity int    `json:"buy_quantity,omitempty"`
+	GetQuantity int    `json:"get_quantity,omitempty"`
+	// MinSpend is the subtotal the basket must reach.
+	MinSpend int64 `json:"min_spend,omitempty"`
+	// Stores lists the stores the voucher can be used at. Empty means any.
+	Stores []string `json:"stores,omitempty"`
+}
+
+func errInvalidRule(reason string) error {
+	return errors.Wrap(errors.ErrMalformedEntity, errors.New("invalid discount rule: "+reason))
+}
+
+// Validate checks that the rule is complete and consistent.
+func (r DiscountRule) Validate() error {
+	switch r.Type {
+	case DiscountPercent:
+		if r.Percent < 1 || r.Percent > 100 {
+			return errInvalidRule("percent must be between 1 and 100")
+		}
+		if r.Cap < 0 {
+			return errInvalidRule("cap must not be negative")
+		}
+	case DiscountFixed:
+		if r.Amount <= 0 {
+			return errInvalidRule("amount must be positive")
+		}
+		if r.Currency == "" {
+			return errInvalidRule("fixed discounts need a currency")
+		}
+	case DiscountBuyXGetY:
+		if r.SKU == "" {
+			return errInvalidRule("sku is required")
+		}
+		if r.BuyQuantity < 1 || r.GetQuantity < 1 {
+			return errInvalidRule("buy_quantity and get_quantity must be at least 1")
+		}
+	case DiscountFreeItem:
+		if r.SKU == "" {
+			return errInvalidRule("sku is required")
+		}
+	default:
+		return errInvalidRule("type must be percent, fixed, buy_x_get_y or free_item")
+	}
+	if r.Currency != "" && !validCurrency(r.Currency) {
+		return errInvalidRule("currency must be an ISO 4217 code")
+	}
+	if r.MinSpend < 0 {
+		return errInvalidRule("min_spend must not be negative")
+	}
+	if (r.MinSpend > 0 || r.Cap > 0) && r.Currency == "" {
+		return errInvalidRule("cap and min_spend need a currency")
+	}
+	if len(r.Stores) > maxDiscountStores {
+		return errInvalidRule(fmt.Sprintf("at most %d stores are allowed", maxDiscountStores))
+	}
+	for _, store := range r.Stores {
+		if store == "" {
+			return errInvalidRule("stores must not be empty")
+		}
+	}
+	return nil
+}
+
+func validCurrency(c string) bool {
+	if len(c) != 3 {
+		return false
+	}
+	for i := 0; i < 3; i++ {
+		if c[i] < 'A' || c[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Value stores the rule as JSONB.
+func (r DiscountRule) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+// Scan reads the rule from JSONB.
+func (r *DiscountRule) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	default:
+		return fmt.Errorf("cannot scan %T into DiscountRule", src)
+	}
+}
+
+// BasketItem is one line of a basket. UnitPrice is in minor units.
+type BasketItem struct {
+	SKU       string `json:"sku"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int64  `json:"unit_price"`
+}
+
+// Basket is what a customer is about to pay for at a store.
+type Basket struct {
+	StoreID  string       `json:"store_id"`
+	Currency string       `json:"currency"`
+	Items    []BasketItem `json:"items"`
+}
+
+// Subtotal returns the basket total before discounts.
+func (b Basket) Subtotal() int64 {
+	var total int64
+	for _, item := range b.Items {
+		total += int64(item.Quantity) * item.UnitPrice
+	}
+	return total
+}
+
+// FreeItem is an item a voucher gives away.
+type FreeItem struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+// Quote is the outcome of applying a voucher to a basket. When Applicable
+// is false, Reason says why.
+type Quote struct {
+	Applicable bool       `json:"applicable"`
+	Reason     string     `json:"reason,omitempty"`
+	Subtotal   int64      `json:"subtotal"`
+	Discount   int64      `json:"discount"`
+	Total      int64      `json:"total"`
+	Currency   string     `json:"currency"`
+	FreeItems  []FreeItem `json:"free_items,omitempty"`
+}
+
+// Apply computes the discount r grants on b.
+func (r DiscountRule) Apply(b Basket) Quote {
+	q := Quote{
+		Subtotal: b.Subtotal(),
+		Currency: b.Currency,
+	}
+	q.Total = q.Subtotal
+	if len(r.Stores) > 0 && !contains(r.Stores, b.StoreID) {
+		q.Reason = "voucher is not valid at this store"
+		return q
+	}
+	if r.Currency != "" && r.Currency != b.Currency {
+		q.Reason = "voucher is in " + r.Currency
+		return q
+	}
+	if q.Subtotal < r.MinSpend {
+		q.Reason = fmt.Sprintf("basket is below the minimum spend of %d", r.MinSpend)
+		return q
+	}
+
+	switch r.Type {
+	case DiscountPercent:
+		q.Discount = q.Subtotal * int64(r.Percent) / 100
+		if r.Cap > 0 && q.Discount > r.Cap {
+			q.Discount = r.Cap
+		}
+	case DiscountFixed:
+		q.Discount = r.Amount
+	case DiscountBuyXGetY:
+		quantity, price := skuQuantity(b, r.SKU)
+		free := quantity / (r.BuyQuantity + r.GetQuantity) * r.GetQuantity
+		if free == 0 {
+			q.Reason = fmt.Sprintf("buy %d of %s to get %d free", r.BuyQuantity, r.SKU, r.GetQuantity)
+			return q
+		}
+		q.Discount = int64(free) * price
+		q.FreeItems = []FreeItem{{SKU: r.SKU, Quantity: free}}
+	case DiscountFreeItem:
+		// The item is handed over either way; if it was rung up, it is free.
+		if quantity, price := skuQuantity(b, r.SKU); quantity > 0 {
+			q.Discount = price
+		}
+		q.FreeItems = []FreeItem{{SKU: r.SKU, Quantity: 1}}
+	}
+	if q.Discount > q.Subtotal {
+		q.Discount = q.Subtotal
+	}
+	q.Applicable = true
+	q.Total = q.Subtotal - q.Discount
+	return q
+}
+
+// skuQuantity returns how many units of sku the basket holds and the lowest
+// unit price they were rung up at, so free units are the cheapest ones.
+func skuQuantity(b Basket, sku string) (int, int64) {
+	quantity := 0
+	var price int64 = -1
+	for _, item := range b.Items {
+		if item.SKU != sku {
+			continue
+		}
+		quantity += item.Quantity
+		if price < 0 || item.UnitPrice < price {
+			price = item.UnitPrice
+		}
+	}
+	if price < 0 {
+		price = 0
+	}
+	return quantity, price
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
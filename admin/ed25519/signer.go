@@ -0,0 +1,86 @@
+// Package ed25519 provides a voucher signer implementation utilizing Ed25519.
+//
+// A payload has the form `VOU1.<claims>.<signature>`, where claims is the
+// JSON encoded admin.VoucherClaims and both parts are base64url encoded
+// without padding. Anyone holding the public key can verify a payload
+// offline.
+package ed25519
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+// Version prefixes every payload so the format can evolve.
+const Version = "VOU1"
+
+var errMissingPrivateKey = errors.New("signer has no private key")
+
+var _ admin.VoucherSigner = (*signer)(nil)
+
+type signer struct {
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+// New instantiates an Ed25519 based voucher signer.
+func New(key ed25519.PrivateKey) admin.VoucherSigner {
+	return &signer{
+		private: key,
+		public:  key.Public().(ed25519.PublicKey),
+	}
+}
+
+// NewVerifier instantiates a signer that can only verify payloads. It is
+// meant for scanners that hold nothing but the public key.
+func NewVerifier(key ed25519.PublicKey) admin.VoucherSigner {
+	return &signer{
+		public: key,
+	}
+}
+
+func (s *signer) Sign(claims admin.VoucherClaims) (string, error) {
+	if s.private == nil {
+		return "", errMissingPrivateKey
+	}
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	body := Version + "." + base64.RawURLEncoding.EncodeToString(data)
+	sig := ed25519.Sign(s.private, []byte(body))
+	return body + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func (s *signer) Verify(payload string) (admin.VoucherClaims, error) {
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 || parts[0] != Version {
+		return admin.VoucherClaims{}, admin.ErrMalformedVoucherPayload
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return admin.VoucherClaims{}, admin.ErrMalformedVoucherPayload
+	}
+	body := parts[0] + "." + parts[1]
+	if !ed25519.Verify(s.public, []byte(body), sig) {
+		return admin.VoucherClaims{}, admin.ErrInvalidVoucherSignature
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return admin.VoucherClaims{}, admin.ErrMalformedVoucherPayload
+	}
+	var claims admin.VoucherClaims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return admin.VoucherClaims{}, admin.ErrMalformedVoucherPayload
+	}
+	return claims, nil
+}
+
+func (s *signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.public)
+}
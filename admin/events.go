@@ -5,23 +5,75 @@ import (
 	"time"
 )
 
+// Event lifecycle states.
+const (
+	EventDraft         = "draft"
+	EventPendingReview = "pending_review"
+	EventApproved      = "approved"
+	EventRunning       = "running"
+	EventEnded         = "ended"
+	EventCancelled     = "cancelled"
+)
+
+// eventTransitions lists the states each state may move to. Moving an
+// approved event to running and a running event to ended happens on a
+// schedule, based on StartTime and EndTime.
+var eventTransitions = map[string][]string{
+	EventDraft:         {EventPendingReview, EventCancelled},
+	EventPendingReview: {EventApproved, EventDraft, EventCancelled},
+	EventApproved:      {EventRunning, EventEnded, EventCancelled},
+	EventRunning:       {EventEnded, EventCancelled},
+}
+
+// CanTransition reports whether an event may move from one state to another.
+func CanTransition(from, to string) bool {
+	for _, s := range eventTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
 type Event struct {
-	ID         string    `db:"id" json:"id,omitempty"`
-	Name       string    `db:"name" json:"name"`
-	Images     string    `db:"images" json:"images"`
-	VoucherNum int       `db:"voucher_num" json:"voucher_num"`
-	StartTime  time.Time `db:"start_time" json:"start_time"`
-	EndTime    time.Time `db:"end_time" json:"end_time"`
-	GameID     string    `db:"game_id" json:"game_id"`
-	UserID     string    `db:"user_id" json:"user_id"`
-	CreatedAt  time.Time `db:"created_at" json:"created_at,omitempty"`
-	UpdatedAt  time.Time `db:"updated_at" json:"updated_at,omitempty"`
+	ID           string    `db:"id" json:"id,omitempty"`
+	Name         string    `db:"name" json:"name"`
+	Images       string    `db:"images" json:"images"`
+	VoucherNum   int       `db:"voucher_num" json:"voucher_num"`
+	StartTime    time.Time `db:"start_time" json:"start_time"`
+	EndTime      time.Time `db:"end_time" json:"end_time"`
+	GameID       string    `db:"game_id" json:"game_id"`
+	UserID       string    `db:"user_id" json:"user_id"`
+	Status       string    `db:"status" json:"status,omitempty"`
+	StatusReason string    `db:"status_reason" json:"status_reason,omitempty"`
+	// QuestionSetID is the question set played by events of quiz games.
+	QuestionSetID string     `db:"question_set_id" json:"question_set_id,omitempty"`
+	QuizMode      string     `db:"quiz_mode" json:"quiz_mode,omitempty"`
+	QuizPrizes    QuizPrizes `db:"quiz_prizes" json:"quiz_prizes,omitempty"`
+	// PlayTurns is how many free turns each player gets a day.
+	PlayTurns int       `db:"play_turns" json:"play_turns,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at,omitempty"`
 }
 
 type EventRepository interface {
 	GetEventByID(ctx context.Context, id string, enterprise_id string) (Event, error)
 	CreateEvent(ctx context.Context, event Event) error
-	UpdateEvent(ctx context.Context, event Event) error
+	// UpdateEvent locks the event, lets update turn it into the changes to
+	// store and stores them, all in one transaction. Zero fields of the
+	// changes are left as they are. It fails with ErrEventNotFound, and with
+	// ErrInventoryExceeded when voucher_num would drop below the vouchers
+	// the event's templates, batches and single vouchers hold.
+	UpdateEvent(ctx context.Context, id string, update func(current Event) (Event, error)) error
 	GetAllEventsByEnterpriseID(ctx context.Context, enterprise_id string) ([]Event, error)
 	GetEventByTime(ctx context.Context, enterprise_id string, start time.Time, end time.Time) ([]Event, error)
+	// GetEvent returns the event regardless of which enterprise owns it.
+	GetEvent(ctx context.Context, id string) (Event, error)
+	GetEventsByStatus(ctx context.Context, status string) ([]Event, error)
+	// UpdateEventStatus moves the event to status `to` only if it is still in
+	// status `from`.
+	UpdateEventStatus(ctx context.Context, id, from, to, reason string) error
+	// AdvanceEventStatuses starts approved events whose start time has passed
+	// and ends events whose end time has passed.
+	AdvanceEventStatuses(ctx context.Context) (int64, error)
 }
@@ -0,0 +1,45 @@
+// Package file provides a notifier implementation that writes messages to a
+// file or the log instead of delivering them, for local runs.
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+var errWriteMessage = errors.New("Write message failed")
+
+var _ admin.Notifier = (*fileNotifier)(nil)
+
+type fileNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// New instantiates a notifier that writes every message to w as a line of
+// JSON. It accepts every channel.
+func New(w io.Writer) admin.Notifier {
+	return &fileNotifier{w: w}
+}
+
+func (n *fileNotifier) Notify(_ context.Context, msg admin.Message) error {
+	line, err := json.Marshal(struct {
+		SentAt time.Time `json:"sent_at"`
+		admin.Message
+	}{time.Now(), msg})
+	if err != nil {
+		return errors.Wrap(errWriteMessage, err)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, err := n.w.Write(append(line, '\n')); err != nil {
+		return errors.Wrap(errWriteMessage, err)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package admin
+
+// PasswordHasher specifies an API for generating and comparing password hashes.
+type PasswordHasher interface {
+	// Hash generates the hashed string from plain-text.
+	Hash(string) (string, error)
+
+	// Compare compares plain-text version to the hashed one. An error should
+	// indicate failed comparison.
+	Compare(plain string, hashed string) error
+
+	// Recognizes reports whether the stored value was produced by this hasher.
+	// Values that are not recognized are treated as legacy plain-text passwords.
+	Recognizes(hashed string) bool
+}
@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"time"
+)
+
+const (
+	voucherCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	voucherCodeLength   = 12
+)
+
+// VoucherTemplate describes a kind of voucher an event hands out and how many
+// of them it may issue. Issued vouchers are stored as Voucher rows that point
+// back to their template and are owned by a player.
+type VoucherTemplate struct {
+	ID          string        `db:"id" json:"id,omitempty"`
+	EventID     string        `db:"event_id" json:"event_id"`
+	Name        string        `db:"name" json:"name"`
+	Images      string        `db:"images" json:"images"`
+	Value       int           `db:"value" json:"value"`
+	Description string        `db:"description" json:"description"`
+	ExpiredTime time.Time     `db:"expired_time" json:"expired_time"`
+	Quantity    int           `db:"quantity" json:"quantity"`
+	Issued      int           `db:"issued" json:"issued"`
+	Rule        *DiscountRule `db:"rule" json:"rule,omitempty"`
+	CreatedAt   time.Time     `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt   time.Time     `db:"updated_at" json:"updated_at,omitempty"`
+}
+
+// Remaining returns how many vouchers of the template can still be issued.
+func (t VoucherTemplate) Remaining() int {
+	return t.Quantity - t.Issued
+}
+
+type InventoryRepository interface {
+	GetTemplatesByEventID(ctx context.Context, eventID string) ([]VoucherTemplate, error)
+	GetTemplateByID(ctx context.Context, id string, eventID string) (VoucherTemplate, error)
+	// CreateTemplate fails with ErrInventoryExceeded if the quantities of the
+	// event's templates, together with its vouchers created one by one or in
+	// batches, would add up to more than Event.VoucherNum.
+	CreateTemplate(ctx context.Context, template VoucherTemplate) (string, error)
+	// UpdateTemplateQuantity fails with ErrInventoryExceeded under the same
+	// rule, and when the quantity would drop below what was already issued.
+	UpdateTemplateQuantity(ctx context.Context, id string, eventID string, quantity int) error
+	// IssueVoucher takes one voucher out of the template's stock and stores it
+	// as owned by userID. It fails with ErrOutOfStock when nothing is left.
+	IssueVoucher(ctx context.Context, templateID string, eventID string, userID string, code string) (Voucher, error)
+}
+
+// GenerateVoucherCode returns a random code made of characters that are hard
+// to confuse when read aloud or typed at a till.
+func GenerateVoucherCode() (string, error) {
+	code := make([]byte, voucherCodeLength)
+	max := big.NewInt(int64(len(voucherCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = voucherCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
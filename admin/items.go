@@ -0,0 +1,229 @@
+package admin
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+// Item rarities, from the most to the least common.
+const (
+	RarityCommon    = "common"
+	RarityUncommon  = "uncommon"
+	RarityRare      = "rare"
+	RarityEpic      = "epic"
+	RarityLegendary = "legendary"
+)
+
+// Item trade statuses. A trade starts pending and ends in exactly one of the
+// other states.
+const (
+	ItemTradePending   = "pending"
+	ItemTradeAccepted  = "accepted"
+	ItemTradeDeclined  = "declined"
+	ItemTradeCancelled = "cancelled"
+)
+
+// Bounds of recipes and item stacks.
+const (
+	MaxIngredients   = 20
+	MaxItemQuantity  = 1000
+	MaxTradeStacks   = 20
+	MaxItemCodeLen   = 64
+	MaxItemNameLen   = 254
+	MaxItemImageLen  = 2048
+	MaxRecipeNameLen = 254
+)
+
+var itemCodePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidRarity reports whether r is a known rarity.
+func ValidRarity(r string) bool {
+	switch r {
+	case RarityCommon, RarityUncommon, RarityRare, RarityEpic, RarityLegendary:
+		return true
+	}
+	return false
+}
+
+// ValidItemCode reports whether code can name an item: lower case letters,
+// digits, '-' and '_'.
+func ValidItemCode(code string) bool {
+	return len(code) <= MaxItemCodeLen && itemCodePattern.MatchString(code)
+}
+
+// Item is a collectible of an event's catalog. Players hold items by Code,
+// which is unique in the event and never changes.
+type Item struct {
+	ID        string    `db:"id" json:"id,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at,omitempty"`
+	EventID   string    `db:"event_id" json:"event_id,omitempty"`
+	Code      string    `db:"code" json:"code"`
+	Name      string    `db:"name" json:"name"`
+	Image     string    `db:"image" json:"image"`
+	Rarity    string    `db:"rarity" json:"rarity"`
+}
+
+func errInvalidItem(reason string) error {
+	return errors.Wrap(errors.ErrMalformedEntity, errors.New("invalid item: "+reason))
+}
+
+// Validate checks the fields an enterprise sets on a catalog item. The code
+// is only checked when set, as it cannot change once the item exists.
+func (i Item) Validate() error {
+	if i.Code != "" && !ValidItemCode(i.Code) {
+		return errInvalidItem(fmt.Sprintf("code must be 1 to %d lower case letters, digits, '-' and '_'", MaxItemCodeLen))
+	}
+	if strings.TrimSpace(i.Name) == "" || len(i.Name) > MaxItemNameLen {
+		return errInvalidItem(fmt.Sprintf("name must be 1 to %d characters", MaxItemNameLen))
+	}
+	if len(i.Image) > MaxItemImageLen {
+		return errInvalidItem(fmt.Sprintf("image must be at most %d characters", MaxItemImageLen))
+	}
+	if !ValidRarity(i.Rarity) {
+		return errInvalidItem("rarity must be common, uncommon, rare, epic or legendary")
+	}
+	return nil
+}
+
+// ItemStack is a quantity of one item.
+type ItemStack struct {
+	ItemCode string `json:"item_code"`
+	Quantity int    `json:"quantity"`
+}
+
+// ItemStacks is stored as JSONB.
+type ItemStacks []ItemStack
+
+func (s ItemStacks) Value() (driver.Value, error) {
+	if s == nil {
+		s = ItemStacks{}
+	}
+	return json.Marshal(s)
+}
+
+func (s *ItemStacks) Scan(src interface{}) error {
+	return scanJSON(src, s)
+}
+
+func errInvalidItems(reason string) error {
+	return errors.Wrap(errors.ErrMalformedEntity, errors.New("invalid items: "+reason))
+}
+
+// Validate checks that the stacks name distinct items, at most max of them,
+// each in a positive quantity.
+func (s ItemStacks) Validate(max int) error {
+	if len(s) > max {
+		return errInvalidItems(fmt.Sprintf("at most %d stacks are allowed", max))
+	}
+	seen := map[string]bool{}
+	for _, stack := range s {
+		if !ValidItemCode(stack.ItemCode) {
+			return errInvalidItems("item_code must be lower case letters, digits, '-' and '_'")
+		}
+		if seen[stack.ItemCode] {
+			return errInvalidItems("each item can appear once")
+		}
+		if stack.Quantity < 1 || stack.Quantity > MaxItemQuantity {
+			return errInvalidItems(fmt.Sprintf("quantity must be between 1 and %d", MaxItemQuantity))
+		}
+		seen[stack.ItemCode] = true
+	}
+	return nil
+}
+
+// InventoryItem is what a player holds of an item, with its catalog entry.
+type InventoryItem struct {
+	EventID   string    `db:"event_id" json:"event_id"`
+	ItemCode  string    `db:"item_code" json:"item_code"`
+	Quantity  int       `db:"quantity" json:"quantity"`
+	Name      string    `db:"name" json:"name"`
+	Image     string    `db:"image" json:"image"`
+	Rarity    string    `db:"rarity" json:"rarity"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Recipe exchanges a set of items for a voucher of one of the event's
+// templates.
+type Recipe struct {
+	ID          string     `db:"id" json:"id,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at,omitempty"`
+	EventID     string     `db:"event_id" json:"event_id,omitempty"`
+	Name        string     `db:"name" json:"name"`
+	TemplateID  string     `db:"template_id" json:"template_id"`
+	Ingredients ItemStacks `db:"ingredients" json:"ingredients"`
+}
+
+// Validate checks the name and ingredients of the recipe.
+func (r Recipe) Validate() error {
+	if strings.TrimSpace(r.Name) == "" || len(r.Name) > MaxRecipeNameLen {
+		return errors.Wrap(errors.ErrMalformedEntity, errors.New(fmt.Sprintf("invalid recipe: name must be 1 to %d characters", MaxRecipeNameLen)))
+	}
+	if len(r.Ingredients) == 0 {
+		return errInvalidItems("a recipe needs at least one ingredient")
+	}
+	return r.Ingredients.Validate(MaxIngredients)
+}
+
+// ItemCatalog is what players see of an event's items and recipes.
+type ItemCatalog struct {
+	Items   []Item   `json:"items"`
+	Recipes []Recipe `json:"recipes"`
+}
+
+// ItemTrade offers the Give items of FromUserID for the Want items of
+// ToUserID. Nothing is held while the offer is pending; both sides must
+// still have their items when it is accepted.
+type ItemTrade struct {
+	ID         string     `db:"id" json:"id"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updated_at"`
+	EventID    string     `db:"event_id" json:"event_id"`
+	FromUserID string     `db:"from_user_id" json:"from_user_id"`
+	ToUserID   string     `db:"to_user_id" json:"to_user_id"`
+	Give       ItemStacks `db:"give" json:"give"`
+	Want       ItemStacks `db:"want" json:"want"`
+	Status     string     `db:"status" json:"status"`
+}
+
+type ItemRepository interface {
+	// CreateItem fails with ErrItemCodeTaken when the event already has an
+	// item with the code.
+	CreateItem(ctx context.Context, item Item) (string, error)
+	GetItems(ctx context.Context, eventID string) ([]Item, error)
+	GetItem(ctx context.Context, id string, eventID string) (Item, error)
+	// UpdateItem changes the name, image and rarity; the code stays.
+	UpdateItem(ctx context.Context, item Item) error
+	GetInventory(ctx context.Context, eventID string, userID string) ([]InventoryItem, error)
+
+	CreateRecipe(ctx context.Context, recipe Recipe) (string, error)
+	GetRecipes(ctx context.Context, eventID string) ([]Recipe, error)
+	GetRecipe(ctx context.Context, id string, eventID string) (Recipe, error)
+	UpdateRecipe(ctx context.Context, recipe Recipe) error
+	DeleteRecipe(ctx context.Context, id string, eventID string) error
+	// ExchangeRecipe takes the recipe's ingredients out of the player's
+	// inventory and issues a voucher of its template in one transaction. It
+	// fails with ErrNotEnoughItems or ErrOutOfStock and then changes nothing.
+	ExchangeRecipe(ctx context.Context, recipe Recipe, userID string, code string) (Voucher, error)
+
+	// GiftItems moves items between players in one transaction. It fails
+	// with ErrNotEnoughItems when the sender lacks any of them.
+	GiftItems(ctx context.Context, eventID string, fromUserID string, toUserID string, items ItemStacks) error
+	CreateItemTrade(ctx context.Context, trade ItemTrade) (ItemTrade, error)
+	GetItemTrade(ctx context.Context, id string) (ItemTrade, error)
+	GetPendingItemTradesByUser(ctx context.Context, eventID string, userID string) ([]ItemTrade, error)
+	// AcceptItemTrade swaps the items of both sides and closes the trade in
+	// one transaction. It fails with ErrNotEnoughItems when either side lacks
+	// any of their items.
+	AcceptItemTrade(ctx context.Context, id string) (ItemTrade, error)
+	// CloseItemTrade moves a pending trade to status.
+	CloseItemTrade(ctx context.Context, id string, status string) error
+}
@@ -0,0 +1,179 @@
+// Package jwt provides a TokenIssuer implementation that signs access tokens
+// as JSON Web Tokens. Tokens are verified without a database lookup; the
+// token IDs (jti) of revoked tokens are kept in an in-memory denylist that
+// is reloaded from the access_tokens table, so access tokens should be short
+// lived.
+package jwt
+
+import (
+	"context"
+	"crypto/rsa"
+	"sync"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/auth"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+
+	issuerName = "vou"
+)
+
+var (
+	errUnknownKey       = errors.New("unknown signing key")
+	errUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	errMissingActiveKey = errors.New("active signing key is not configured")
+	errSignToken        = errors.New("failed to sign token")
+)
+
+// Key is a signing key identified by the `kid` header of the tokens it signs.
+// HS256 keys use Secret, RS256 keys use PrivateKey.
+type Key struct {
+	ID         string
+	Alg        string
+	Secret     []byte
+	PrivateKey *rsa.PrivateKey
+}
+
+type claims struct {
+	jwtlib.RegisteredClaims
+	Role         string `json:"role"`
+	EnterpriseID string `json:"enterprise_id,omitempty"`
+}
+
+var (
+	_ admin.TokenIssuer      = (*issuer)(nil)
+	_ admin.RevocationSyncer = (*issuer)(nil)
+)
+
+type issuer struct {
+	keys   map[string]Key
+	active Key
+	auth   admin.AuthRepository
+
+	mu      sync.RWMutex
+	revoked map[string]struct{}
+}
+
+// New instantiates a JWT issuer. Tokens are signed with the active key and
+// verified with any of the configured keys, so retired keys can be kept
+// around until the tokens they signed expire. Revoked tokens are rejected
+// once SyncRevoked loaded them from auth.
+func New(keys []Key, activeKID string, auth admin.AuthRepository) (admin.TokenIssuer, error) {
+	ki := make(map[string]Key, len(keys))
+	for _, k := range keys {
+		if k.Alg != HS256 && k.Alg != RS256 {
+			return nil, errors.Wrap(errUnsupportedAlg, errors.New(k.Alg))
+		}
+		ki[k.ID] = k
+	}
+	active, ok := ki[activeKID]
+	if !ok {
+		return nil, errMissingActiveKey
+	}
+	return &issuer{
+		keys:    ki,
+		active:  active,
+		auth:    auth,
+		revoked: map[string]struct{}{},
+	}, nil
+}
+
+func (i *issuer) Issue(p auth.Principal, ttl time.Duration) (string, error) {
+	now := time.Now()
+	tc := claims{
+		RegisteredClaims: jwtlib.RegisteredClaims{
+			ID:        p.TokenID,
+			Subject:   p.UserID,
+			Issuer:    issuerName,
+			IssuedAt:  jwtlib.NewNumericDate(now),
+			ExpiresAt: jwtlib.NewNumericDate(now.Add(ttl)),
+		},
+		Role:         p.Role,
+		EnterpriseID: p.EnterpriseID,
+	}
+	token := jwtlib.NewWithClaims(signingMethod(i.active.Alg), tc)
+	token.Header["kid"] = i.active.ID
+
+	var key interface{} = i.active.Secret
+	if i.active.Alg == RS256 {
+		key = i.active.PrivateKey
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return "", errors.Wrap(errSignToken, err)
+	}
+	return signed, nil
+}
+
+func (i *issuer) Parse(ctx context.Context, accessToken string) (auth.Principal, error) {
+	var tc claims
+	_, err := jwtlib.ParseWithClaims(accessToken, &tc, i.keyFunc,
+		jwtlib.WithValidMethods([]string{HS256, RS256}),
+		jwtlib.WithIssuer(issuerName),
+		jwtlib.WithExpirationRequired(),
+	)
+	if err != nil {
+		return auth.Principal{}, errors.Wrap(admin.ErrInvalidToken, err)
+	}
+	// A valid signature does not mean the token was not revoked since.
+	i.mu.RLock()
+	_, revoked := i.revoked[tc.ID]
+	i.mu.RUnlock()
+	if revoked {
+		return auth.Principal{}, admin.ErrInvalidToken
+	}
+	return auth.Principal{
+		UserID:       tc.Subject,
+		Role:         tc.Role,
+		EnterpriseID: tc.EnterpriseID,
+		TokenID:      tc.ID,
+	}, nil
+}
+
+// SyncRevoked replaces the denylist with the revoked tokens in auth. Revoked
+// rows are kept there until the access token expires, so the list stays as
+// small as the tokens revoked within one access token lifetime.
+func (i *issuer) SyncRevoked(ctx context.Context) (int64, error) {
+	tokens, err := i.auth.GetRevokedAccessTokens(ctx)
+	if err != nil {
+		return 0, err
+	}
+	revoked := make(map[string]struct{}, len(tokens))
+	for _, t := range tokens {
+		revoked[t] = struct{}{}
+	}
+	i.mu.Lock()
+	i.revoked = revoked
+	i.mu.Unlock()
+	return int64(len(revoked)), nil
+}
+
+func (i *issuer) keyFunc(token *jwtlib.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := i.keys[kid]
+	if !ok {
+		return nil, errUnknownKey
+	}
+	// Reject tokens whose header algorithm does not match the key, otherwise
+	// an RS256 public key could be abused as an HS256 secret.
+	if token.Method.Alg() != key.Alg {
+		return nil, errUnsupportedAlg
+	}
+	if key.Alg == RS256 {
+		return &key.PrivateKey.PublicKey, nil
+	}
+	return key.Secret, nil
+}
+
+func signingMethod(alg string) jwtlib.SigningMethod {
+	if alg == RS256 {
+		return jwtlib.SigningMethodRS256
+	}
+	return jwtlib.SigningMethodHS256
+}
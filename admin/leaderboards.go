@@ -0,0 +1,148 @@
+package admin
+
+import (
+	"context"
+	"time"
+)
+
+// Leaderboard windows. Daily and weekly boards restart every day and every
+// week (from Monday); the event board covers the whole event, and the
+// all-time board every event of the same game.
+const (
+	PeriodDaily   = "daily"
+	PeriodWeekly  = "weekly"
+	PeriodEvent   = "event"
+	PeriodAllTime = "all_time"
+)
+
+// Sources of score entries.
+const (
+	ScoreQuiz     = "quiz"
+	ScoreLiveQuiz = "live_quiz"
+)
+
+const (
+	// DefLeaderboardLimit and MaxLeaderboardLimit bound a top-N query.
+	DefLeaderboardLimit = 10
+	MaxLeaderboardLimit = 100
+	// DefLeaderboardRadius and MaxLeaderboardRadius bound how many
+	// neighbours are shown on each side of a player.
+	DefLeaderboardRadius = 5
+	MaxLeaderboardRadius = 25
+	// DefStandingsLimit and MaxStandingsLimit bound a page of final
+	// standings.
+	DefStandingsLimit = 100
+	MaxStandingsLimit = 1000
+)
+
+// ValidPeriod reports whether p is a known leaderboard window.
+func ValidPeriod(p string) bool {
+	switch p {
+	case PeriodDaily, PeriodWeekly, PeriodEvent, PeriodAllTime:
+		return true
+	}
+	return false
+}
+
+// PeriodStart returns the first day of the window of period that holds day.
+// Event and all-time windows have a single window and start at the zero
+// time.
+func PeriodStart(period string, day time.Time) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case PeriodDaily:
+		return day
+	case PeriodWeekly:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	return time.Time{}
+}
+
+// ScoreEntry is points a player scored in an event. Entries are recorded
+// once per Source and Ref, so a retried play cannot score twice. Quiz
+// answers are referenced by question; a live session is recorded as one
+// entry per player when it finishes.
+type ScoreEntry struct {
+	Seq       int64     `db:"seq" json:"seq"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	EventID   string    `db:"event_id" json:"event_id"`
+	GameID    string    `db:"game_id" json:"game_id"`
+	UserID    string    `db:"user_id" json:"user_id"`
+	Points    int       `db:"points" json:"points"`
+	Source    string    `db:"source" json:"source"`
+	Ref       string    `db:"ref" json:"ref"`
+}
+
+// Board names one window of a leaderboard. ScopeID is the event, or the
+// game for the all-time board.
+type Board struct {
+	ScopeID string
+	Period  string
+	Start   time.Time
+}
+
+// LeaderboardRow is a player's place on a board. Players on the same points
+// are ranked by who reached them first.
+type LeaderboardRow struct {
+	Rank      int64     `db:"rank" json:"rank"`
+	UserID    string    `db:"user_id" json:"user_id"`
+	Username  string    `db:"username" json:"username"`
+	Points    int64     `db:"points" json:"points"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// LeaderboardQuery asks for a board of an event. Date picks the daily or
+// weekly window and defaults to today.
+type LeaderboardQuery struct {
+	EventID string
+	Period  string
+	Date    time.Time
+	Limit   int
+	Radius  int
+}
+
+// Leaderboard is a window of a board: its top rows, or a player and their
+// neighbours.
+type Leaderboard struct {
+	EventID     string           `json:"event_id"`
+	GameID      string           `json:"game_id"`
+	Period      string           `json:"period"`
+	PeriodStart *time.Time       `json:"period_start,omitempty"`
+	Me          *LeaderboardRow  `json:"me,omitempty"`
+	Rows        []LeaderboardRow `json:"rows"`
+}
+
+// Standing is a player's final place in an event.
+type Standing struct {
+	Rank     int64  `db:"rank" json:"rank"`
+	UserID   string `db:"user_id" json:"user_id"`
+	Username string `db:"username" json:"username"`
+	Points   int64  `db:"points" json:"points"`
+}
+
+// Standings is the event board frozen when the event ended. It never
+// changes afterwards, so prizes can be handed out from it.
+type Standings struct {
+	EventID   string     `db:"event_id" json:"event_id"`
+	FrozenAt  time.Time  `db:"frozen_at" json:"frozen_at"`
+	Players   int64      `db:"players" json:"players"`
+	Standings []Standing `db:"-" json:"standings"`
+}
+
+type LeaderboardRepository interface {
+	// RecordScore appends the entry and adds its points to every board of
+	// the event and game. Entries of events that are not running, and
+	// entries already recorded, are ignored.
+	RecordScore(ctx context.Context, entry ScoreEntry) error
+	// GetTop returns the first limit rows of the board.
+	GetTop(ctx context.Context, board Board, limit int) ([]LeaderboardRow, error)
+	// GetAround returns the player's row and up to radius rows on each side
+	// of it. It fails with ErrNotRanked when the player has no points on the
+	// board.
+	GetAround(ctx context.Context, board Board, userID string, radius int) (LeaderboardRow, []LeaderboardRow, error)
+	// FreezeStandings snapshots the event board of every ended event that
+	// has not been frozen yet and returns how many were.
+	FreezeStandings(ctx context.Context) (int64, error)
+	// GetStandings fails with ErrStandingsNotFrozen until the event is.
+	GetStandings(ctx context.Context, eventID string, limit int, offset int) (Standings, error)
+}
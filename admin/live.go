@@ -0,0 +1,146 @@
+package admin
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// Quiz modes. A self-paced quiz is played by each player on their own while
+// the event runs; a live quiz is hosted once, at the event's start time, for
+// everyone connected.
+const (
+	QuizSelfPaced = "self_paced"
+	QuizLive      = "live"
+)
+
+// Phases of a live session.
+const (
+	LiveLobby    = "lobby"
+	LiveQuestion = "question"
+	LiveReveal   = "reveal"
+	LiveFinished = "finished"
+)
+
+// Types of the messages pushed to players of a live session.
+const (
+	LiveMsgState       = "state"
+	LiveMsgQuestion    = "question"
+	LiveMsgAnswer      = "answer"
+	LiveMsgLeaderboard = "leaderboard"
+	LiveMsgFinished    = "finished"
+	LiveMsgError       = "error"
+)
+
+// Pacing of a live session.
+const (
+	// LiveCountdown is the wait between the session opening and the first
+	// question, for players to connect.
+	LiveCountdown = 10 * time.Second
+	// LiveRevealTime is how long the leaderboard shows between questions.
+	LiveRevealTime = 5 * time.Second
+	// LiveRetention is how long a finished session stays readable.
+	LiveRetention = 10 * time.Minute
+	// LiveLeaderboardSize is how many top scorers are pushed each round.
+	LiveLeaderboardSize = 10
+	// LiveHeartbeat is how often the hosting replica renews its claim on a
+	// session. A claim not renewed for LiveHostTimeout is recovered.
+	LiveHeartbeat   = 10 * time.Second
+	LiveHostTimeout = 45 * time.Second
+)
+
+// QuizPrizes lists voucher template IDs by rank: the first goes to the top
+// scorer of a live session, the second to the runner-up, and so on.
+type QuizPrizes []string
+
+func (p QuizPrizes) Value() (driver.Value, error) {
+	if p == nil {
+		p = QuizPrizes{}
+	}
+	return json.Marshal(p)
+}
+
+func (p *QuizPrizes) Scan(src interface{}) error {
+	return scanJSON(src, p)
+}
+
+// LiveSession is the state of a live quiz. During LiveQuestion answers to
+// Round are accepted from RoundStartedAt until Deadline; in the other phases
+// Deadline is when the next phase starts.
+type LiveSession struct {
+	EventID        string
+	SetID          string
+	Status         string
+	Round          int
+	Questions      []Question
+	OpenedAt       time.Time
+	RoundStartedAt time.Time
+	Deadline       time.Time
+}
+
+// Accepts reports whether an answer to round at now is within the window.
+func (s LiveSession) Accepts(round int, now time.Time) bool {
+	return s.Status == LiveQuestion && s.Round == round && !now.Before(s.RoundStartedAt) && !now.After(s.Deadline)
+}
+
+// LiveScore is a player's standing. Ties on score go to whoever answered
+// correctly faster in total.
+type LiveScore struct {
+	Rank      int    `json:"rank"`
+	UserID    string `json:"user_id"`
+	Score     int    `json:"score"`
+	Correct   int    `json:"correct"`
+	ElapsedMS int64  `json:"elapsed_ms"`
+}
+
+// LivePrize is a voucher won in a live session.
+type LivePrize struct {
+	Rank       int    `json:"rank"`
+	UserID     string `json:"user_id"`
+	TemplateID string `json:"template_id"`
+	VoucherID  string `json:"voucher_id"`
+}
+
+// LiveMessage is pushed to players. Which fields are set depends on Type.
+type LiveMessage struct {
+	Type        string          `json:"type"`
+	EventID     string          `json:"event_id"`
+	Status      string          `json:"status,omitempty"`
+	Round       int             `json:"round"`
+	Total       int             `json:"total,omitempty"`
+	Question    *PlayerQuestion `json:"question,omitempty"`
+	Deadline    *time.Time      `json:"deadline,omitempty"`
+	Answer      *QuizAnswer     `json:"answer,omitempty"`
+	Leaderboard []LiveScore     `json:"leaderboard,omitempty"`
+	Me          *LiveScore      `json:"me,omitempty"`
+	Prizes      []LivePrize     `json:"prizes,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// LiveSessionStore keeps the state of live sessions. The memory store only
+// serves players connected to the replica hosting the session, so requests
+// reaching other replicas are forwarded to it.
+type LiveSessionStore interface {
+	CreateSession(ctx context.Context, session LiveSession) error
+	GetSession(ctx context.Context, eventID string) (LiveSession, error)
+	UpdateSession(ctx context.Context, session LiveSession) error
+	DeleteSession(ctx context.Context, eventID string) error
+	// Join adds the player to the leaderboard with no points.
+	Join(ctx context.Context, eventID string, userID string) error
+	// RecordAnswer adds a graded answer to the player's score. It fails
+	// unless round is open and the player has not answered it yet.
+	RecordAnswer(ctx context.Context, eventID string, userID string, round int, answer QuizAnswer) error
+	// Leaderboard returns every player who joined, best first.
+	Leaderboard(ctx context.Context, eventID string) ([]LiveScore, error)
+}
+
+// LiveHub fans messages out to the players connected to a session.
+type LiveHub interface {
+	// Subscribe returns the messages for userID in the event's session and a
+	// function to stop receiving them. Slow receivers miss messages rather
+	// than hold up the session.
+	Subscribe(eventID string, userID string) (<-chan LiveMessage, func())
+	Broadcast(eventID string, msg LiveMessage)
+	Send(eventID string, userID string, msg LiveMessage)
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/resrrdttrt/VOU/admin"
+)
+
+var _ admin.LiveHub = (*hub)(nil)
+
+// subscriberBuffer is how many messages a subscriber may fall behind before
+// it starts missing them.
+const subscriberBuffer = 32
+
+type subscriber struct {
+	userID string
+	ch     chan admin.LiveMessage
+}
+
+type hub struct {
+	mu   sync.RWMutex
+	subs map[string]map[*subscriber]struct{}
+}
+
+// NewLiveHub returns a hub that delivers messages within the process.
+func NewLiveHub() admin.LiveHub {
+	return &hub{
+		subs: map[string]map[*subscriber]struct{}{},
+	}
+}
+
+func (h *hub) Subscribe(eventID string, userID string) (<-chan admin.LiveMessage, func()) {
+	sub := &subscriber{
+		userID: userID,
+		ch:     make(chan admin.LiveMessage, subscriberBuffer),
+	}
+	h.mu.Lock()
+	if h.subs[eventID] == nil {
+		h.subs[eventID] = map[*subscriber]struct{}{}
+	}
+	h.subs[eventID][sub] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs[eventID], sub)
+			if len(h.subs[eventID]) == 0 {
+				delete(h.subs, eventID)
+			}
+			close(sub.ch)
+			h.mu.Unlock()
+		})
+	}
+	return sub.ch, cancel
+}
+
+func (h *hub) Broadcast(eventID string, msg admin.LiveMessage) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs[eventID] {
+		deliver(sub, msg)
+	}
+}
+
+func (h *hub) Send(eventID string, userID string, msg admin.LiveMessage) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs[eventID] {
+		if sub.userID == userID {
+			deliver(sub, msg)
+		}
+	}
+}
+
+func deliver(sub *subscriber, msg admin.LiveMessage) {
+	select {
+	case sub.ch <- msg:
+	default:
+	}
+}
@@ -0,0 +1,141 @@
+// Package memory provides in-process implementations of the live quiz
+// session store and hub.
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/resrrdttrt/VOU/admin"
+)
+
+var _ admin.LiveSessionStore = (*sessionStore)(nil)
+
+type liveEntry struct {
+	session  admin.LiveSession
+	players  map[string]*admin.LiveScore
+	answered map[int]map[string]bool
+}
+
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*liveEntry
+}
+
+// NewLiveSessionStore returns a store that keeps sessions in memory.
+func NewLiveSessionStore() admin.LiveSessionStore {
+	return &sessionStore{
+		sessions: map[string]*liveEntry{},
+	}
+}
+
+func (s *sessionStore) CreateSession(ctx context.Context, session admin.LiveSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[session.EventID]; ok {
+		return admin.ErrLiveSessionOpen
+	}
+	s.sessions[session.EventID] = &liveEntry{
+		session:  session,
+		players:  map[string]*admin.LiveScore{},
+		answered: map[int]map[string]bool{},
+	}
+	return nil
+}
+
+func (s *sessionStore) GetSession(ctx context.Context, eventID string) (admin.LiveSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[eventID]
+	if !ok {
+		return admin.LiveSession{}, admin.ErrLiveSessionNotFound
+	}
+	return e.session, nil
+}
+
+func (s *sessionStore) UpdateSession(ctx context.Context, session admin.LiveSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[session.EventID]
+	if !ok {
+		return admin.ErrLiveSessionNotFound
+	}
+	e.session = session
+	return nil
+}
+
+func (s *sessionStore) DeleteSession(ctx context.Context, eventID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, eventID)
+	return nil
+}
+
+func (s *sessionStore) Join(ctx context.Context, eventID string, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[eventID]
+	if !ok {
+		return admin.ErrLiveSessionNotFound
+	}
+	if _, ok := e.players[userID]; !ok {
+		e.players[userID] = &admin.LiveScore{UserID: userID}
+	}
+	return nil
+}
+
+func (s *sessionStore) RecordAnswer(ctx context.Context, eventID string, userID string, round int, answer admin.QuizAnswer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[eventID]
+	if !ok {
+		return admin.ErrLiveSessionNotFound
+	}
+	if e.session.Status != admin.LiveQuestion || e.session.Round != round {
+		return admin.ErrAnswerWindowClosed
+	}
+	player, ok := e.players[userID]
+	if !ok {
+		return admin.ErrNotJoined
+	}
+	if e.answered[round] == nil {
+		e.answered[round] = map[string]bool{}
+	}
+	if e.answered[round][userID] {
+		return admin.ErrQuestionAnswered
+	}
+	e.answered[round][userID] = true
+	player.Score += answer.Points
+	if answer.Correct {
+		player.Correct++
+		player.ElapsedMS += answer.ElapsedMS
+	}
+	return nil
+}
+
+func (s *sessionStore) Leaderboard(ctx context.Context, eventID string) ([]admin.LiveScore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[eventID]
+	if !ok {
+		return nil, admin.ErrLiveSessionNotFound
+	}
+	board := make([]admin.LiveScore, 0, len(e.players))
+	for _, p := range e.players {
+		board = append(board, *p)
+	}
+	sort.Slice(board, func(i, j int) bool {
+		if board[i].Score != board[j].Score {
+			return board[i].Score > board[j].Score
+		}
+		if board[i].ElapsedMS != board[j].ElapsedMS {
+			return board[i].ElapsedMS < board[j].ElapsedMS
+		}
+		return board[i].UserID < board[j].UserID
+	})
+	for i := range board {
+		board[i].Rank = i + 1
+	}
+	return board, nil
+}
@@ -0,0 +1,195 @@
+package admin
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/resrrdttrt/VOU/pkg/auth"
+)
+
+const (
+	// TOTPIssuer names the service in authenticator apps.
+	TOTPIssuer = "VOU"
+	// TOTPDigits, TOTPPeriod and TOTPSkew follow the RFC 6238 defaults most
+	// authenticator apps use: six digits every 30 seconds, and one step of
+	// clock drift accepted on either side.
+	TOTPDigits = 6
+	TOTPPeriod = 30
+	TOTPSkew   = 1
+	// RecoveryCodeCount is how many recovery codes a user gets at a time.
+	RecoveryCodeCount = 10
+	// MFAChallengeTTL is how long the second step of a login can take, and
+	// MFAMaxChallengesPerHour how many logins a user can start in an hour.
+	MFAChallengeTTL         = 5 * time.Minute
+	MFAMaxChallengesPerHour = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFARole reports whether users of role can turn on two-factor
+// authentication.
+func MFARole(role string) bool {
+	switch role {
+	case auth.RoleAdmin, auth.RoleEnterprise, auth.RoleEnterpriseStaff:
+		return true
+	}
+	return false
+}
+
+// MFA is a user's TOTP secret. It only guards logins once EnabledAt is set,
+// which happens when the user proves their authenticator app works.
+type MFA struct {
+	UserID    string     `db:"user_id" json:"user_id"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+	Secret    string     `db:"secret" json:"-"`
+	LastStep  int64      `db:"last_step" json:"-"`
+	EnabledAt *time.Time `db:"enabled_at" json:"enabled_at,omitempty"`
+}
+
+// Enabled reports whether logins need a code.
+func (m MFA) Enabled() bool {
+	return m.EnabledAt != nil
+}
+
+// MFAEnrollment is what an authenticator app needs to be set up. QRCode is
+// the provisioning URI as a PNG data URI.
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	QRCode          string `json:"qr_code"`
+}
+
+// MFAChallenge is returned by the first step of a login that needs a code.
+// EnrollmentRequired is set when the role enforces MFA and the user has not
+// set it up yet.
+type MFAChallenge struct {
+	Token              string `json:"token"`
+	ExpiresIn          int64  `json:"expires_in"`
+	EnrollmentRequired bool   `json:"enrollment_required,omitempty"`
+}
+
+// MFAPolicy tells whether every user of Role must use MFA.
+type MFAPolicy struct {
+	Role      string    `db:"role" json:"role"`
+	Enforced  bool      `db:"enforced" json:"enforced"`
+	UpdatedBy string    `db:"updated_by" json:"updated_by"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// GenerateTOTPSecret returns a random 160-bit secret, base32 encoded.
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPProvisioningURI returns the otpauth URI authenticator apps scan to add
+// account.
+func TOTPProvisioningURI(account string, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", TOTPIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(TOTPPeriod))
+	label := url.PathEscape(TOTPIssuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// TOTPCode returns the code of secret for the time step step.
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod), nil
+}
+
+// MatchTOTP looks for code among the steps around now and returns the step
+// it belongs to, which callers record so the code cannot be used twice.
+func MatchTOTP(secret string, code string, now time.Time) (int64, bool) {
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+	current := now.Unix() / TOTPPeriod
+	for step := current - TOTPSkew; step <= current+TOTPSkew; step++ {
+		want, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes returns RecoveryCodeCount single-use codes of the
+// form XXXXX-XXXXX.
+func GenerateRecoveryCodes() ([]string, error) {
+	format := CodeFormat{Length: 10, Charset: CharsetAlphanumeric, Checksum: ChecksumNone}
+	codes := make([]string, 0, RecoveryCodeCount)
+	for len(codes) < RecoveryCodeCount {
+		code, err := format.Generate()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns what is stored for a recovery code. Case and
+// dashes are ignored, since users type the codes back in.
+func HashRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return HashClaimToken(code)
+}
+
+type MFARepository interface {
+	// GetMFA fails with ErrMFANotEnrolled when the user never started
+	// enrolling.
+	GetMFA(ctx context.Context, userID string) (MFA, error)
+	// SaveSecret starts enrolling the user with secret, replacing a secret
+	// that was never confirmed. It fails with ErrMFAAlreadyEnabled.
+	SaveSecret(ctx context.Context, userID string, secret string) error
+	// EnableMFA turns MFA on and replaces the user's recovery codes.
+	EnableMFA(ctx context.Context, userID string, recoveryHashes []string) error
+	// DeleteMFA removes the secret and recovery codes of the user.
+	DeleteMFA(ctx context.Context, userID string) error
+	// UseStep records that the code of step was used. It fails with
+	// ErrInvalidMFACode when that step or a later one already was, so a code
+	// cannot be replayed.
+	UseStep(ctx context.Context, userID string, step int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userID string, hashes []string) error
+	// UseRecoveryCode consumes an unused recovery code of the user. It fails
+	// with ErrInvalidMFACode.
+	UseRecoveryCode(ctx context.Context, userID string, hash string) error
+	GetMFAPolicies(ctx context.Context) ([]MFAPolicy, error)
+	SetMFAPolicy(ctx context.Context, policy MFAPolicy) error
+	// MFAEnforced reports whether users of role must use MFA.
+	MFAEnforced(ctx context.Context, role string) (bool, error)
+}
@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+// Channels a message can be delivered through.
+const (
+	ChannelEmail = "email"
+	ChannelPhone = "phone"
+)
+
+// ErrChannelNotSupported indicates a notifier that cannot deliver to the
+// requested channel.
+var ErrChannelNotSupported = errors.Wrap(errors.ErrMalformedEntity, errors.New("notifications cannot be sent to this channel"))
+
+// Message is a notification to an email address or a phone number.
+type Message struct {
+	Channel string `json:"channel"`
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier delivers messages to users.
+type Notifier interface {
+	// Notify sends msg. It fails with ErrChannelNotSupported when it cannot
+	// reach msg.Channel.
+	Notify(ctx context.Context, msg Message) error
+}
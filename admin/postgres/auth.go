@@ -2,10 +2,11 @@ package postgres
 
 import (
 	"context"
-	"math/rand"
-	"time"
+	"crypto/rand"
+	"encoding/base64"
 
 	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/auth"
 	"github.com/resrrdttrt/VOU/pkg/db"
 	"github.com/resrrdttrt/VOU/pkg/errors"
 	log "github.com/resrrdttrt/VOU/pkg/logger"
@@ -25,65 +26,192 @@ func NewAuthRepository(db db.Database, l log.Logger) admin.AuthRepository {
 	}
 }
 
-func (r *authRepository) Login(ctx context.Context, username string, password string) (admin.Token, error) {
-	query := `SELECT id, email, password FROM users WHERE username = :username`
+func (r *authRepository) CreateAccessToken(ctx context.Context, userID string, mfa bool, cfg admin.TokenConfig) (admin.Token, error) {
+	token, params, err := newTokenPair(userID, mfa, cfg)
+	if err != nil {
+		return admin.Token{}, err
+	}
+	_, err = r.db.NamedExecContext(ctx, insertTokenQuery, params)
+	if err != nil {
+		return admin.Token{}, errors.Wrap(ErrInsertDb, err)
+	}
+	return token, nil
+}
+
+func (r *authRepository) RefreshAccessToken(ctx context.Context, refreshToken string, cfg admin.TokenConfig, check func(userID string, mfa bool) error) (string, admin.Token, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", admin.Token{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	// Revoking and reading in one statement makes each refresh token single-use.
+	query := `UPDATE access_tokens SET revoked_at = NOW(), updated_at = NOW()
+		WHERE refresh_token = :refresh_token AND revoked_at IS NULL AND refresh_expires_at > NOW()
+		RETURNING user_id, mfa`
 	params := map[string]interface{}{
-		"username": username,
+		"refresh_token": refreshToken,
 	}
-	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	rows, err := tx.NamedQuery(query, params)
 	if err != nil {
-		return admin.Token{}, errors.Wrap(ErrSelectDb, err)
+		return "", admin.Token{}, errors.Wrap(ErrUpdateDb, err)
 	}
-	defer rows.Close()
-	var user admin.User
+	var userID string
+	var mfa bool
 	if rows.Next() {
-		if err := rows.StructScan(&user); err != nil {
-			return admin.Token{}, errors.Wrap(ErrSelectDb, err)
-		}
-		if user.Password != password {
-			return admin.Token{}, errors.New("password is incorrect")
-		}
-		// Generate random access token
-		accessToken := generateAccessToken()
-
-		// Add access token to access_token table by SQL
-		insertQuery := `INSERT INTO access_tokens (user_id, token) VALUES (:user_id, :token)`
-		insertParams := map[string]interface{}{
-			"user_id": user.ID,
-			"token":   accessToken,
-		}
-		_, err = r.db.NamedExecContext(ctx, insertQuery, insertParams)
-		if err != nil {
-			return admin.Token{}, errors.Wrap(ErrInsertDb, err)
+		if err := rows.Scan(&userID, &mfa); err != nil {
+			rows.Close()
+			return "", admin.Token{}, errors.Wrap(ErrSelectDb, err)
 		}
+	}
+	rows.Close()
+	if userID == "" {
+		return "", admin.Token{}, admin.ErrInvalidToken
+	}
+	if err := check(userID, mfa); err != nil {
+		return "", admin.Token{}, err
+	}
 
-		// Return the generated access token
-		return admin.Token{
-			AccessToken: accessToken,
-		}, nil
-	} else {
-		return admin.Token{}, errors.New("user not found")
+	token, insertParams, err := newTokenPair(userID, mfa, cfg)
+	if err != nil {
+		return "", admin.Token{}, err
+	}
+	if _, err := tx.NamedExec(insertTokenQuery, insertParams); err != nil {
+		return "", admin.Token{}, errors.Wrap(ErrInsertDb, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", admin.Token{}, errors.Wrap(ErrInsertDb, err)
 	}
+	return userID, token, nil
 }
 
-func generateAccessToken() string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	const length = 32
+func (r *authRepository) RevokeAccessToken(ctx context.Context, accessToken string) error {
+	query := `UPDATE access_tokens SET revoked_at = NOW(), updated_at = NOW() WHERE token = :token AND revoked_at IS NULL`
+	params := map[string]interface{}{
+		"token": accessToken,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrInvalidToken
+	}
+	return nil
+}
 
-	rand.Seed(time.Now().UnixNano())
+func (r *authRepository) RevokeAllAccessTokens(ctx context.Context, userID string) error {
+	query := `UPDATE access_tokens SET revoked_at = NOW(), updated_at = NOW() WHERE user_id = :user_id AND revoked_at IS NULL`
+	params := map[string]interface{}{
+		"user_id": userID,
+	}
+	_, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	return nil
+}
 
-	token := make([]byte, length)
-	for i := 0; i < length; i++ {
-		token[i] = charset[rand.Intn(len(charset))]
+// DeleteDeadAccessTokens keeps revoked pairs until their access token
+// expires, so issuers that verify tokens without this table can still learn
+// about the revocation from GetRevokedAccessTokens.
+func (r *authRepository) DeleteDeadAccessTokens(ctx context.Context) (int64, error) {
+	query := `DELETE FROM access_tokens WHERE (revoked_at IS NOT NULL AND expires_at <= NOW()) OR refresh_expires_at <= NOW()`
+	params := map[string]interface{}{}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return 0, errors.Wrap(ErrDeleteDb, err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
+
+func (r *authRepository) GetRevokedAccessTokens(ctx context.Context) ([]string, error) {
+	query := `SELECT token FROM access_tokens WHERE revoked_at IS NOT NULL AND expires_at > NOW()`
+	params := map[string]interface{}{}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	tokens := []string{}
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+const insertTokenQuery = `INSERT INTO access_tokens (user_id, token, expires_at, refresh_token, refresh_expires_at, mfa)
+	VALUES (:user_id, :token, NOW() + make_interval(secs => :access_ttl), :refresh_token, NOW() + make_interval(secs => :refresh_ttl), :mfa)`
+
+func newTokenPair(userID string, mfa bool, cfg admin.TokenConfig) (admin.Token, map[string]interface{}, error) {
+	accessToken, err := generateToken()
+	if err != nil {
+		return admin.Token{}, nil, errors.Wrap(ErrGenerateToken, err)
+	}
+	refreshToken, err := generateToken()
+	if err != nil {
+		return admin.Token{}, nil, errors.Wrap(ErrGenerateToken, err)
+	}
+	params := map[string]interface{}{
+		"user_id":       userID,
+		"token":         accessToken,
+		"access_ttl":    cfg.AccessTTL.Seconds(),
+		"refresh_token": refreshToken,
+		"refresh_ttl":   cfg.RefreshTTL.Seconds(),
+		"mfa":           mfa,
+	}
+	token := admin.Token{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(cfg.AccessTTL.Seconds()),
 	}
+	return token, params, nil
+}
 
-	return string(token)
+// generateToken returns 256 bits from the system CSPRNG, URL-safe encoded.
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
+func (r *authRepository) GetPrincipalByAccessToken(ctx context.Context, accessToken string) (auth.Principal, error) {
+	query := `SELECT t.token, t.user_id, u.role, u.enterprise_id FROM access_tokens t JOIN users u ON u.id = t.user_id
+		WHERE t.token = :token AND t.revoked_at IS NULL AND t.expires_at > NOW()`
+	params := map[string]interface{}{
+		"token": accessToken,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return auth.Principal{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return auth.Principal{}, admin.ErrInvalidToken
+	}
+	var tokenID string
+	var user admin.User
+	if err := rows.Scan(&tokenID, &user.ID, &user.Role, &user.EnterpriseID); err != nil {
+		return auth.Principal{}, errors.Wrap(ErrSelectDb, err)
+	}
+	return auth.Principal{
+		UserID:       user.ID,
+		Role:         user.Role,
+		EnterpriseID: user.Enterprise(),
+		TokenID:      tokenID,
+	}, nil
+}
 
 func (r *authRepository) GetUserIDByAccessToken(accessToken string) (string, error) {
 	var userID string
-	query := `SELECT user_id FROM access_tokens WHERE token = :token`
+	query := `SELECT user_id FROM access_tokens WHERE token = :token AND revoked_at IS NULL AND expires_at > NOW()`
 	params := map[string]interface{}{
 		"token": accessToken,
 	}
@@ -99,7 +227,7 @@ func (r *authRepository) GetUserIDByAccessToken(accessToken string) (string, err
 		}
 		return userID, nil
 	} else {
-		return "", errors.New("user not found")
+		return "", admin.ErrInvalidToken
 	}
 }
 
@@ -124,4 +252,3 @@ func (r *authRepository) GetUserRoleByID(userID string) (string, error) {
 		return "", errors.New("user not found")
 	}
 }
-
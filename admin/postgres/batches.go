@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/db"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+var _ admin.VoucherBatchRepository = (*voucherBatchRepository)(nil)
+
+// batchVoucherColumns are the columns written for every generated voucher.
+var batchVoucherColumns = []string{"code", "qrcode", "images", "value", "description", "expired_time", "status", "event_id", "batch_id", "rule"}
+
+type voucherBatchRepository struct {
+	db db.Database
+	l  log.Logger
+}
+
+func NewVoucherBatchRepository(db db.Database, l log.Logger) admin.VoucherBatchRepository {
+	return &voucherBatchRepository{
+		db: db,
+		l:  l,
+	}
+}
+
+func (r *voucherBatchRepository) CreateBatch(ctx context.Context, batch admin.VoucherBatch) (string, error) {
+	query := `INSERT INTO voucher_batches (event_id, count, status, prefix, code_length, charset, checksum, images, value, description, expired_time, rule)
+		VALUES (:event_id, :count, :status, :prefix, :code_length, :charset, :checksum, :images, :value, :description, :expired_time, :rule) RETURNING id`
+	params := map[string]interface{}{
+		"event_id":     batch.EventID,
+		"count":        batch.Count,
+		"status":       batch.Status,
+		"prefix":       batch.Prefix,
+		"code_length":  batch.Length,
+		"charset":      batch.Charset,
+		"checksum":     batch.Checksum,
+		"images":       batch.Images,
+		"value":        batch.Value,
+		"description":  batch.Description,
+		"expired_time": batch.ExpiredTime,
+		"rule":         batch.Rule,
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", errors.Wrap(ErrInsertDb, err)
+	}
+	defer tx.Rollback()
+
+	// The batch holds its whole count of the event's voucher_num from the
+	// start, so it cannot run out of room halfway.
+	if err := checkEventCapacity(ctx, tx, batch.EventID, "", batch.Count); err != nil {
+		return "", err
+	}
+	rows, err := tx.NamedQuery(query, params)
+	if err != nil {
+		return "", errors.Wrap(ErrInsertDb, err)
+	}
+	var id string
+	if rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return "", errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return "", errors.Wrap(ErrInsertDb, err)
+	}
+	return id, nil
+}
+
+func (r *voucherBatchRepository) GetBatch(ctx context.Context, id string, eventID string) (admin.VoucherBatch, error) {
+	query := `SELECT * FROM voucher_batches WHERE id = :id AND event_id = :event_id`
+	params := map[string]interface{}{
+		"id":       id,
+		"event_id": eventID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.VoucherBatch{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var batch admin.VoucherBatch
+	if rows.Next() {
+		if err := rows.StructScan(&batch); err != nil {
+			return admin.VoucherBatch{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return batch, nil
+	} else {
+		return admin.VoucherBatch{}, admin.ErrBatchNotFound
+	}
+}
+
+func (r *voucherBatchRepository) UpdateBatch(ctx context.Context, id string, attempt int, status string, generated int, errMsg string) error {
+	query := `UPDATE voucher_batches SET status = :status, generated = :generated, error = :error, updated_at = NOW()
+		WHERE id = :id AND attempt = :attempt`
+	params := map[string]interface{}{
+		"id":        id,
+		"attempt":   attempt,
+		"status":    status,
+		"generated": generated,
+		"error":     errMsg,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrBatchTakenOver
+	}
+	return nil
+}
+
+// ClaimStaleBatches recounts generated from the vouchers table, since a run
+// may have died between inserting a chunk and recording it.
+func (r *voucherBatchRepository) ClaimStaleBatches(ctx context.Context, timeout time.Duration) ([]admin.VoucherBatch, error) {
+	query := `UPDATE voucher_batches b SET attempt = b.attempt + 1, updated_at = NOW(),
+			generated = (SELECT COUNT(*) FROM vouchers v WHERE v.batch_id = b.id::text)
+		WHERE b.status IN (:pending, :running) AND b.updated_at < NOW() - make_interval(secs => :timeout)
+		RETURNING b.*`
+	params := map[string]interface{}{
+		"pending": admin.BatchPending,
+		"running": admin.BatchRunning,
+		"timeout": timeout.Seconds(),
+	}
+	rows, err := r.db.NamedExecWithResponse(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrUpdateDb, err)
+	}
+	defer rows.Close()
+	batches := []admin.VoucherBatch{}
+	for rows.Next() {
+		var batch admin.VoucherBatch
+		if err := rows.StructScan(&batch); err != nil {
+			return nil, errors.Wrap(ErrUpdateDb, err)
+		}
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
+
+// InsertBatchVouchers writes all codes with one multi-row INSERT. The unique
+// index on vouchers.code rejects duplicates, which ON CONFLICT turns into
+// skipped rows so the caller can generate replacements.
+func (r *voucherBatchRepository) InsertBatchVouchers(ctx context.Context, batch admin.VoucherBatch, codes []string) (int, error) {
+	if len(codes) == 0 {
+		return 0, nil
+	}
+	n := len(batchVoucherColumns)
+	values := make([]string, 0, len(codes))
+	args := make([]interface{}, 0, len(codes)*n)
+	for i, code := range codes {
+		placeholders := make([]string, n)
+		for j := range placeholders {
+			placeholders[j] = fmt.Sprintf("$%d", i*n+j+1)
+		}
+		values = append(values, "("+strings.Join(placeholders, ", ")+")")
+		args = append(args, code, code, batch.Images, batch.Value, batch.Description, batch.ExpiredTime, admin.VoucherActive, batch.EventID, batch.ID, batch.Rule)
+	}
+	query := `INSERT INTO vouchers (` + strings.Join(batchVoucherColumns, ", ") + `) VALUES ` +
+		strings.Join(values, ", ") + ` ON CONFLICT (code) DO NOTHING`
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, errors.Wrap(ErrInsertDb, err)
+	}
+	defer tx.Rollback()
+
+	// Under the event lock, so two runs of the batch cannot both fill the
+	// last of its count.
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM events WHERE id = $1 FOR UPDATE`, batch.EventID); err != nil {
+		return 0, errors.Wrap(ErrSelectDb, err)
+	}
+	var generated int
+	if err := tx.GetContext(ctx, &generated, `SELECT COUNT(*) FROM vouchers WHERE batch_id = $1`, batch.ID); err != nil {
+		return 0, errors.Wrap(ErrSelectDb, err)
+	}
+	if generated+len(codes) > batch.Count {
+		return 0, admin.ErrInventoryExceeded
+	}
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, errors.Wrap(ErrInsertDb, err)
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(ErrInsertDb, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(ErrInsertDb, err)
+	}
+	return int(inserted), nil
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"time"
+
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/db"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+var _ admin.OneTimeCodeRepository = (*codeRepository)(nil)
+
+type codeRepository struct {
+	db db.Database
+	l  log.Logger
+}
+
+func NewOneTimeCodeRepository(db db.Database, l log.Logger) admin.OneTimeCodeRepository {
+	return &codeRepository{
+		db: db,
+		l:  l,
+	}
+}
+
+// CreateCode serializes the codes of a user and purpose on an advisory lock,
+// so concurrent requests cannot both pass the throttle.
+func (r *codeRepository) CreateCode(ctx context.Context, code admin.OneTimeCode, ttl time.Duration, resendAfter time.Duration, maxPerHour int) (admin.OneTimeCode, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return admin.OneTimeCode{}, errors.Wrap(ErrInsertDb, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))`, code.UserID, code.Purpose); err != nil {
+		return admin.OneTimeCode{}, errors.Wrap(ErrSelectDb, err)
+	}
+	var sent struct {
+		LastHour int  `db:"last_hour"`
+		TooSoon  bool `db:"too_soon"`
+	}
+	err = tx.GetContext(ctx, &sent, `SELECT COUNT(*) AS last_hour,
+			COALESCE(MAX(created_at) > NOW() - make_interval(secs => $3), false) AS too_soon
+		FROM one_time_codes WHERE user_id = $1 AND purpose = $2 AND created_at > NOW() - INTERVAL '1 hour'`,
+		code.UserID, code.Purpose, resendAfter.Seconds())
+	if err != nil {
+		return admin.OneTimeCode{}, errors.Wrap(ErrSelectDb, err)
+	}
+	if sent.TooSoon || sent.LastHour >= maxPerHour {
+		return admin.OneTimeCode{}, admin.ErrCodeThrottled
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE one_time_codes SET expires_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()`, code.UserID, code.Purpose)
+	if err != nil {
+		return admin.OneTimeCode{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	var created admin.OneTimeCode
+	err = tx.GetContext(ctx, &created, `INSERT INTO one_time_codes (user_id, purpose, channel, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + make_interval(secs => $5)) RETURNING *`,
+		code.UserID, code.Purpose, code.Channel, code.CodeHash, ttl.Seconds())
+	if err != nil {
+		return admin.OneTimeCode{}, errors.Wrap(ErrInsertDb, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return admin.OneTimeCode{}, errors.Wrap(ErrInsertDb, err)
+	}
+	return created, nil
+}
+
+// UseCode commits wrong guesses too, so they count even though the call
+// fails.
+func (r *codeRepository) UseCode(ctx context.Context, userID string, purpose string, hash string, maxAttempts int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	var code admin.OneTimeCode
+	err = tx.GetContext(ctx, &code, `SELECT * FROM one_time_codes
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW() AND attempts < $3
+		ORDER BY created_at DESC LIMIT 1 FOR UPDATE`, userID, purpose, maxAttempts)
+	if err == sql.ErrNoRows {
+		return admin.ErrInvalidCode
+	}
+	if err != nil {
+		return errors.Wrap(ErrSelectDb, err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(code.CodeHash), []byte(hash)) != 1 {
+		if _, err := tx.ExecContext(ctx, `UPDATE one_time_codes SET attempts = attempts + 1 WHERE id = $1`, code.ID); err != nil {
+			return errors.Wrap(ErrUpdateDb, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return errors.Wrap(ErrUpdateDb, err)
+		}
+		return admin.ErrInvalidCode
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE one_time_codes SET used_at = NOW() WHERE id = $1`, code.ID); err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	return nil
+}
+
+func (r *codeRepository) UseToken(ctx context.Context, purpose string, hash string) (string, error) {
+	query := `UPDATE one_time_codes SET used_at = NOW()
+		WHERE code_hash = :code_hash AND purpose = :purpose AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`
+	params := map[string]interface{}{
+		"code_hash": hash,
+		"purpose":   purpose,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return "", errors.Wrap(ErrUpdateDb, err)
+	}
+	defer rows.Close()
+	var userID string
+	if rows.Next() {
+		if err := rows.Scan(&userID); err != nil {
+			return "", errors.Wrap(ErrUpdateDb, err)
+		}
+		return userID, nil
+	} else {
+		return "", admin.ErrInvalidCode
+	}
+}
+
+func (r *codeRepository) GetToken(ctx context.Context, purpose string, hash string, maxAttempts int) (admin.OneTimeCode, error) {
+	query := `SELECT * FROM one_time_codes
+		WHERE code_hash = :code_hash AND purpose = :purpose AND used_at IS NULL AND expires_at > NOW() AND attempts < :max_attempts`
+	params := map[string]interface{}{
+		"code_hash":    hash,
+		"purpose":      purpose,
+		"max_attempts": maxAttempts,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.OneTimeCode{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var code admin.OneTimeCode
+	if rows.Next() {
+		if err := rows.StructScan(&code); err != nil {
+			return admin.OneTimeCode{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return code, nil
+	} else {
+		return admin.OneTimeCode{}, admin.ErrInvalidCode
+	}
+}
+
+func (r *codeRepository) FailCode(ctx context.Context, id string) error {
+	query := `UPDATE one_time_codes SET attempts = attempts + 1 WHERE id = :id`
+	params := map[string]interface{}{
+		"id": id,
+	}
+	if _, err := r.db.NamedExecContext(ctx, query, params); err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/db"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+var _ admin.DrawRepository = (*drawRepository)(nil)
+
+type drawRepository struct {
+	db db.Database
+	l  log.Logger
+}
+
+func NewDrawRepository(db db.Database, l log.Logger) admin.DrawRepository {
+	return &drawRepository{
+		db: db,
+		l:  l,
+	}
+}
+
+func (r *drawRepository) GetPrizes(ctx context.Context, eventID string) ([]admin.Prize, error) {
+	query := `SELECT * FROM draw_prizes WHERE event_id = :event_id ORDER BY created_at, id`
+	params := map[string]interface{}{
+		"event_id": eventID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	prizes := []admin.Prize{}
+	for rows.Next() {
+		var prize admin.Prize
+		if err := rows.StructScan(&prize); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		prizes = append(prizes, prize)
+	}
+	return prizes, nil
+}
+
+// ReplacePrizeTable also refuses events that already have draws, such as
+// one cancelled while running, so the log always replays against the table
+// it was drawn from.
+func (r *drawRepository) ReplacePrizeTable(ctx context.Context, eventID string, turns int, prizes []admin.Prize) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE events SET play_turns = $1, updated_at = NOW()
+		WHERE id = $2 AND status NOT IN ($3, $4) AND NOT EXISTS (SELECT 1 FROM draws WHERE event_id = $2)`,
+		turns, eventID, admin.EventRunning, admin.EventEnded)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrPrizeTableLocked
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM draw_prizes WHERE event_id = $1`, eventID); err != nil {
+		return errors.Wrap(ErrDeleteDb, err)
+	}
+	for _, prize := range prizes {
+		_, err := tx.ExecContext(ctx, `INSERT INTO draw_prizes (event_id, kind, name, template_id, item_code, weight, initial_stock, stock)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $7)`,
+			eventID, prize.Kind, prize.Name, prize.TemplateID, prize.ItemCode, prize.Weight, prize.InitialStock)
+		if err != nil {
+			return errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	return nil
+}
+
+// Draw serializes the draws of an event on an advisory lock, which keeps the
+// stock and the Seq/Hash chain consistent without blocking other writers of
+// the event row, and spends the turn under the player's turns lock.
+func (r *drawRepository) Draw(ctx context.Context, eventID string, userID string, voucherCode string, roll func(total int64) (int64, error)) (admin.Draw, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return admin.Draw{}, errors.Wrap(ErrInsertDb, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended('draws:' || $1, 0))`, eventID); err != nil {
+		return admin.Draw{}, errors.Wrap(ErrSelectDb, err)
+	}
+	var running bool
+	err = tx.GetContext(ctx, &running, `SELECT status = $2 FROM events WHERE id = $1`, eventID, admin.EventRunning)
+	if err == sql.ErrNoRows || (err == nil && !running) {
+		return admin.Draw{}, admin.ErrEventNotRunning
+	}
+	if err != nil {
+		return admin.Draw{}, errors.Wrap(ErrSelectDb, err)
+	}
+	balance, err := lockTurns(ctx, tx, eventID, userID)
+	if err != nil {
+		return admin.Draw{}, err
+	}
+	if balance < 1 {
+		return admin.Draw{}, admin.ErrNoTurnsLeft
+	}
+
+	var prizes []admin.Prize
+	if err := tx.SelectContext(ctx, &prizes, `SELECT * FROM draw_prizes WHERE event_id = $1 ORDER BY created_at, id`, eventID); err != nil {
+		return admin.Draw{}, errors.Wrap(ErrSelectDb, err)
+	}
+	byID := map[string]admin.Prize{}
+	var candidates admin.DrawCandidates
+	for _, prize := range prizes {
+		if !prize.InStock() {
+			continue
+		}
+		if prize.Kind == admin.PrizeVoucher {
+			// The template can run out through other channels before the
+			// prize does.
+			var left int
+			err := tx.GetContext(ctx, &left, `SELECT quantity - issued FROM voucher_templates WHERE id = $1 AND event_id = $2`, prize.TemplateID, eventID)
+			if err != nil && err != sql.ErrNoRows {
+				return admin.Draw{}, errors.Wrap(ErrSelectDb, err)
+			}
+			if left <= 0 {
+				continue
+			}
+		}
+		byID[prize.ID] = prize
+		candidates = append(candidates, admin.DrawCandidate{PrizeID: prize.ID, Weight: int64(prize.Weight)})
+	}
+	total := candidates.TotalWeight()
+	if total == 0 {
+		return admin.Draw{}, admin.ErrPrizesExhausted
+	}
+	rolled, err := roll(total)
+	if err != nil {
+		return admin.Draw{}, err
+	}
+	prizeID, ok := candidates.Select(rolled)
+	if !ok {
+		return admin.Draw{}, errors.New("roll out of range")
+	}
+	prize := byID[prizeID]
+
+	draw := admin.Draw{
+		EventID:     eventID,
+		UserID:      userID,
+		PrizeID:     prize.ID,
+		Kind:        prize.Kind,
+		Name:        prize.Name,
+		ItemCode:    prize.ItemCode,
+		Roll:        rolled,
+		TotalWeight: total,
+		Candidates:  candidates,
+	}
+	if prize.Kind != admin.PrizeNothing {
+		res, err := tx.ExecContext(ctx, `UPDATE draw_prizes SET stock = stock - 1, updated_at = NOW() WHERE id = $1 AND stock > 0`, prize.ID)
+		if err != nil {
+			return admin.Draw{}, errors.Wrap(ErrUpdateDb, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return admin.Draw{}, admin.ErrPrizesExhausted
+		}
+	}
+	switch prize.Kind {
+	case admin.PrizeVoucher:
+		voucher, err := issueVoucher(ctx, tx, prize.TemplateID, eventID, userID, voucherCode)
+		if err != nil {
+			return admin.Draw{}, err
+		}
+		draw.VoucherID = voucher.ID
+	case admin.PrizeItem:
+		if err := applyItemDeltas(ctx, tx, eventID, []itemDelta{{userID: userID, itemCode: prize.ItemCode, amount: 1}}); err != nil {
+			return admin.Draw{}, err
+		}
+	}
+
+	var last struct {
+		Seq  int64  `db:"seq"`
+		Hash string `db:"hash"`
+	}
+	err = tx.GetContext(ctx, &last, `SELECT seq, hash FROM draws WHERE event_id = $1 ORDER BY seq DESC LIMIT 1`, eventID)
+	if err != nil && err != sql.ErrNoRows {
+		return admin.Draw{}, errors.Wrap(ErrSelectDb, err)
+	}
+	draw.Seq = last.Seq + 1
+	draw.PrevHash = last.Hash
+	draw.Hash = draw.ComputeHash()
+
+	err = tx.GetContext(ctx, &draw, `INSERT INTO draws (event_id, seq, user_id, prize_id, kind, name, voucher_id, item_code, roll, total_weight, candidates, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING *`,
+		draw.EventID, draw.Seq, draw.UserID, draw.PrizeID, draw.Kind, draw.Name, draw.VoucherID, draw.ItemCode,
+		draw.Roll, draw.TotalWeight, draw.Candidates, draw.PrevHash, draw.Hash)
+	if err != nil {
+		return admin.Draw{}, errors.Wrap(ErrInsertDb, err)
+	}
+	if _, err := appendTurn(ctx, tx, eventID, userID, balance, -1, admin.TurnPlay, draw.ID); err != nil {
+		return admin.Draw{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return admin.Draw{}, errors.Wrap(ErrInsertDb, err)
+	}
+	return draw, nil
+}
+
+func (r *drawRepository) GetDraws(ctx context.Context, eventID string) ([]admin.Draw, error) {
+	query := `SELECT * FROM draws WHERE event_id = :event_id ORDER BY seq`
+	params := map[string]interface{}{
+		"event_id": eventID,
+	}
+	return r.queryDraws(ctx, query, params)
+}
+
+func (r *drawRepository) GetDrawsByUser(ctx context.Context, eventID string, userID string) ([]admin.Draw, error) {
+	query := `SELECT * FROM draws WHERE event_id = :event_id AND user_id = :user_id ORDER BY seq`
+	params := map[string]interface{}{
+		"event_id": eventID,
+		"user_id":  userID,
+	}
+	return r.queryDraws(ctx, query, params)
+}
+
+func (r *drawRepository) queryDraws(ctx context.Context, query string, params map[string]interface{}) ([]admin.Draw, error) {
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	draws := []admin.Draw{}
+	for rows.Next() {
+		var draw admin.Draw
+		if err := rows.StructScan(&draw); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		draws = append(draws, draw)
+	}
+	return draws, nil
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/resrrdttrt/VOU/admin"
@@ -24,11 +25,10 @@ func NewEventRepository(db db.Database, l log.Logger) admin.EventRepository {
 	}
 }
 
-
 func (r *eventRepository) GetEventByID(ctx context.Context, id string, enterprise_id string) (admin.Event, error) {
 	query := `SELECT * FROM events WHERE id = :id AND user_id = :user_id`
 	params := map[string]interface{}{
-		"id":           id,
+		"id":      id,
 		"user_id": enterprise_id,
 	}
 	rows, err := r.db.NamedQueryContext(ctx, query, params)
@@ -47,10 +47,10 @@ func (r *eventRepository) GetEventByID(ctx context.Context, id string, enterpris
 	}
 }
 
-
 func (r *eventRepository) CreateEvent(ctx context.Context, event admin.Event) error {
-	query := `INSERT INTO events (id, name, images, voucher_num, start_time, end_time, game_id, user_id) VALUES (:id, :name, :images, :voucher_num, :start_time, :end_time, :game_id, :user_id) RETURNING id`
+	query := `INSERT INTO events (name, images, voucher_num, start_time, end_time, game_id, user_id, status) VALUES (:name, :images, :voucher_num, :start_time, :end_time, :game_id, :user_id, :status) RETURNING id`
 	params := map[string]interface{}{
+		"status":      event.Status,
 		"name":        event.Name,
 		"images":      event.Images,
 		"voucher_num": event.VoucherNum,
@@ -66,11 +66,36 @@ func (r *eventRepository) CreateEvent(ctx context.Context, event admin.Event) er
 	return nil
 }
 
-func (r *eventRepository) UpdateEvent(ctx context.Context, event admin.Event) error {
+func (r *eventRepository) UpdateEvent(ctx context.Context, id string, update func(current admin.Event) (admin.Event, error)) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	var current admin.Event
+	err = tx.GetContext(ctx, &current, `SELECT * FROM events WHERE id = $1 FOR UPDATE`, id)
+	if err == sql.ErrNoRows {
+		return admin.ErrEventNotFound
+	}
+	if err != nil {
+		return errors.Wrap(ErrSelectDb, err)
+	}
+	event, err := update(current)
+	if err != nil {
+		return err
+	}
+	if event.VoucherNum != 0 && event.VoucherNum != current.VoucherNum {
+		// The event row stays locked until commit, so templates, batches and
+		// vouchers cannot be added past the new voucher_num meanwhile.
+		if err := checkVoucherNum(ctx, tx, id, event.VoucherNum); err != nil {
+			return err
+		}
+	}
+
 	query := `UPDATE events SET `
 	params := map[string]interface{}{
-		"id": event.ID,
-		"user_id": event.UserID,
+		"id": id,
 	}
 
 	if event.Name != "" {
@@ -103,9 +128,17 @@ func (r *eventRepository) UpdateEvent(ctx context.Context, event admin.Event) er
 		params["game_id"] = event.GameID
 	}
 
-	query = query[:len(query)-2] + ` WHERE id = :id and user_id = :user_id RETURNING *`
-	_, err := r.db.NamedExecContext(ctx, query, params)
-	if err != nil {
+	if event.Status != "" {
+		query += `status = :status, status_reason = :status_reason, `
+		params["status"] = event.Status
+		params["status_reason"] = event.StatusReason
+	}
+
+	query += `updated_at = NOW() WHERE id = :id`
+	if _, err := tx.NamedExecContext(ctx, query, params); err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if err := tx.Commit(); err != nil {
 		return errors.Wrap(ErrUpdateDb, err)
 	}
 	return nil
@@ -133,7 +166,8 @@ func (r *eventRepository) GetAllEventsByEnterpriseID(ctx context.Context, enterp
 }
 
 func (r *eventRepository) GetEventByTime(ctx context.Context, enterprise_id string, start time.Time, end time.Time) ([]admin.Event, error) {
-	query := `SELECT * FROM events WHERE user_id = :user_id AND start_time >= :start_time AND end_time <= :end_time`
+	// Events overlapping the window, not only those fully inside it.
+	query := `SELECT * FROM events WHERE user_id = :user_id AND start_time <= :end_time AND end_time >= :start_time ORDER BY start_time`
 	params := map[string]interface{}{
 		"user_id":    enterprise_id,
 		"start_time": start,
@@ -153,4 +187,90 @@ func (r *eventRepository) GetEventByTime(ctx context.Context, enterprise_id stri
 		events = append(events, event)
 	}
 	return events, nil
-}
\ No newline at end of file
+}
+
+func (r *eventRepository) GetEvent(ctx context.Context, id string) (admin.Event, error) {
+	query := `SELECT * FROM events WHERE id = :id`
+	params := map[string]interface{}{
+		"id": id,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.Event{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var event admin.Event
+	if rows.Next() {
+		if err := rows.StructScan(&event); err != nil {
+			return admin.Event{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return event, nil
+	} else {
+		return admin.Event{}, admin.ErrEventNotFound
+	}
+}
+
+func (r *eventRepository) GetEventsByStatus(ctx context.Context, status string) ([]admin.Event, error) {
+	query := `SELECT * FROM events WHERE status = :status ORDER BY start_time`
+	params := map[string]interface{}{
+		"status": status,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var events []admin.Event
+	for rows.Next() {
+		var event admin.Event
+		if err := rows.StructScan(&event); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (r *eventRepository) UpdateEventStatus(ctx context.Context, id, from, to, reason string) error {
+	query := `UPDATE events SET status = :to, status_reason = :reason, updated_at = NOW() WHERE id = :id AND status = :from`
+	params := map[string]interface{}{
+		"id":     id,
+		"from":   from,
+		"to":     to,
+		"reason": reason,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrInvalidTransition
+	}
+	return nil
+}
+
+func (r *eventRepository) AdvanceEventStatuses(ctx context.Context) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	var total int64
+	queries := []string{
+		`UPDATE events SET status = 'running', updated_at = NOW() WHERE status = 'approved' AND start_time <= NOW()`,
+		`UPDATE events SET status = 'ended', updated_at = NOW() WHERE status = 'running' AND end_time <= NOW()`,
+	}
+	for _, query := range queries {
+		res, err := tx.ExecContext(ctx, query)
+		if err != nil {
+			return 0, errors.Wrap(ErrUpdateDb, err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(ErrUpdateDb, err)
+	}
+	return total, nil
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq" // required for SQL access
+	"github.com/lib/pq"
 	"github.com/resrrdttrt/VOU/pkg/errors"
 	migrate "github.com/rubenv/sql-migrate"
 )
@@ -36,6 +36,15 @@ var (
 	ErrNoData        = errors.New("No data found")
 )
 
+// uniqueViolation is the SQLSTATE Postgres reports when a unique index
+// rejects a row.
+const uniqueViolation = "23505"
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == uniqueViolation
+}
+
 func ConnectRead(cfg Config) (*sqlx.DB, error) {
 	url := fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s sslcert=%s sslkey=%s sslrootcert=%s \n", cfg.Host, cfg.PortRead, cfg.User, cfg.Name, cfg.Pass, cfg.SSLMode, cfg.SSLCert, cfg.SSLKey, cfg.SSLRootCert)
 	db, err := sqlx.Open("postgres", url)
@@ -81,6 +90,15 @@ func migrateDB(db *sql.DB) error {
 					`DROP TABLE "users"`,
 				},
 			},
+			{
+				Id: "user_v2_enterprise",
+				Up: []string{
+					`ALTER TABLE "users" ADD COLUMN IF NOT EXISTS enterprise_id VARCHAR(36) NOT NULL DEFAULT ''`,
+				},
+				Down: []string{
+					`ALTER TABLE "users" DROP COLUMN IF EXISTS enterprise_id`,
+				},
+			},
 			{
 				Id: "game_table",
 				Up: []string{
@@ -116,6 +134,29 @@ func migrateDB(db *sql.DB) error {
 					`DROP TABLE "access_tokens"`,
 				},
 			},
+			{
+				Id: "access_token_v2_expiry",
+				Up: []string{
+					`ALTER TABLE "access_tokens"
+						ADD COLUMN IF NOT EXISTS expires_at         TIMESTAMP       NOT NULL DEFAULT NOW(),
+						ADD COLUMN IF NOT EXISTS revoked_at         TIMESTAMP,
+						ADD COLUMN IF NOT EXISTS refresh_token      VARCHAR(254),
+						ADD COLUMN IF NOT EXISTS refresh_expires_at TIMESTAMP       NOT NULL DEFAULT NOW()`,
+					`CREATE UNIQUE INDEX IF NOT EXISTS access_tokens_token_idx ON "access_tokens" (token)`,
+					`CREATE UNIQUE INDEX IF NOT EXISTS access_tokens_refresh_token_idx ON "access_tokens" (refresh_token)`,
+					`CREATE INDEX IF NOT EXISTS access_tokens_user_id_idx ON "access_tokens" (user_id)`,
+				},
+				Down: []string{
+					`DROP INDEX IF EXISTS access_tokens_user_id_idx`,
+					`DROP INDEX IF EXISTS access_tokens_refresh_token_idx`,
+					`DROP INDEX IF EXISTS access_tokens_token_idx`,
+					`ALTER TABLE "access_tokens"
+						DROP COLUMN IF EXISTS refresh_expires_at,
+						DROP COLUMN IF EXISTS refresh_token,
+						DROP COLUMN IF EXISTS revoked_at,
+						DROP COLUMN IF EXISTS expires_at`,
+				},
+			},
 			{
 				Id: "enterprise_table",
 				Up: []string{
@@ -156,6 +197,20 @@ func migrateDB(db *sql.DB) error {
 					`DROP TABLE "events"`,
 				},
 			},
+			{
+				Id: "event_v2_status",
+				Up: []string{
+					`ALTER TABLE "events"
+						ADD COLUMN IF NOT EXISTS status        VARCHAR(20)     NOT NULL DEFAULT 'approved',
+						ADD COLUMN IF NOT EXISTS status_reason TEXT            NOT NULL DEFAULT ''`,
+					`ALTER TABLE "events" ALTER COLUMN status SET DEFAULT 'draft'`,
+					`CREATE INDEX IF NOT EXISTS events_status_idx ON "events" (status)`,
+				},
+				Down: []string{
+					`DROP INDEX IF EXISTS events_status_idx`,
+					`ALTER TABLE "events" DROP COLUMN IF EXISTS status_reason, DROP COLUMN IF EXISTS status`,
+				},
+			},
 			{
 				Id: "voucher_table",
 				Up: []string{
@@ -171,13 +226,569 @@ func migrateDB(db *sql.DB) error {
 						description     TEXT            NOT NULL,
 						expired_time    TIMESTAMP       NOT NULL,
 						status          VARCHAR(20)     NOT NULL,
-						event_id        UUID            NOT NULL,
+						event_id        UUID            NOT NULL
 					)`,
 				},
 				Down: []string{
 					`DROP TABLE "vouchers"`,
 				},
 			},
+			{
+				Id: "voucher_template_table",
+				Up: []string{
+					`CREATE EXTENSION IF NOT EXISTS "uuid-ossp";`,
+					`CREATE TABLE IF NOT EXISTS "voucher_templates" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						updated_at      TIMESTAMP       DEFAULT NOW(),
+						event_id        UUID            NOT NULL,
+						name            VARCHAR(254)    NOT NULL,
+						images          TEXT            NOT NULL,
+						value           INTEGER         NOT NULL,
+						description     TEXT            NOT NULL,
+						expired_time    TIMESTAMP       NOT NULL,
+						quantity        INTEGER         NOT NULL CHECK (quantity >= 0),
+						issued          INTEGER         NOT NULL DEFAULT 0,
+						CHECK (issued <= quantity)
+					)`,
+					`CREATE INDEX IF NOT EXISTS voucher_templates_event_id_idx ON "voucher_templates" (event_id)`,
+				},
+				Down: []string{
+					`DROP TABLE "voucher_templates"`,
+				},
+			},
+			{
+				Id: "voucher_v2_owner",
+				Up: []string{
+					`ALTER TABLE "vouchers"
+						ADD COLUMN IF NOT EXISTS template_id VARCHAR(36)     NOT NULL DEFAULT '',
+						ADD COLUMN IF NOT EXISTS owner_id    VARCHAR(36)     NOT NULL DEFAULT ''`,
+				},
+				Down: []string{
+					`ALTER TABLE "vouchers" DROP COLUMN IF EXISTS owner_id, DROP COLUMN IF EXISTS template_id`,
+				},
+			},
+			{
+				Id: "voucher_v3_batch",
+				Up: []string{
+					`CREATE TABLE IF NOT EXISTS "voucher_batches" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						updated_at      TIMESTAMP       DEFAULT NOW(),
+						event_id        UUID            NOT NULL,
+						count           INTEGER         NOT NULL,
+						generated       INTEGER         NOT NULL DEFAULT 0,
+						status          VARCHAR(20)     NOT NULL,
+						error           TEXT            NOT NULL DEFAULT '',
+						prefix          VARCHAR(16)     NOT NULL DEFAULT '',
+						code_length     INTEGER         NOT NULL,
+						charset         VARCHAR(20)     NOT NULL,
+						checksum        VARCHAR(20)     NOT NULL,
+						images          TEXT            NOT NULL,
+						value           INTEGER         NOT NULL,
+						description     TEXT            NOT NULL,
+						expired_time    TIMESTAMP       NOT NULL
+					)`,
+					`CREATE INDEX IF NOT EXISTS voucher_batches_event_id_idx ON "voucher_batches" (event_id)`,
+					`ALTER TABLE "vouchers" ADD COLUMN IF NOT EXISTS batch_id VARCHAR(36) NOT NULL DEFAULT ''`,
+				},
+				Down: []string{
+					`ALTER TABLE "vouchers" DROP COLUMN IF EXISTS batch_id`,
+					`DROP TABLE "voucher_batches"`,
+				},
+			},
+			{
+				Id: "voucher_v3_code_unique",
+				Up: []string{
+					`CREATE UNIQUE INDEX IF NOT EXISTS vouchers_code_key ON "vouchers" (code)`,
+				},
+				Down: []string{
+					`DROP INDEX IF EXISTS vouchers_code_key`,
+				},
+			},
+			{
+				Id: "voucher_v4_redemption",
+				Up: []string{
+					`ALTER TABLE "vouchers"
+						ADD COLUMN IF NOT EXISTS redeemed_at TIMESTAMP,
+						ADD COLUMN IF NOT EXISTS redeemed_by VARCHAR(36)     NOT NULL DEFAULT '',
+						ADD COLUMN IF NOT EXISTS store_id    VARCHAR(64)     NOT NULL DEFAULT ''`,
+					`CREATE TABLE IF NOT EXISTS "voucher_redemptions" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						voucher_id      VARCHAR(36)     NOT NULL DEFAULT '',
+						code            VARCHAR(254)    NOT NULL,
+						event_id        VARCHAR(36)     NOT NULL DEFAULT '',
+						enterprise_id   VARCHAR(36)     NOT NULL DEFAULT '',
+						cashier_id      VARCHAR(36)     NOT NULL,
+						store_id        VARCHAR(64)     NOT NULL,
+						outcome         VARCHAR(32)     NOT NULL
+					)`,
+					`CREATE INDEX IF NOT EXISTS voucher_redemptions_voucher_id_idx ON "voucher_redemptions" (voucher_id)`,
+					`CREATE INDEX IF NOT EXISTS voucher_redemptions_code_idx ON "voucher_redemptions" (code)`,
+				},
+				Down: []string{
+					`DROP TABLE "voucher_redemptions"`,
+					`ALTER TABLE "vouchers" DROP COLUMN IF EXISTS store_id, DROP COLUMN IF EXISTS redeemed_by, DROP COLUMN IF EXISTS redeemed_at`,
+				},
+			},
+			{
+				Id: "voucher_v5_expiry",
+				Up: []string{
+					`CREATE INDEX IF NOT EXISTS vouchers_status_expired_time_idx ON "vouchers" (status, expired_time)`,
+					`CREATE TABLE IF NOT EXISTS "voucher_reminders" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						voucher_id      UUID            NOT NULL,
+						owner_id        VARCHAR(36)     NOT NULL,
+						kind            VARCHAR(8)      NOT NULL,
+						expired_time    TIMESTAMP       NOT NULL,
+						UNIQUE (voucher_id, kind)
+					)`,
+					`CREATE INDEX IF NOT EXISTS voucher_reminders_owner_id_idx ON "voucher_reminders" (owner_id)`,
+				},
+				Down: []string{
+					`DROP TABLE "voucher_reminders"`,
+					`DROP INDEX IF EXISTS vouchers_status_expired_time_idx`,
+				},
+			},
+			{
+				Id: "voucher_v6_rule",
+				Up: []string{
+					`ALTER TABLE "vouchers" ADD COLUMN IF NOT EXISTS rule JSONB`,
+					`ALTER TABLE "voucher_templates" ADD COLUMN IF NOT EXISTS rule JSONB`,
+					`ALTER TABLE "voucher_batches" ADD COLUMN IF NOT EXISTS rule JSONB`,
+				},
+				Down: []string{
+					`ALTER TABLE "voucher_batches" DROP COLUMN IF EXISTS rule`,
+					`ALTER TABLE "voucher_templates" DROP COLUMN IF EXISTS rule`,
+					`ALTER TABLE "vouchers" DROP COLUMN IF EXISTS rule`,
+				},
+			},
+			{
+				Id: "voucher_v7_wallet",
+				Up: []string{
+					`CREATE INDEX IF NOT EXISTS vouchers_owner_id_expired_time_idx ON "vouchers" (owner_id, expired_time, id)`,
+				},
+				Down: []string{
+					`DROP INDEX IF EXISTS vouchers_owner_id_expired_time_idx`,
+				},
+			},
+			{
+				Id: "voucher_v8_transfer",
+				Up: []string{
+					`CREATE TABLE IF NOT EXISTS "voucher_transfers" (
+						id                  UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at          TIMESTAMP       DEFAULT NOW(),
+						updated_at          TIMESTAMP       DEFAULT NOW(),
+						voucher_id          UUID            NOT NULL,
+						from_user_id        VARCHAR(36)     NOT NULL,
+						to_user_id          VARCHAR(36)     NOT NULL DEFAULT '',
+						claim_token_hash    VARCHAR(64)     NOT NULL DEFAULT '',
+						status              VARCHAR(20)     NOT NULL,
+						expires_at          TIMESTAMP       NOT NULL,
+						accepted_at         TIMESTAMP
+					)`,
+					`CREATE UNIQUE INDEX IF NOT EXISTS voucher_transfers_pending_key ON "voucher_transfers" (voucher_id) WHERE status = 'pending'`,
+					`CREATE UNIQUE INDEX IF NOT EXISTS voucher_transfers_claim_key ON "voucher_transfers" (claim_token_hash) WHERE claim_token_hash <> ''`,
+					`CREATE INDEX IF NOT EXISTS voucher_transfers_from_user_id_idx ON "voucher_transfers" (from_user_id)`,
+					`CREATE INDEX IF NOT EXISTS voucher_transfers_to_user_id_idx ON "voucher_transfers" (to_user_id)`,
+				},
+				Down: []string{
+					`DROP TABLE "voucher_transfers"`,
+				},
+			},
+			{
+				Id: "quiz_table",
+				Up: []string{
+					`CREATE TABLE IF NOT EXISTS "quiz_question_sets" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						updated_at      TIMESTAMP       DEFAULT NOW(),
+						enterprise_id   VARCHAR(36)     NOT NULL,
+						name            VARCHAR(254)    NOT NULL,
+						description     TEXT            NOT NULL DEFAULT ''
+					)`,
+					`CREATE INDEX IF NOT EXISTS quiz_question_sets_enterprise_id_idx ON "quiz_question_sets" (enterprise_id)`,
+					`CREATE TABLE IF NOT EXISTS "quiz_questions" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						updated_at      TIMESTAMP       DEFAULT NOW(),
+						set_id          UUID            NOT NULL REFERENCES quiz_question_sets (id) ON DELETE CASCADE,
+						position        INTEGER         NOT NULL,
+						text            TEXT            NOT NULL,
+						media           TEXT            NOT NULL DEFAULT '',
+						choices         JSONB           NOT NULL,
+						correct         JSONB           NOT NULL,
+						time_limit      INTEGER         NOT NULL,
+						difficulty      VARCHAR(20)     NOT NULL
+					)`,
+					`CREATE INDEX IF NOT EXISTS quiz_questions_set_id_position_idx ON "quiz_questions" (set_id, position)`,
+					`CREATE TABLE IF NOT EXISTS "quiz_attempts" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						updated_at      TIMESTAMP       DEFAULT NOW(),
+						event_id        UUID            NOT NULL,
+						user_id         VARCHAR(36)     NOT NULL,
+						set_id          UUID            NOT NULL,
+						position        INTEGER         NOT NULL DEFAULT 0,
+						total           INTEGER         NOT NULL,
+						score           INTEGER         NOT NULL DEFAULT 0,
+						correct         INTEGER         NOT NULL DEFAULT 0,
+						served_at       TIMESTAMP,
+						finished_at     TIMESTAMP,
+						UNIQUE (event_id, user_id)
+					)`,
+					`CREATE TABLE IF NOT EXISTS "quiz_answers" (
+						attempt_id      UUID            NOT NULL REFERENCES quiz_attempts (id) ON DELETE CASCADE,
+						question_id     UUID            NOT NULL,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						choices         JSONB           NOT NULL,
+						correct         BOOLEAN         NOT NULL,
+						timed_out       BOOLEAN         NOT NULL,
+						points          INTEGER         NOT NULL,
+						elapsed_ms      BIGINT          NOT NULL,
+						PRIMARY KEY (attempt_id, question_id)
+					)`,
+					`ALTER TABLE "events" ADD COLUMN IF NOT EXISTS question_set_id VARCHAR(36) NOT NULL DEFAULT ''`,
+				},
+				Down: []string{
+					`ALTER TABLE "events" DROP COLUMN IF EXISTS question_set_id`,
+					`DROP TABLE "quiz_answers"`,
+					`DROP TABLE "quiz_attempts"`,
+					`DROP TABLE "quiz_questions"`,
+					`DROP TABLE "quiz_question_sets"`,
+				},
+			},
+			{
+				Id: "quiz_v2_live",
+				Up: []string{
+					`ALTER TABLE "events"
+						ADD COLUMN IF NOT EXISTS quiz_mode     VARCHAR(20)     NOT NULL DEFAULT 'self_paced',
+						ADD COLUMN IF NOT EXISTS quiz_prizes   JSONB           NOT NULL DEFAULT '[]'`,
+					`CREATE TABLE IF NOT EXISTS "quiz_live_sessions" (
+						event_id        UUID            PRIMARY KEY,
+						opened_at       TIMESTAMP       NOT NULL DEFAULT NOW(),
+						finished_at     TIMESTAMP
+					)`,
+				},
+				Down: []string{
+					`DROP TABLE "quiz_live_sessions"`,
+					`ALTER TABLE "events" DROP COLUMN IF EXISTS quiz_prizes, DROP COLUMN IF EXISTS quiz_mode`,
+				},
+			},
+			{
+				Id: "shake_table",
+				Up: []string{
+					`ALTER TABLE "events" ADD COLUMN IF NOT EXISTS play_turns INTEGER NOT NULL DEFAULT 0`,
+					`CREATE TABLE IF NOT EXISTS "draw_prizes" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						updated_at      TIMESTAMP       DEFAULT NOW(),
+						event_id        UUID            NOT NULL,
+						kind            VARCHAR(20)     NOT NULL,
+						name            VARCHAR(254)    NOT NULL,
+						template_id     VARCHAR(36)     NOT NULL DEFAULT '',
+						item_code       VARCHAR(64)     NOT NULL DEFAULT '',
+						weight          INTEGER         NOT NULL CHECK (weight > 0),
+						initial_stock   INTEGER         NOT NULL DEFAULT 0,
+						stock           INTEGER         NOT NULL DEFAULT 0 CHECK (stock >= 0)
+					)`,
+					`CREATE INDEX IF NOT EXISTS draw_prizes_event_id_idx ON "draw_prizes" (event_id)`,
+					`CREATE TABLE IF NOT EXISTS "draws" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						event_id        UUID            NOT NULL,
+						seq             BIGINT          NOT NULL,
+						user_id         VARCHAR(36)     NOT NULL,
+						prize_id        UUID            NOT NULL,
+						kind            VARCHAR(20)     NOT NULL,
+						name            VARCHAR(254)    NOT NULL,
+						voucher_id      VARCHAR(36)     NOT NULL DEFAULT '',
+						item_code       VARCHAR(64)     NOT NULL DEFAULT '',
+						roll            BIGINT          NOT NULL,
+						total_weight    BIGINT          NOT NULL,
+						candidates      JSONB           NOT NULL,
+						prev_hash       VARCHAR(64)     NOT NULL,
+						hash            VARCHAR(64)     NOT NULL,
+						UNIQUE (event_id, seq)
+					)`,
+					`CREATE INDEX IF NOT EXISTS draws_event_id_user_id_idx ON "draws" (event_id, user_id)`,
+					`CREATE TABLE IF NOT EXISTS "player_items" (
+						user_id         VARCHAR(36)     NOT NULL,
+						event_id        UUID            NOT NULL,
+						item_code       VARCHAR(64)     NOT NULL,
+						quantity        INTEGER         NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+						updated_at      TIMESTAMP       DEFAULT NOW(),
+						PRIMARY KEY (user_id, event_id, item_code)
+					)`,
+				},
+				Down: []string{
+					`DROP TABLE "player_items"`,
+					`DROP TABLE "draws"`,
+					`DROP TABLE "draw_prizes"`,
+					`ALTER TABLE "events" DROP COLUMN IF EXISTS play_turns`,
+				},
+			},
+			{
+				Id: "shake_v2_items",
+				Up: []string{
+					`CREATE TABLE IF NOT EXISTS "event_items" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						updated_at      TIMESTAMP       DEFAULT NOW(),
+						event_id        UUID            NOT NULL,
+						code            VARCHAR(64)     NOT NULL,
+						name            VARCHAR(254)    NOT NULL,
+						image           TEXT            NOT NULL DEFAULT '',
+						rarity          VARCHAR(20)     NOT NULL,
+						UNIQUE (event_id, code)
+					)`,
+					`CREATE TABLE IF NOT EXISTS "item_recipes" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						updated_at      TIMESTAMP       DEFAULT NOW(),
+						event_id        UUID            NOT NULL,
+						name            VARCHAR(254)    NOT NULL,
+						template_id     VARCHAR(36)     NOT NULL,
+						ingredients     JSONB           NOT NULL
+					)`,
+					`CREATE INDEX IF NOT EXISTS item_recipes_event_id_idx ON "item_recipes" (event_id)`,
+					`CREATE TABLE IF NOT EXISTS "item_trades" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						updated_at      TIMESTAMP       DEFAULT NOW(),
+						event_id        UUID            NOT NULL,
+						from_user_id    VARCHAR(36)     NOT NULL,
+						to_user_id      VARCHAR(36)     NOT NULL,
+						give            JSONB           NOT NULL,
+						want            JSONB           NOT NULL,
+						status          VARCHAR(20)     NOT NULL
+					)`,
+					`CREATE INDEX IF NOT EXISTS item_trades_event_id_from_user_id_idx ON "item_trades" (event_id, from_user_id)`,
+					`CREATE INDEX IF NOT EXISTS item_trades_event_id_to_user_id_idx ON "item_trades" (event_id, to_user_id)`,
+				},
+				Down: []string{
+					`DROP TABLE "item_trades"`,
+					`DROP TABLE "item_recipes"`,
+					`DROP TABLE "event_items"`,
+				},
+			},
+			{
+				Id: "turns_table",
+				Up: []string{
+					`CREATE TABLE IF NOT EXISTS "play_turns" (
+						seq             BIGSERIAL       PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						day             DATE            NOT NULL DEFAULT CURRENT_DATE,
+						event_id        UUID            NOT NULL,
+						game_id         UUID            NOT NULL,
+						user_id         VARCHAR(36)     NOT NULL,
+						amount          INTEGER         NOT NULL,
+						balance         INTEGER         NOT NULL CHECK (balance >= 0),
+						reason          VARCHAR(20)     NOT NULL,
+						ref             VARCHAR(64)     NOT NULL DEFAULT ''
+					)`,
+					`CREATE INDEX IF NOT EXISTS play_turns_event_id_user_id_idx ON "play_turns" (event_id, user_id, seq)`,
+					`CREATE UNIQUE INDEX IF NOT EXISTS play_turns_daily_key ON "play_turns" (event_id, user_id, day) WHERE reason = 'daily'`,
+					`CREATE UNIQUE INDEX IF NOT EXISTS play_turns_share_key ON "play_turns" (event_id, user_id, day) WHERE reason = 'share'`,
+					`CREATE UNIQUE INDEX IF NOT EXISTS play_turns_invite_key ON "play_turns" (event_id, ref) WHERE reason = 'invite'`,
+					`CREATE OR REPLACE FUNCTION play_turns_append_only() RETURNS trigger AS $$
+					BEGIN
+						RAISE EXCEPTION 'play_turns is append-only';
+					END;
+					$$ LANGUAGE plpgsql`,
+					`CREATE TRIGGER play_turns_append_only BEFORE UPDATE OR DELETE ON "play_turns"
+						FOR EACH ROW EXECUTE PROCEDURE play_turns_append_only()`,
+					`CREATE TABLE IF NOT EXISTS "turn_requests" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						updated_at      TIMESTAMP       DEFAULT NOW(),
+						event_id        UUID            NOT NULL,
+						from_user_id    VARCHAR(36)     NOT NULL,
+						to_user_id      VARCHAR(36)     NOT NULL,
+						status          VARCHAR(20)     NOT NULL
+					)`,
+					`CREATE UNIQUE INDEX IF NOT EXISTS turn_requests_pending_key ON "turn_requests" (event_id, from_user_id, to_user_id) WHERE status = 'pending'`,
+					`CREATE INDEX IF NOT EXISTS turn_requests_to_user_id_idx ON "turn_requests" (to_user_id)`,
+				},
+				Down: []string{
+					`DROP TABLE "turn_requests"`,
+					`DROP TABLE "play_turns"`,
+					`DROP FUNCTION IF EXISTS play_turns_append_only()`,
+				},
+			},
+			{
+				Id: "leaderboard_table",
+				Up: []string{
+					`CREATE TABLE IF NOT EXISTS "score_entries" (
+						seq             BIGSERIAL       PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						event_id        UUID            NOT NULL,
+						game_id         UUID            NOT NULL,
+						user_id         VARCHAR(36)     NOT NULL,
+						points          INTEGER         NOT NULL,
+						source          VARCHAR(20)     NOT NULL,
+						ref             VARCHAR(128)    NOT NULL,
+						UNIQUE (event_id, user_id, source, ref)
+					)`,
+					`CREATE TABLE IF NOT EXISTS "leaderboard_scores" (
+						scope_id        UUID            NOT NULL,
+						period          VARCHAR(20)     NOT NULL,
+						period_start    DATE            NOT NULL,
+						user_id         VARCHAR(36)     NOT NULL,
+						points          BIGINT          NOT NULL,
+						updated_at      TIMESTAMP       NOT NULL DEFAULT NOW(),
+						PRIMARY KEY (scope_id, period, period_start, user_id)
+					)`,
+					`CREATE INDEX IF NOT EXISTS leaderboard_scores_rank_idx ON "leaderboard_scores"
+						(scope_id, period, period_start, points DESC, updated_at, user_id)`,
+					`CREATE TABLE IF NOT EXISTS "leaderboard_snapshots" (
+						event_id        UUID            PRIMARY KEY,
+						frozen_at       TIMESTAMP       NOT NULL DEFAULT NOW(),
+						players         BIGINT          NOT NULL
+					)`,
+					`CREATE TABLE IF NOT EXISTS "leaderboard_standings" (
+						event_id        UUID            NOT NULL,
+						rank            BIGINT          NOT NULL,
+						user_id         VARCHAR(36)     NOT NULL,
+						points          BIGINT          NOT NULL,
+						PRIMARY KEY (event_id, rank),
+						UNIQUE (event_id, user_id)
+					)`,
+				},
+				Down: []string{
+					`DROP TABLE "leaderboard_standings"`,
+					`DROP TABLE "leaderboard_snapshots"`,
+					`DROP TABLE "leaderboard_scores"`,
+					`DROP TABLE "score_entries"`,
+				},
+			},
+			{
+				Id: "user_v3_codes",
+				Up: []string{
+					`CREATE TABLE IF NOT EXISTS "one_time_codes" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						user_id         VARCHAR(36)     NOT NULL,
+						purpose         VARCHAR(20)     NOT NULL,
+						channel         VARCHAR(20)     NOT NULL,
+						code_hash       VARCHAR(64)     NOT NULL,
+						expires_at      TIMESTAMP       NOT NULL,
+						attempts        INTEGER         NOT NULL DEFAULT 0,
+						used_at         TIMESTAMP
+					)`,
+					`CREATE INDEX IF NOT EXISTS one_time_codes_user_id_idx ON "one_time_codes" (user_id, purpose, created_at)`,
+				},
+				Down: []string{
+					`DROP TABLE "one_time_codes"`,
+				},
+			},
+			{
+				Id: "user_v4_password_reset",
+				Up: []string{
+					`CREATE INDEX IF NOT EXISTS one_time_codes_code_hash_idx ON "one_time_codes" (code_hash)`,
+				},
+				Down: []string{
+					`DROP INDEX IF EXISTS one_time_codes_code_hash_idx`,
+				},
+			},
+			{
+				Id: "user_v5_mfa",
+				Up: []string{
+					`CREATE TABLE IF NOT EXISTS "user_mfa" (
+						user_id         VARCHAR(36)     PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						updated_at      TIMESTAMP       DEFAULT NOW(),
+						secret          VARCHAR(64)     NOT NULL,
+						last_step       BIGINT          NOT NULL DEFAULT 0,
+						enabled_at      TIMESTAMP
+					)`,
+					`CREATE TABLE IF NOT EXISTS "mfa_recovery_codes" (
+						id              UUID            DEFAULT uuid_generate_v4() PRIMARY KEY,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						user_id         VARCHAR(36)     NOT NULL,
+						code_hash       VARCHAR(64)     NOT NULL,
+						used_at         TIMESTAMP,
+						UNIQUE (user_id, code_hash)
+					)`,
+					`CREATE TABLE IF NOT EXISTS "mfa_policies" (
+						role            VARCHAR(20)     PRIMARY KEY,
+						enforced        BOOLEAN         NOT NULL DEFAULT FALSE,
+						updated_by      VARCHAR(36)     NOT NULL DEFAULT '',
+						updated_at      TIMESTAMP       DEFAULT NOW()
+					)`,
+				},
+				Down: []string{
+					`DROP TABLE "mfa_policies"`,
+					`DROP TABLE "mfa_recovery_codes"`,
+					`DROP TABLE "user_mfa"`,
+				},
+			},
+			{
+				Id: "user_v6_unique_contacts",
+				Up: []string{
+					`CREATE UNIQUE INDEX IF NOT EXISTS users_username_key ON "users" (username)`,
+					`CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON "users" (email) WHERE email <> ''`,
+					`CREATE UNIQUE INDEX IF NOT EXISTS users_phone_key ON "users" (phone) WHERE phone <> ''`,
+					`CREATE INDEX IF NOT EXISTS users_status_created_at_idx ON "users" (status, created_at)`,
+				},
+				Down: []string{
+					`DROP INDEX IF EXISTS users_status_created_at_idx`,
+					`DROP INDEX IF EXISTS users_phone_key`,
+					`DROP INDEX IF EXISTS users_email_key`,
+					`DROP INDEX IF EXISTS users_username_key`,
+				},
+			},
+			{
+				Id: "quiz_v3_live_host",
+				Up: []string{
+					`ALTER TABLE "quiz_live_sessions"
+						ADD COLUMN IF NOT EXISTS host          VARCHAR(254)    NOT NULL DEFAULT '',
+						ADD COLUMN IF NOT EXISTS heartbeat_at  TIMESTAMP       NOT NULL DEFAULT NOW()`,
+				},
+				Down: []string{
+					`ALTER TABLE "quiz_live_sessions" DROP COLUMN IF EXISTS heartbeat_at, DROP COLUMN IF EXISTS host`,
+				},
+			},
+			{
+				Id: "turns_v2_invite_codes",
+				Up: []string{
+					`CREATE TABLE IF NOT EXISTS "invite_codes" (
+						event_id        UUID            NOT NULL,
+						user_id         VARCHAR(36)     NOT NULL,
+						code            VARCHAR(16)     NOT NULL,
+						created_at      TIMESTAMP       DEFAULT NOW(),
+						PRIMARY KEY (event_id, user_id)
+					)`,
+					`CREATE UNIQUE INDEX IF NOT EXISTS invite_codes_code_key ON "invite_codes" (event_id, code)`,
+				},
+				Down: []string{
+					`DROP TABLE "invite_codes"`,
+				},
+			},
+			{
+				Id: "access_token_v3_mfa",
+				Up: []string{
+					`ALTER TABLE "access_tokens" ADD COLUMN IF NOT EXISTS mfa BOOLEAN NOT NULL DEFAULT FALSE`,
+				},
+				Down: []string{
+					`ALTER TABLE "access_tokens" DROP COLUMN IF EXISTS mfa`,
+				},
+			},
+			{
+				Id: "voucher_v9_batch_attempt",
+				Up: []string{
+					`ALTER TABLE "voucher_batches" ADD COLUMN IF NOT EXISTS attempt INTEGER NOT NULL DEFAULT 0`,
+					`CREATE INDEX IF NOT EXISTS voucher_batches_status_idx ON "voucher_batches" (status, updated_at)`,
+					`CREATE INDEX IF NOT EXISTS vouchers_batch_id_idx ON "vouchers" (batch_id)`,
+				},
+				Down: []string{
+					`DROP INDEX IF EXISTS vouchers_batch_id_idx`,
+					`DROP INDEX IF EXISTS voucher_batches_status_idx`,
+					`ALTER TABLE "voucher_batches" DROP COLUMN IF EXISTS attempt`,
+				},
+			},
 		},
 	}
 
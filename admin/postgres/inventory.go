@@ -0,0 +1,273 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/db"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+var _ admin.InventoryRepository = (*inventoryRepository)(nil)
+
+type inventoryRepository struct {
+	db db.Database
+	l  log.Logger
+}
+
+func NewInventoryRepository(db db.Database, l log.Logger) admin.InventoryRepository {
+	return &inventoryRepository{
+		db: db,
+		l:  l,
+	}
+}
+
+func (r *inventoryRepository) GetTemplatesByEventID(ctx context.Context, eventID string) ([]admin.VoucherTemplate, error) {
+	query := `SELECT * FROM voucher_templates WHERE event_id = :event_id ORDER BY created_at`
+	params := map[string]interface{}{
+		"event_id": eventID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var templates []admin.VoucherTemplate
+	for rows.Next() {
+		var template admin.VoucherTemplate
+		if err := rows.StructScan(&template); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+func (r *inventoryRepository) GetTemplateByID(ctx context.Context, id string, eventID string) (admin.VoucherTemplate, error) {
+	query := `SELECT * FROM voucher_templates WHERE id = :id AND event_id = :event_id`
+	params := map[string]interface{}{
+		"id":       id,
+		"event_id": eventID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.VoucherTemplate{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var template admin.VoucherTemplate
+	if rows.Next() {
+		if err := rows.StructScan(&template); err != nil {
+			return admin.VoucherTemplate{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return template, nil
+	} else {
+		return admin.VoucherTemplate{}, admin.ErrTemplateNotFound
+	}
+}
+
+func (r *inventoryRepository) CreateTemplate(ctx context.Context, template admin.VoucherTemplate) (string, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", errors.Wrap(ErrInsertDb, err)
+	}
+	defer tx.Rollback()
+
+	if err := checkEventCapacity(ctx, tx, template.EventID, "", template.Quantity); err != nil {
+		return "", err
+	}
+
+	query := `INSERT INTO voucher_templates (event_id, name, images, value, description, expired_time, quantity, rule)
+		VALUES (:event_id, :name, :images, :value, :description, :expired_time, :quantity, :rule) RETURNING id`
+	params := map[string]interface{}{
+		"event_id":     template.EventID,
+		"name":         template.Name,
+		"images":       template.Images,
+		"value":        template.Value,
+		"description":  template.Description,
+		"expired_time": template.ExpiredTime,
+		"quantity":     template.Quantity,
+		"rule":         template.Rule,
+	}
+	rows, err := tx.NamedQuery(query, params)
+	if err != nil {
+		return "", errors.Wrap(ErrInsertDb, err)
+	}
+	var id string
+	if rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return "", errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return "", errors.Wrap(ErrInsertDb, err)
+	}
+	return id, nil
+}
+
+func (r *inventoryRepository) UpdateTemplateQuantity(ctx context.Context, id string, eventID string, quantity int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	if err := checkEventCapacity(ctx, tx, eventID, id, quantity); err != nil {
+		return err
+	}
+
+	query := `UPDATE voucher_templates SET quantity = :quantity, updated_at = NOW() WHERE id = :id AND event_id = :event_id AND issued <= :quantity`
+	params := map[string]interface{}{
+		"id":       id,
+		"event_id": eventID,
+		"quantity": quantity,
+	}
+	res, err := tx.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrInventoryExceeded
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	return nil
+}
+
+func (r *inventoryRepository) IssueVoucher(ctx context.Context, templateID string, eventID string, userID string, code string) (admin.Voucher, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return admin.Voucher{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	voucher, err := issueVoucher(ctx, tx, templateID, eventID, userID, code)
+	if err != nil {
+		return admin.Voucher{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return admin.Voucher{}, errors.Wrap(ErrInsertDb, err)
+	}
+	return voucher, nil
+}
+
+// issueVoucher takes one voucher out of the template's stock and stores it
+// as owned by userID, within tx.
+func issueVoucher(ctx context.Context, tx *sqlx.Tx, templateID string, eventID string, userID string, code string) (admin.Voucher, error) {
+	// The conditional update takes the row lock, so concurrent issuers queue
+	// up here and re-check `issued < quantity` against the committed value.
+	query := `UPDATE voucher_templates SET issued = issued + 1, updated_at = NOW()
+		WHERE id = :id AND event_id = :event_id AND issued < quantity
+		RETURNING *`
+	params := map[string]interface{}{
+		"id":       templateID,
+		"event_id": eventID,
+	}
+	rows, err := tx.NamedQuery(query, params)
+	if err != nil {
+		return admin.Voucher{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	var template admin.VoucherTemplate
+	found := rows.Next()
+	if found {
+		if err := rows.StructScan(&template); err != nil {
+			rows.Close()
+			return admin.Voucher{}, errors.Wrap(ErrSelectDb, err)
+		}
+	}
+	rows.Close()
+	if !found {
+		return admin.Voucher{}, admin.ErrOutOfStock
+	}
+
+	insertQuery := `INSERT INTO vouchers (code, qrcode, images, value, description, expired_time, status, event_id, template_id, owner_id, rule)
+		VALUES (:code, :qrcode, :images, :value, :description, :expired_time, :status, :event_id, :template_id, :owner_id, :rule)
+		RETURNING *`
+	insertParams := map[string]interface{}{
+		"code":         code,
+		"qrcode":       code,
+		"images":       template.Images,
+		"value":        template.Value,
+		"description":  template.Description,
+		"expired_time": template.ExpiredTime,
+		"status":       admin.VoucherActive,
+		"event_id":     eventID,
+		"template_id":  templateID,
+		"owner_id":     userID,
+		"rule":         template.Rule,
+	}
+	rows, err = tx.NamedQuery(insertQuery, insertParams)
+	if isUniqueViolation(err) {
+		return admin.Voucher{}, admin.ErrVoucherCodeTaken
+	}
+	if err != nil {
+		return admin.Voucher{}, errors.Wrap(ErrInsertDb, err)
+	}
+	var voucher admin.Voucher
+	if rows.Next() {
+		if err := rows.StructScan(&voucher); err != nil {
+			rows.Close()
+			return admin.Voucher{}, errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	rows.Close()
+	return voucher, nil
+}
+
+// checkEventCapacity locks the event row and verifies that the event's
+// vouchers, with the template excludeID set to quantity and quantity more
+// vouchers otherwise, fit in voucher_num. Locking the event serialises
+// concurrent changes to the inventory of one event.
+func checkEventCapacity(ctx context.Context, tx *sqlx.Tx, eventID string, excludeID string, quantity int) error {
+	var voucherNum int
+	err := tx.QueryRowxContext(ctx, `SELECT voucher_num FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&voucherNum)
+	if err == sql.ErrNoRows {
+		return admin.ErrEventNotFound
+	}
+	if err != nil {
+		return errors.Wrap(ErrSelectDb, err)
+	}
+	allocated, err := allocatedVouchers(ctx, tx, eventID, excludeID)
+	if err != nil {
+		return err
+	}
+	if allocated+quantity > voucherNum {
+		return admin.ErrInventoryExceeded
+	}
+	return nil
+}
+
+// checkVoucherNum verifies that the event's vouchers fit in voucherNum. The
+// caller must hold the lock on the event row.
+func checkVoucherNum(ctx context.Context, tx *sqlx.Tx, eventID string, voucherNum int) error {
+	allocated, err := allocatedVouchers(ctx, tx, eventID, "")
+	if err != nil {
+		return err
+	}
+	if allocated > voucherNum {
+		return admin.ErrInventoryExceeded
+	}
+	return nil
+}
+
+// allocatedVouchers returns how much of the event's voucher_num is spoken
+// for: the quantities of its templates other than excludeID, the vouchers
+// created one by one, and the count of every batch that did not fail. A
+// failed batch keeps what it generated.
+func allocatedVouchers(ctx context.Context, tx *sqlx.Tx, eventID string, excludeID string) (int, error) {
+	query := `SELECT
+		(SELECT COALESCE(SUM(quantity), 0) FROM voucher_templates WHERE event_id = $1 AND id::text <> $2)
+		+ (SELECT COUNT(*) FROM vouchers WHERE event_id = $1 AND template_id = '' AND batch_id = '')
+		+ (SELECT COALESCE(SUM(CASE WHEN status = $3 THEN generated ELSE count END), 0) FROM voucher_batches WHERE event_id = $1)`
+	var allocated int
+	if err := tx.QueryRowxContext(ctx, query, eventID, excludeID, admin.BatchFailed).Scan(&allocated); err != nil {
+		return 0, errors.Wrap(ErrSelectDb, err)
+	}
+	return allocated, nil
+}
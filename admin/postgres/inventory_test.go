@@ -0,0 +1,223 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/db"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+// testDSNEnv names the variable holding the DSN of a disposable database the
+// repository tests may migrate and write to. They are skipped without it.
+const testDSNEnv = "VOU_TEST_DB_DSN"
+
+func connectTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	conn, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetMaxOpenConns(20)
+	if err := migrateDB(conn.DB); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+	return conn
+}
+
+func createTestEvent(t *testing.T, conn *sqlx.DB, voucherNum int) string {
+	t.Helper()
+	var eventID string
+	err := conn.Get(&eventID, `INSERT INTO events (user_id, name, images, voucher_num, start_time, end_time, game_id)
+		VALUES (uuid_generate_v4(), 'test', '', $1, NOW(), NOW() + INTERVAL '1 day', uuid_generate_v4()) RETURNING id`, voucherNum)
+	if err != nil {
+		t.Fatalf("create event: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Exec(`DELETE FROM vouchers WHERE event_id = $1`, eventID)
+		conn.Exec(`DELETE FROM voucher_templates WHERE event_id = $1`, eventID)
+		conn.Exec(`DELETE FROM voucher_batches WHERE event_id = $1`, eventID)
+		conn.Exec(`DELETE FROM events WHERE id = $1`, eventID)
+	})
+	return eventID
+}
+
+func TestIssueVoucherNeverOversells(t *testing.T) {
+	conn := connectTestDB(t)
+	ctx := context.Background()
+	logger, err := log.New(io.Discard, "error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewInventoryRepository(db.NewReadWrite(conn, conn), logger)
+
+	const quantity = 10
+	const callers = 60
+
+	eventID := createTestEvent(t, conn, quantity)
+
+	templateID, err := repo.CreateTemplate(ctx, admin.VoucherTemplate{
+		EventID:     eventID,
+		Name:        "stress",
+		Value:       10,
+		ExpiredTime: time.Now().Add(24 * time.Hour),
+		Quantity:    quantity,
+	})
+	if err != nil {
+		t.Fatalf("create template: %v", err)
+	}
+
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		issued     int
+		outOfStock int
+		failures   []error
+	)
+	start := make(chan struct{})
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			code := fmt.Sprintf("STRESS%s%03d", eventID[:8], i)
+			_, err := repo.IssueVoucher(ctx, templateID, eventID, fmt.Sprintf("player-%d", i), code)
+			mu.Lock()
+			defer mu.Unlock()
+			switch err {
+			case nil:
+				issued++
+			case admin.ErrOutOfStock:
+				outOfStock++
+			default:
+				failures = append(failures, err)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	if len(failures) > 0 {
+		t.Fatalf("unexpected errors: %v", failures)
+	}
+	if issued != quantity {
+		t.Errorf("issued %d vouchers, want %d", issued, quantity)
+	}
+	if outOfStock != callers-quantity {
+		t.Errorf("%d calls ran out of stock, want %d", outOfStock, callers-quantity)
+	}
+
+	var stored int
+	if err := conn.GetContext(ctx, &stored, `SELECT COUNT(*) FROM vouchers WHERE template_id = $1`, templateID); err != nil {
+		t.Fatal(err)
+	}
+	if stored != quantity {
+		t.Errorf("stored %d vouchers, want %d", stored, quantity)
+	}
+	template, err := repo.GetTemplateByID(ctx, templateID, eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if template.Issued != quantity {
+		t.Errorf("template issued = %d, want %d", template.Issued, quantity)
+	}
+}
+
+func TestUpdateEventKeepsTemplatesInVoucherNum(t *testing.T) {
+	conn := connectTestDB(t)
+	ctx := context.Background()
+	logger, err := log.New(io.Discard, "error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := db.NewReadWrite(conn, conn)
+	inventory := NewInventoryRepository(database, logger)
+	events := NewEventRepository(database, logger)
+
+	eventID := createTestEvent(t, conn, 10)
+	_, err = inventory.CreateTemplate(ctx, admin.VoucherTemplate{
+		EventID:     eventID,
+		Name:        "capacity",
+		ExpiredTime: time.Now().Add(24 * time.Hour),
+		Quantity:    8,
+	})
+	if err != nil {
+		t.Fatalf("create template: %v", err)
+	}
+
+	setVoucherNum := func(n int) error {
+		return events.UpdateEvent(ctx, eventID, func(current admin.Event) (admin.Event, error) {
+			return admin.Event{VoucherNum: n}, nil
+		})
+	}
+	if err := setVoucherNum(7); err != admin.ErrInventoryExceeded {
+		t.Fatalf("shrinking below the templates: expected %v, got %v", admin.ErrInventoryExceeded, err)
+	}
+	if err := setVoucherNum(8); err != nil {
+		t.Fatalf("shrinking to the templates: %v", err)
+	}
+	event, err := events.GetEvent(ctx, eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.VoucherNum != 8 {
+		t.Errorf("voucher_num = %d, want 8", event.VoucherNum)
+	}
+}
+
+func TestVouchersAndBatchesCountAgainstVoucherNum(t *testing.T) {
+	conn := connectTestDB(t)
+	ctx := context.Background()
+	logger, err := log.New(io.Discard, "error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := db.NewReadWrite(conn, conn)
+	inventory := NewInventoryRepository(database, logger)
+	vouchers := NewVoucherRepository(database, logger)
+	batches := NewVoucherBatchRepository(database, logger)
+
+	eventID := createTestEvent(t, conn, 10)
+	expires := time.Now().Add(24 * time.Hour)
+	if _, err := inventory.CreateTemplate(ctx, admin.VoucherTemplate{EventID: eventID, Name: "capacity", ExpiredTime: expires, Quantity: 5}); err != nil {
+		t.Fatalf("create template: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		voucher := admin.Voucher{Code: fmt.Sprintf("CAP-%s-%d", eventID, i), EventID: eventID, ExpiredTime: expires, Status: admin.VoucherActive}
+		if err := vouchers.CreateVoucher(ctx, voucher); err != nil {
+			t.Fatalf("create voucher %d: %v", i, err)
+		}
+	}
+	batch := admin.VoucherBatch{EventID: eventID, Count: 4, Status: admin.BatchPending, Length: 8, Charset: admin.CharsetAlphanumeric, Checksum: admin.ChecksumNone, ExpiredTime: expires}
+	if _, err := batches.CreateBatch(ctx, batch); err != admin.ErrInventoryExceeded {
+		t.Fatalf("batch past voucher_num: expected %v, got %v", admin.ErrInventoryExceeded, err)
+	}
+	batch.Count = 3
+	batch.ID, err = batches.CreateBatch(ctx, batch)
+	if err != nil {
+		t.Fatalf("batch filling voucher_num: %v", err)
+	}
+	voucher := admin.Voucher{Code: fmt.Sprintf("CAP-%s-full", eventID), EventID: eventID, ExpiredTime: expires, Status: admin.VoucherActive}
+	if err := vouchers.CreateVoucher(ctx, voucher); err != admin.ErrInventoryExceeded {
+		t.Fatalf("voucher past voucher_num: expected %v, got %v", admin.ErrInventoryExceeded, err)
+	}
+	codes := []string{batch.ID + "-1", batch.ID + "-2", batch.ID + "-3", batch.ID + "-4"}
+	if _, err := batches.InsertBatchVouchers(ctx, batch, codes); err != admin.ErrInventoryExceeded {
+		t.Fatalf("batch past its count: expected %v, got %v", admin.ErrInventoryExceeded, err)
+	}
+	if n, err := batches.InsertBatchVouchers(ctx, batch, codes[:3]); err != nil || n != 3 {
+		t.Fatalf("batch within its count: inserted %d, %v", n, err)
+	}
+}
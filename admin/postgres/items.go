@@ -0,0 +1,459 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/db"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+var _ admin.ItemRepository = (*itemRepository)(nil)
+
+type itemRepository struct {
+	db db.Database
+	l  log.Logger
+}
+
+func NewItemRepository(db db.Database, l log.Logger) admin.ItemRepository {
+	return &itemRepository{
+		db: db,
+		l:  l,
+	}
+}
+
+func (r *itemRepository) CreateItem(ctx context.Context, item admin.Item) (string, error) {
+	query := `INSERT INTO event_items (event_id, code, name, image, rarity)
+		VALUES (:event_id, :code, :name, :image, :rarity) RETURNING id`
+	params := map[string]interface{}{
+		"event_id": item.EventID,
+		"code":     item.Code,
+		"name":     item.Name,
+		"image":    item.Image,
+		"rarity":   item.Rarity,
+	}
+	rows, err := r.db.NamedExecWithResponse(ctx, query, params)
+	if isUniqueViolation(err) {
+		return "", admin.ErrItemCodeTaken
+	}
+	if err != nil {
+		return "", errors.Wrap(ErrInsertDb, err)
+	}
+	defer rows.Close()
+	var id string
+	if rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			return "", errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	return id, nil
+}
+
+func (r *itemRepository) GetItems(ctx context.Context, eventID string) ([]admin.Item, error) {
+	query := `SELECT * FROM event_items WHERE event_id = :event_id ORDER BY created_at, code`
+	params := map[string]interface{}{
+		"event_id": eventID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	items := []admin.Item{}
+	for rows.Next() {
+		var item admin.Item
+		if err := rows.StructScan(&item); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (r *itemRepository) GetItem(ctx context.Context, id string, eventID string) (admin.Item, error) {
+	query := `SELECT * FROM event_items WHERE id = :id AND event_id = :event_id`
+	params := map[string]interface{}{
+		"id":       id,
+		"event_id": eventID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.Item{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var item admin.Item
+	if rows.Next() {
+		if err := rows.StructScan(&item); err != nil {
+			return admin.Item{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return item, nil
+	} else {
+		return admin.Item{}, admin.ErrItemNotFound
+	}
+}
+
+func (r *itemRepository) UpdateItem(ctx context.Context, item admin.Item) error {
+	query := `UPDATE event_items SET name = :name, image = :image, rarity = :rarity, updated_at = NOW()
+		WHERE id = :id AND event_id = :event_id`
+	params := map[string]interface{}{
+		"id":       item.ID,
+		"event_id": item.EventID,
+		"name":     item.Name,
+		"image":    item.Image,
+		"rarity":   item.Rarity,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrItemNotFound
+	}
+	return nil
+}
+
+// GetInventory lists what the player holds. Items won before they were
+// added to the catalog show under their code.
+func (r *itemRepository) GetInventory(ctx context.Context, eventID string, userID string) ([]admin.InventoryItem, error) {
+	query := `SELECT p.event_id, p.item_code, p.quantity, p.updated_at,
+			COALESCE(i.name, p.item_code) AS name, COALESCE(i.image, '') AS image, COALESCE(i.rarity, :common) AS rarity
+		FROM player_items p LEFT JOIN event_items i ON i.event_id = p.event_id AND i.code = p.item_code
+		WHERE p.event_id = :event_id AND p.user_id = :user_id AND p.quantity > 0
+		ORDER BY p.item_code`
+	params := map[string]interface{}{
+		"event_id": eventID,
+		"user_id":  userID,
+		"common":   admin.RarityCommon,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	items := []admin.InventoryItem{}
+	for rows.Next() {
+		var item admin.InventoryItem
+		if err := rows.StructScan(&item); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (r *itemRepository) CreateRecipe(ctx context.Context, recipe admin.Recipe) (string, error) {
+	query := `INSERT INTO item_recipes (event_id, name, template_id, ingredients)
+		VALUES (:event_id, :name, :template_id, :ingredients) RETURNING id`
+	params := map[string]interface{}{
+		"event_id":    recipe.EventID,
+		"name":        recipe.Name,
+		"template_id": recipe.TemplateID,
+		"ingredients": recipe.Ingredients,
+	}
+	rows, err := r.db.NamedExecWithResponse(ctx, query, params)
+	if err != nil {
+		return "", errors.Wrap(ErrInsertDb, err)
+	}
+	defer rows.Close()
+	var id string
+	if rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			return "", errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	return id, nil
+}
+
+func (r *itemRepository) GetRecipes(ctx context.Context, eventID string) ([]admin.Recipe, error) {
+	query := `SELECT * FROM item_recipes WHERE event_id = :event_id ORDER BY created_at`
+	params := map[string]interface{}{
+		"event_id": eventID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	recipes := []admin.Recipe{}
+	for rows.Next() {
+		var recipe admin.Recipe
+		if err := rows.StructScan(&recipe); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		recipes = append(recipes, recipe)
+	}
+	return recipes, nil
+}
+
+func (r *itemRepository) GetRecipe(ctx context.Context, id string, eventID string) (admin.Recipe, error) {
+	query := `SELECT * FROM item_recipes WHERE id = :id AND event_id = :event_id`
+	params := map[string]interface{}{
+		"id":       id,
+		"event_id": eventID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.Recipe{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var recipe admin.Recipe
+	if rows.Next() {
+		if err := rows.StructScan(&recipe); err != nil {
+			return admin.Recipe{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return recipe, nil
+	} else {
+		return admin.Recipe{}, admin.ErrRecipeNotFound
+	}
+}
+
+func (r *itemRepository) UpdateRecipe(ctx context.Context, recipe admin.Recipe) error {
+	query := `UPDATE item_recipes SET name = :name, template_id = :template_id, ingredients = :ingredients, updated_at = NOW()
+		WHERE id = :id AND event_id = :event_id`
+	params := map[string]interface{}{
+		"id":          recipe.ID,
+		"event_id":    recipe.EventID,
+		"name":        recipe.Name,
+		"template_id": recipe.TemplateID,
+		"ingredients": recipe.Ingredients,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrRecipeNotFound
+	}
+	return nil
+}
+
+func (r *itemRepository) DeleteRecipe(ctx context.Context, id string, eventID string) error {
+	query := `DELETE FROM item_recipes WHERE id = :id AND event_id = :event_id`
+	params := map[string]interface{}{
+		"id":       id,
+		"event_id": eventID,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrDeleteDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrRecipeNotFound
+	}
+	return nil
+}
+
+func (r *itemRepository) ExchangeRecipe(ctx context.Context, recipe admin.Recipe, userID string, code string) (admin.Voucher, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return admin.Voucher{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	var deltas []itemDelta
+	for _, stack := range recipe.Ingredients {
+		deltas = append(deltas, itemDelta{userID: userID, itemCode: stack.ItemCode, amount: -stack.Quantity})
+	}
+	if err := applyItemDeltas(ctx, tx, recipe.EventID, deltas); err != nil {
+		return admin.Voucher{}, err
+	}
+	voucher, err := issueVoucher(ctx, tx, recipe.TemplateID, recipe.EventID, userID, code)
+	if err != nil {
+		return admin.Voucher{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return admin.Voucher{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	return voucher, nil
+}
+
+func (r *itemRepository) GiftItems(ctx context.Context, eventID string, fromUserID string, toUserID string, items admin.ItemStacks) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	if err := applyItemDeltas(ctx, tx, eventID, moveItems(fromUserID, toUserID, items)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	return nil
+}
+
+func (r *itemRepository) CreateItemTrade(ctx context.Context, trade admin.ItemTrade) (admin.ItemTrade, error) {
+	query := `INSERT INTO item_trades (event_id, from_user_id, to_user_id, give, want, status)
+		VALUES (:event_id, :from_user_id, :to_user_id, :give, :want, :status) RETURNING *`
+	params := map[string]interface{}{
+		"event_id":     trade.EventID,
+		"from_user_id": trade.FromUserID,
+		"to_user_id":   trade.ToUserID,
+		"give":         trade.Give,
+		"want":         trade.Want,
+		"status":       admin.ItemTradePending,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.ItemTrade{}, errors.Wrap(ErrInsertDb, err)
+	}
+	defer rows.Close()
+	var created admin.ItemTrade
+	if rows.Next() {
+		if err := rows.StructScan(&created); err != nil {
+			return admin.ItemTrade{}, errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	return created, nil
+}
+
+func (r *itemRepository) GetItemTrade(ctx context.Context, id string) (admin.ItemTrade, error) {
+	query := `SELECT * FROM item_trades WHERE id = :id`
+	params := map[string]interface{}{
+		"id": id,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.ItemTrade{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var trade admin.ItemTrade
+	if rows.Next() {
+		if err := rows.StructScan(&trade); err != nil {
+			return admin.ItemTrade{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return trade, nil
+	} else {
+		return admin.ItemTrade{}, admin.ErrItemTradeNotFound
+	}
+}
+
+func (r *itemRepository) GetPendingItemTradesByUser(ctx context.Context, eventID string, userID string) ([]admin.ItemTrade, error) {
+	query := `SELECT * FROM item_trades
+		WHERE event_id = :event_id AND (from_user_id = :user_id OR to_user_id = :user_id) AND status = :pending
+		ORDER BY created_at DESC`
+	params := map[string]interface{}{
+		"event_id": eventID,
+		"user_id":  userID,
+		"pending":  admin.ItemTradePending,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	trades := []admin.ItemTrade{}
+	for rows.Next() {
+		var trade admin.ItemTrade
+		if err := rows.StructScan(&trade); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		trades = append(trades, trade)
+	}
+	return trades, nil
+}
+
+func (r *itemRepository) AcceptItemTrade(ctx context.Context, id string) (admin.ItemTrade, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return admin.ItemTrade{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	var trade admin.ItemTrade
+	err = tx.GetContext(ctx, &trade, `SELECT * FROM item_trades WHERE id = $1 FOR UPDATE`, id)
+	if err == sql.ErrNoRows {
+		return admin.ItemTrade{}, admin.ErrItemTradeNotFound
+	}
+	if err != nil {
+		return admin.ItemTrade{}, errors.Wrap(ErrSelectDb, err)
+	}
+	if trade.Status != admin.ItemTradePending {
+		return admin.ItemTrade{}, admin.ErrItemTradeClosed
+	}
+
+	deltas := append(moveItems(trade.FromUserID, trade.ToUserID, trade.Give), moveItems(trade.ToUserID, trade.FromUserID, trade.Want)...)
+	if err := applyItemDeltas(ctx, tx, trade.EventID, deltas); err != nil {
+		return admin.ItemTrade{}, err
+	}
+	err = tx.GetContext(ctx, &trade, `UPDATE item_trades SET status = $1, updated_at = NOW() WHERE id = $2 RETURNING *`, admin.ItemTradeAccepted, trade.ID)
+	if err != nil {
+		return admin.ItemTrade{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return admin.ItemTrade{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	return trade, nil
+}
+
+func (r *itemRepository) CloseItemTrade(ctx context.Context, id string, status string) error {
+	query := `UPDATE item_trades SET status = :status, updated_at = NOW() WHERE id = :id AND status = :pending`
+	params := map[string]interface{}{
+		"id":      id,
+		"status":  status,
+		"pending": admin.ItemTradePending,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrItemTradeClosed
+	}
+	return nil
+}
+
+// itemDelta changes how many of an item a player holds.
+type itemDelta struct {
+	userID   string
+	itemCode string
+	amount   int
+}
+
+func moveItems(fromUserID string, toUserID string, items admin.ItemStacks) []itemDelta {
+	var deltas []itemDelta
+	for _, stack := range items {
+		deltas = append(deltas,
+			itemDelta{userID: fromUserID, itemCode: stack.ItemCode, amount: -stack.Quantity},
+			itemDelta{userID: toUserID, itemCode: stack.ItemCode, amount: stack.Quantity})
+	}
+	return deltas
+}
+
+// applyItemDeltas applies deltas in player and item order, so transactions
+// touching the same inventories lock their rows in the same order and cannot
+// deadlock. A debit only matches a row holding enough of the item.
+func applyItemDeltas(ctx context.Context, tx *sqlx.Tx, eventID string, deltas []itemDelta) error {
+	sort.SliceStable(deltas, func(i, j int) bool {
+		if deltas[i].userID != deltas[j].userID {
+			return deltas[i].userID < deltas[j].userID
+		}
+		return deltas[i].itemCode < deltas[j].itemCode
+	})
+	for _, d := range deltas {
+		if d.amount < 0 {
+			res, err := tx.ExecContext(ctx, `UPDATE player_items SET quantity = quantity + $1, updated_at = NOW()
+				WHERE user_id = $2 AND event_id = $3 AND item_code = $4 AND quantity >= -$1`,
+				d.amount, d.userID, eventID, d.itemCode)
+			if err != nil {
+				return errors.Wrap(ErrUpdateDb, err)
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				return admin.ErrNotEnoughItems
+			}
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO player_items (user_id, event_id, item_code, quantity) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, event_id, item_code) DO UPDATE SET quantity = player_items.quantity + $4, updated_at = NOW()`,
+			d.userID, eventID, d.itemCode, d.amount)
+		if err != nil {
+			return errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Leader elects one replica to run background jobs. The replica that holds
+// a session level advisory lock on key is the leader. The lock lives on a
+// dedicated connection, so Postgres frees it as soon as the leader dies or
+// loses its connection and another replica can take over.
+type Leader struct {
+	db   *sqlx.DB
+	key  int64
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+// NewLeader returns a Leader competing for the advisory lock key.
+func NewLeader(db *sqlx.DB, key int64) *Leader {
+	return &Leader{
+		db:  db,
+		key: key,
+	}
+}
+
+// IsLeader reports whether this replica holds the lock, trying to take it
+// when it does not.
+func (l *Leader) IsLeader(ctx context.Context) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err == nil {
+			return true
+		}
+		l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, l.key).Scan(&acquired); err != nil || !acquired {
+		conn.Close()
+		return false
+	}
+	l.conn = conn
+	return true
+}
+
+// Resign releases the lock if this replica holds it.
+func (l *Leader) Resign(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return
+	}
+	l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.key)
+	l.conn.Close()
+	l.conn = nil
+}
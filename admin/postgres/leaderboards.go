@@ -0,0 +1,292 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/db"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+var _ admin.LeaderboardRepository = (*leaderboardRepository)(nil)
+
+type leaderboardRepository struct {
+	db db.Database
+	l  log.Logger
+}
+
+func NewLeaderboardRepository(db db.Database, l log.Logger) admin.LeaderboardRepository {
+	return &leaderboardRepository{
+		db: db,
+		l:  l,
+	}
+}
+
+func (r *leaderboardRepository) RecordScore(ctx context.Context, entry admin.ScoreEntry) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(ErrInsertDb, err)
+	}
+	defer tx.Rollback()
+
+	if err := recordScore(ctx, tx, entry); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(ErrInsertDb, err)
+	}
+	return nil
+}
+
+// recordScore adds the entry to the boards in tx, so it is kept exactly when
+// the play that scored it is. It holds a share lock on the event row, so the
+// event cannot end, and its standings be frozen, while the points are being
+// added.
+func recordScore(ctx context.Context, tx *sqlx.Tx, entry admin.ScoreEntry) error {
+	if entry.Points <= 0 {
+		return nil
+	}
+	var gameID string
+	err := tx.GetContext(ctx, &gameID, `SELECT game_id FROM events WHERE id = $1 AND status = $2 FOR SHARE`, entry.EventID, admin.EventRunning)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(ErrSelectDb, err)
+	}
+	res, err := tx.ExecContext(ctx, `INSERT INTO score_entries (event_id, game_id, user_id, points, source, ref)
+		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (event_id, user_id, source, ref) DO NOTHING`,
+		entry.EventID, gameID, entry.UserID, entry.Points, entry.Source, entry.Ref)
+	if err != nil {
+		return errors.Wrap(ErrInsertDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	boards := []admin.Board{
+		{ScopeID: entry.EventID, Period: admin.PeriodDaily, Start: admin.PeriodStart(admin.PeriodDaily, now)},
+		{ScopeID: entry.EventID, Period: admin.PeriodWeekly, Start: admin.PeriodStart(admin.PeriodWeekly, now)},
+		{ScopeID: entry.EventID, Period: admin.PeriodEvent},
+		{ScopeID: gameID, Period: admin.PeriodAllTime},
+	}
+	for _, b := range boards {
+		_, err := tx.ExecContext(ctx, `INSERT INTO leaderboard_scores (scope_id, period, period_start, user_id, points)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (scope_id, period, period_start, user_id)
+			DO UPDATE SET points = leaderboard_scores.points + EXCLUDED.points, updated_at = NOW()`,
+			b.ScopeID, b.Period, b.Start, entry.UserID, entry.Points)
+		if err != nil {
+			return errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	return nil
+}
+
+func (r *leaderboardRepository) GetTop(ctx context.Context, board admin.Board, limit int) ([]admin.LeaderboardRow, error) {
+	query := `SELECT ROW_NUMBER() OVER (ORDER BY s.points DESC, s.updated_at, s.user_id) AS rank,
+			s.user_id, COALESCE(u.username, '') AS username, s.points, s.updated_at
+		FROM (
+			SELECT user_id, points, updated_at FROM leaderboard_scores
+			WHERE scope_id = :scope_id AND period = :period AND period_start = :period_start
+			ORDER BY points DESC, updated_at, user_id
+			LIMIT :limit
+		) s LEFT JOIN users u ON u.id::text = s.user_id
+		ORDER BY rank`
+	params := map[string]interface{}{
+		"scope_id":     board.ScopeID,
+		"period":       board.Period,
+		"period_start": board.Start,
+		"limit":        limit,
+	}
+	return r.queryRows(ctx, query, params)
+}
+
+// GetAround counts the players ahead to rank the player, then walks the
+// board index a few rows up and down from them, so the cost does not grow
+// with the size of the board beyond the count.
+func (r *leaderboardRepository) GetAround(ctx context.Context, board admin.Board, userID string, radius int) (admin.LeaderboardRow, []admin.LeaderboardRow, error) {
+	params := map[string]interface{}{
+		"scope_id":     board.ScopeID,
+		"period":       board.Period,
+		"period_start": board.Start,
+		"user_id":      userID,
+		"radius":       radius,
+	}
+	rows, err := r.queryRows(ctx, `SELECT 1 + (
+			SELECT COUNT(*) FROM leaderboard_scores o
+			WHERE o.scope_id = s.scope_id AND o.period = s.period AND o.period_start = s.period_start
+			AND o.points >= s.points
+			AND (o.points > s.points OR (o.updated_at, o.user_id) < (s.updated_at, s.user_id))
+		) AS rank, s.user_id, COALESCE(u.username, '') AS username, s.points, s.updated_at
+		FROM leaderboard_scores s LEFT JOIN users u ON u.id::text = s.user_id
+		WHERE s.scope_id = :scope_id AND s.period = :period AND s.period_start = :period_start AND s.user_id = :user_id`, params)
+	if err != nil {
+		return admin.LeaderboardRow{}, nil, err
+	}
+	if len(rows) == 0 {
+		return admin.LeaderboardRow{}, nil, admin.ErrNotRanked
+	}
+	me := rows[0]
+	params["points"] = me.Points
+	params["updated_at"] = me.UpdatedAt
+
+	above, err := r.queryRows(ctx, `SELECT 0 AS rank, s.user_id, COALESCE(u.username, '') AS username, s.points, s.updated_at
+		FROM leaderboard_scores s LEFT JOIN users u ON u.id::text = s.user_id
+		WHERE s.scope_id = :scope_id AND s.period = :period AND s.period_start = :period_start
+		AND s.points >= :points AND (s.points > :points OR (s.updated_at, s.user_id) < (:updated_at, :user_id))
+		ORDER BY s.points, s.updated_at DESC, s.user_id DESC
+		LIMIT :radius`, params)
+	if err != nil {
+		return admin.LeaderboardRow{}, nil, err
+	}
+	below, err := r.queryRows(ctx, `SELECT 0 AS rank, s.user_id, COALESCE(u.username, '') AS username, s.points, s.updated_at
+		FROM leaderboard_scores s LEFT JOIN users u ON u.id::text = s.user_id
+		WHERE s.scope_id = :scope_id AND s.period = :period AND s.period_start = :period_start
+		AND s.points <= :points AND (s.points < :points OR (s.updated_at, s.user_id) > (:updated_at, :user_id))
+		ORDER BY s.points DESC, s.updated_at, s.user_id
+		LIMIT :radius`, params)
+	if err != nil {
+		return admin.LeaderboardRow{}, nil, err
+	}
+
+	around := make([]admin.LeaderboardRow, 0, len(above)+1+len(below))
+	for i := len(above) - 1; i >= 0; i-- {
+		above[i].Rank = me.Rank - int64(i+1)
+		around = append(around, above[i])
+	}
+	around = append(around, me)
+	for i, row := range below {
+		row.Rank = me.Rank + int64(i+1)
+		around = append(around, row)
+	}
+	return me, around, nil
+}
+
+func (r *leaderboardRepository) queryRows(ctx context.Context, query string, params map[string]interface{}) ([]admin.LeaderboardRow, error) {
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	board := []admin.LeaderboardRow{}
+	for rows.Next() {
+		var row admin.LeaderboardRow
+		if err := rows.StructScan(&row); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		board = append(board, row)
+	}
+	return board, nil
+}
+
+func (r *leaderboardRepository) FreezeStandings(ctx context.Context) (int64, error) {
+	query := `SELECT id FROM events e WHERE status = :ended
+		AND NOT EXISTS (SELECT 1 FROM leaderboard_snapshots s WHERE s.event_id = e.id)`
+	params := map[string]interface{}{
+		"ended": admin.EventEnded,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return 0, errors.Wrap(ErrSelectDb, err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, errors.Wrap(ErrSelectDb, err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	var frozen int64
+	for _, id := range ids {
+		ok, err := r.freeze(ctx, id)
+		if err != nil {
+			return frozen, err
+		}
+		if ok {
+			frozen++
+		}
+	}
+	return frozen, nil
+}
+
+// freeze copies the event board into the event's standings, unless another
+// replica got there first.
+func (r *leaderboardRepository) freeze(ctx context.Context, eventID string) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, errors.Wrap(ErrInsertDb, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO leaderboard_snapshots (event_id, players)
+		SELECT $1::uuid, COUNT(*) FROM leaderboard_scores WHERE scope_id = $1::uuid AND period = $2 AND period_start = $3
+		ON CONFLICT (event_id) DO NOTHING`,
+		eventID, admin.PeriodEvent, time.Time{})
+	if err != nil {
+		return false, errors.Wrap(ErrInsertDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO leaderboard_standings (event_id, rank, user_id, points)
+		SELECT scope_id, ROW_NUMBER() OVER (ORDER BY points DESC, updated_at, user_id), user_id, points
+		FROM leaderboard_scores WHERE scope_id = $1 AND period = $2 AND period_start = $3`,
+		eventID, admin.PeriodEvent, time.Time{})
+	if err != nil {
+		return false, errors.Wrap(ErrInsertDb, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, errors.Wrap(ErrInsertDb, err)
+	}
+	return true, nil
+}
+
+func (r *leaderboardRepository) GetStandings(ctx context.Context, eventID string, limit int, offset int) (admin.Standings, error) {
+	query := `SELECT * FROM leaderboard_snapshots WHERE event_id = :event_id`
+	params := map[string]interface{}{
+		"event_id": eventID,
+		"limit":    limit,
+		"offset":   offset,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.Standings{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var standings admin.Standings
+	if rows.Next() {
+		if err := rows.StructScan(&standings); err != nil {
+			return admin.Standings{}, errors.Wrap(ErrSelectDb, err)
+		}
+	} else {
+		return admin.Standings{}, admin.ErrStandingsNotFrozen
+	}
+
+	query = `SELECT s.rank, s.user_id, COALESCE(u.username, '') AS username, s.points
+		FROM leaderboard_standings s LEFT JOIN users u ON u.id::text = s.user_id
+		WHERE s.event_id = :event_id ORDER BY s.rank LIMIT :limit OFFSET :offset`
+	rows, err = r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.Standings{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	standings.Standings = []admin.Standing{}
+	for rows.Next() {
+		var standing admin.Standing
+		if err := rows.StructScan(&standing); err != nil {
+			return admin.Standings{}, errors.Wrap(ErrSelectDb, err)
+		}
+		standings.Standings = append(standings.Standings, standing)
+	}
+	return standings, nil
+}
@@ -0,0 +1,218 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/db"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+var _ admin.MFARepository = (*mfaRepository)(nil)
+
+type mfaRepository struct {
+	db db.Database
+	l  log.Logger
+}
+
+func NewMFARepository(db db.Database, l log.Logger) admin.MFARepository {
+	return &mfaRepository{
+		db: db,
+		l:  l,
+	}
+}
+
+func (r *mfaRepository) GetMFA(ctx context.Context, userID string) (admin.MFA, error) {
+	query := `SELECT * FROM user_mfa WHERE user_id = :user_id`
+	params := map[string]interface{}{
+		"user_id": userID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.MFA{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var mfa admin.MFA
+	if rows.Next() {
+		if err := rows.StructScan(&mfa); err != nil {
+			return admin.MFA{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return mfa, nil
+	} else {
+		return admin.MFA{}, admin.ErrMFANotEnrolled
+	}
+}
+
+func (r *mfaRepository) SaveSecret(ctx context.Context, userID string, secret string) error {
+	query := `INSERT INTO user_mfa (user_id, secret) VALUES (:user_id, :secret)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_step = 0, updated_at = NOW()
+		WHERE user_mfa.enabled_at IS NULL`
+	params := map[string]interface{}{
+		"user_id": userID,
+		"secret":  secret,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrInsertDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrMFAAlreadyEnabled
+	}
+	return nil
+}
+
+func (r *mfaRepository) EnableMFA(ctx context.Context, userID string, recoveryHashes []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE user_mfa SET enabled_at = NOW(), updated_at = NOW() WHERE user_id = $1 AND enabled_at IS NULL`, userID)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrMFAAlreadyEnabled
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	return nil
+}
+
+func (r *mfaRepository) DeleteMFA(ctx context.Context, userID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(ErrDeleteDb, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return errors.Wrap(ErrDeleteDb, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return errors.Wrap(ErrDeleteDb, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(ErrDeleteDb, err)
+	}
+	return nil
+}
+
+func (r *mfaRepository) UseStep(ctx context.Context, userID string, step int64) error {
+	query := `UPDATE user_mfa SET last_step = :step, updated_at = NOW() WHERE user_id = :user_id AND last_step < :step`
+	params := map[string]interface{}{
+		"user_id": userID,
+		"step":    step,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, hashes []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, hashes); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID string, hashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return errors.Wrap(ErrDeleteDb, err)
+	}
+	for _, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	return nil
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID string, hash string) error {
+	query := `UPDATE mfa_recovery_codes SET used_at = NOW() WHERE user_id = :user_id AND code_hash = :code_hash AND used_at IS NULL`
+	params := map[string]interface{}{
+		"user_id":   userID,
+		"code_hash": hash,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (r *mfaRepository) GetMFAPolicies(ctx context.Context) ([]admin.MFAPolicy, error) {
+	query := `SELECT * FROM mfa_policies ORDER BY role`
+	params := map[string]interface{}{}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	policies := []admin.MFAPolicy{}
+	for rows.Next() {
+		var policy admin.MFAPolicy
+		if err := rows.StructScan(&policy); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+func (r *mfaRepository) SetMFAPolicy(ctx context.Context, policy admin.MFAPolicy) error {
+	query := `INSERT INTO mfa_policies (role, enforced, updated_by) VALUES (:role, :enforced, :updated_by)
+		ON CONFLICT (role) DO UPDATE SET enforced = EXCLUDED.enforced, updated_by = EXCLUDED.updated_by, updated_at = NOW()`
+	params := map[string]interface{}{
+		"role":       policy.Role,
+		"enforced":   policy.Enforced,
+		"updated_by": policy.UpdatedBy,
+	}
+	if _, err := r.db.NamedExecContext(ctx, query, params); err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	return nil
+}
+
+func (r *mfaRepository) MFAEnforced(ctx context.Context, role string) (bool, error) {
+	query := `SELECT enforced FROM mfa_policies WHERE role = :role`
+	params := map[string]interface{}{
+		"role": role,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return false, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var enforced bool
+	if rows.Next() {
+		if err := rows.Scan(&enforced); err != nil {
+			return false, errors.Wrap(ErrSelectDb, err)
+		}
+	}
+	return enforced, nil
+}
@@ -0,0 +1,517 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/db"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+var _ admin.QuizRepository = (*quizRepository)(nil)
+
+type quizRepository struct {
+	db db.Database
+	l  log.Logger
+}
+
+func NewQuizRepository(db db.Database, l log.Logger) admin.QuizRepository {
+	return &quizRepository{
+		db: db,
+		l:  l,
+	}
+}
+
+func (r *quizRepository) CreateQuestionSet(ctx context.Context, set admin.QuestionSet) (string, error) {
+	query := `INSERT INTO quiz_question_sets (enterprise_id, name, description) VALUES (:enterprise_id, :name, :description) RETURNING id`
+	params := map[string]interface{}{
+		"enterprise_id": set.EnterpriseID,
+		"name":          set.Name,
+		"description":   set.Description,
+	}
+	return r.insertReturningID(ctx, query, params)
+}
+
+func (r *quizRepository) GetQuestionSet(ctx context.Context, id string) (admin.QuestionSet, error) {
+	query := `SELECT * FROM quiz_question_sets WHERE id = :id`
+	params := map[string]interface{}{
+		"id": id,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.QuestionSet{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var set admin.QuestionSet
+	if rows.Next() {
+		if err := rows.StructScan(&set); err != nil {
+			return admin.QuestionSet{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return set, nil
+	} else {
+		return admin.QuestionSet{}, admin.ErrQuestionSetNotFound
+	}
+}
+
+func (r *quizRepository) GetQuestionSetsByEnterpriseID(ctx context.Context, enterpriseID string) ([]admin.QuestionSet, error) {
+	query := `SELECT * FROM quiz_question_sets WHERE enterprise_id = :enterprise_id ORDER BY created_at`
+	params := map[string]interface{}{
+		"enterprise_id": enterpriseID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	sets := []admin.QuestionSet{}
+	for rows.Next() {
+		var set admin.QuestionSet
+		if err := rows.StructScan(&set); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+func (r *quizRepository) UpdateQuestionSet(ctx context.Context, set admin.QuestionSet) error {
+	query := `UPDATE quiz_question_sets SET `
+	params := map[string]interface{}{
+		"id": set.ID,
+	}
+
+	if set.Name != "" {
+		query += `name = :name, `
+		params["name"] = set.Name
+	}
+
+	if set.Description != "" {
+		query += `description = :description, `
+		params["description"] = set.Description
+	}
+
+	query += `updated_at = NOW() WHERE id = :id`
+	_, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	return nil
+}
+
+func (r *quizRepository) QuestionSetInPlay(ctx context.Context, id string) (bool, error) {
+	var inPlay bool
+	query := `SELECT EXISTS (SELECT 1 FROM events WHERE question_set_id = $1 AND status = $2)`
+	if err := r.db.GetContext(ctx, &inPlay, query, id, admin.EventRunning); err != nil {
+		return false, errors.Wrap(ErrSelectDb, err)
+	}
+	return inPlay, nil
+}
+
+func (r *quizRepository) AttachQuestionSet(ctx context.Context, eventID string, setID string, mode string, prizes admin.QuizPrizes) error {
+	query := `UPDATE events SET question_set_id = :set_id, quiz_mode = :mode, quiz_prizes = :prizes, updated_at = NOW()
+		WHERE id = :id AND status NOT IN (:running, :ended)`
+	params := map[string]interface{}{
+		"id":      eventID,
+		"set_id":  setID,
+		"mode":    mode,
+		"prizes":  prizes,
+		"running": admin.EventRunning,
+		"ended":   admin.EventEnded,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrQuestionSetLocked
+	}
+	return nil
+}
+
+// CreateQuestion appends the question to its set unless it names a
+// position.
+func (r *quizRepository) CreateQuestion(ctx context.Context, question admin.Question) (string, error) {
+	query := `INSERT INTO quiz_questions (set_id, position, text, media, choices, correct, time_limit, difficulty)
+		VALUES (:set_id, COALESCE(:position, (SELECT COALESCE(MAX(position) + 1, 0) FROM quiz_questions WHERE set_id = :set_id)),
+			:text, :media, :choices, :correct, :time_limit, :difficulty) RETURNING id`
+	params := map[string]interface{}{
+		"set_id":     question.SetID,
+		"position":   nil,
+		"text":       question.Text,
+		"media":      question.Media,
+		"choices":    question.Choices,
+		"correct":    question.Correct,
+		"time_limit": question.TimeLimit,
+		"difficulty": question.Difficulty,
+	}
+	if question.Position > 0 {
+		params["position"] = question.Position
+	}
+	return r.insertReturningID(ctx, query, params)
+}
+
+func (r *quizRepository) GetQuestion(ctx context.Context, id string, setID string) (admin.Question, error) {
+	query := `SELECT * FROM quiz_questions WHERE id = :id AND set_id = :set_id`
+	params := map[string]interface{}{
+		"id":     id,
+		"set_id": setID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.Question{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var question admin.Question
+	if rows.Next() {
+		if err := rows.StructScan(&question); err != nil {
+			return admin.Question{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return question, nil
+	} else {
+		return admin.Question{}, admin.ErrQuestionNotFound
+	}
+}
+
+func (r *quizRepository) GetQuestionsBySetID(ctx context.Context, setID string) ([]admin.Question, error) {
+	query := `SELECT * FROM quiz_questions WHERE set_id = :set_id ORDER BY position, created_at`
+	params := map[string]interface{}{
+		"set_id": setID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	questions := []admin.Question{}
+	for rows.Next() {
+		var question admin.Question
+		if err := rows.StructScan(&question); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		questions = append(questions, question)
+	}
+	return questions, nil
+}
+
+func (r *quizRepository) UpdateQuestion(ctx context.Context, question admin.Question) error {
+	query := `UPDATE quiz_questions SET position = :position, text = :text, media = :media, choices = :choices,
+		correct = :correct, time_limit = :time_limit, difficulty = :difficulty, updated_at = NOW()
+		WHERE id = :id AND set_id = :set_id`
+	params := map[string]interface{}{
+		"id":         question.ID,
+		"set_id":     question.SetID,
+		"position":   question.Position,
+		"text":       question.Text,
+		"media":      question.Media,
+		"choices":    question.Choices,
+		"correct":    question.Correct,
+		"time_limit": question.TimeLimit,
+		"difficulty": question.Difficulty,
+	}
+	_, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	return nil
+}
+
+func (r *quizRepository) DeleteQuestion(ctx context.Context, id string, setID string) error {
+	query := `DELETE FROM quiz_questions WHERE id = :id AND set_id = :set_id`
+	params := map[string]interface{}{
+		"id":     id,
+		"set_id": setID,
+	}
+	_, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrDeleteDb, err)
+	}
+	return nil
+}
+
+func (r *quizRepository) StartQuizAttempt(ctx context.Context, attempt admin.QuizAttempt) (admin.QuizAttempt, error) {
+	query := `INSERT INTO quiz_attempts (event_id, user_id, set_id, total, served_at, finished_at)
+		VALUES (:event_id, :user_id, :set_id, :total, NOW(), CASE WHEN :total = 0 THEN NOW() END)
+		ON CONFLICT (event_id, user_id) DO NOTHING RETURNING *`
+	params := map[string]interface{}{
+		"event_id": attempt.EventID,
+		"user_id":  attempt.UserID,
+		"set_id":   attempt.SetID,
+		"total":    attempt.Total,
+	}
+	rows, err := r.db.NamedExecWithResponse(ctx, query, params)
+	if err != nil {
+		return admin.QuizAttempt{}, errors.Wrap(ErrInsertDb, err)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var started admin.QuizAttempt
+		if err := rows.StructScan(&started); err != nil {
+			return admin.QuizAttempt{}, errors.Wrap(ErrInsertDb, err)
+		}
+		return started, nil
+	}
+	return r.GetQuizAttempt(ctx, attempt.EventID, attempt.UserID)
+}
+
+func (r *quizRepository) GetQuizAttempt(ctx context.Context, eventID string, userID string) (admin.QuizAttempt, error) {
+	query := `SELECT * FROM quiz_attempts WHERE event_id = :event_id AND user_id = :user_id`
+	params := map[string]interface{}{
+		"event_id": eventID,
+		"user_id":  userID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.QuizAttempt{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var attempt admin.QuizAttempt
+	if rows.Next() {
+		if err := rows.StructScan(&attempt); err != nil {
+			return admin.QuizAttempt{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return attempt, nil
+	} else {
+		return admin.QuizAttempt{}, admin.ErrQuizNotStarted
+	}
+}
+
+// AnswerQuizQuestion measures the time since the question was served on the
+// database clock, so neither the client nor a skewed app server can stretch
+// the time limit.
+func (r *quizRepository) AnswerQuizQuestion(ctx context.Context, eventID string, userID string, grade func(a admin.QuizAttempt, elapsed time.Duration) (admin.QuizAnswer, error)) (admin.QuizAttempt, admin.QuizAnswer, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return admin.QuizAttempt{}, admin.QuizAnswer{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	var attempt admin.QuizAttempt
+	err = tx.GetContext(ctx, &attempt, `SELECT * FROM quiz_attempts WHERE event_id = $1 AND user_id = $2 FOR UPDATE`, eventID, userID)
+	if err == sql.ErrNoRows {
+		return admin.QuizAttempt{}, admin.QuizAnswer{}, admin.ErrQuizNotStarted
+	}
+	if err != nil {
+		return admin.QuizAttempt{}, admin.QuizAnswer{}, errors.Wrap(ErrSelectDb, err)
+	}
+	if attempt.FinishedAt != nil {
+		return admin.QuizAttempt{}, admin.QuizAnswer{}, admin.ErrQuizFinished
+	}
+
+	var elapsedMS int64
+	if err := tx.GetContext(ctx, &elapsedMS, `SELECT (EXTRACT(EPOCH FROM clock_timestamp()::timestamp - served_at) * 1000)::bigint FROM quiz_attempts WHERE id = $1`, attempt.ID); err != nil {
+		return admin.QuizAttempt{}, admin.QuizAnswer{}, errors.Wrap(ErrSelectDb, err)
+	}
+	answer, err := grade(attempt, time.Duration(elapsedMS)*time.Millisecond)
+	if err != nil {
+		return admin.QuizAttempt{}, admin.QuizAnswer{}, err
+	}
+
+	answer.AttemptID = attempt.ID
+	err = tx.GetContext(ctx, &answer, `INSERT INTO quiz_answers (attempt_id, question_id, choices, correct, timed_out, points, elapsed_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *`,
+		answer.AttemptID, answer.QuestionID, answer.Choices, answer.Correct, answer.TimedOut, answer.Points, answer.ElapsedMS)
+	if isUniqueViolation(err) {
+		return admin.QuizAttempt{}, admin.QuizAnswer{}, admin.ErrQuestionAnswered
+	}
+	if err != nil {
+		return admin.QuizAttempt{}, admin.QuizAnswer{}, errors.Wrap(ErrInsertDb, err)
+	}
+
+	correct := 0
+	if answer.Correct {
+		correct = 1
+	}
+	err = tx.GetContext(ctx, &attempt, `UPDATE quiz_attempts SET position = position + 1, score = score + $1, correct = correct + $2,
+			served_at = CASE WHEN position + 1 < total THEN clock_timestamp() ELSE served_at END,
+			finished_at = CASE WHEN position + 1 >= total THEN NOW() END,
+			updated_at = NOW()
+		WHERE id = $3 RETURNING *`, answer.Points, correct, attempt.ID)
+	if err != nil {
+		return admin.QuizAttempt{}, admin.QuizAnswer{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	score := admin.ScoreEntry{EventID: eventID, UserID: userID, Points: answer.Points, Source: admin.ScoreQuiz, Ref: answer.QuestionID}
+	if err := recordScore(ctx, tx, score); err != nil {
+		return admin.QuizAttempt{}, admin.QuizAnswer{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return admin.QuizAttempt{}, admin.QuizAnswer{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	return attempt, answer, nil
+}
+
+func (r *quizRepository) GetQuizAnswers(ctx context.Context, attemptID string) ([]admin.QuizAnswer, error) {
+	query := `SELECT * FROM quiz_answers WHERE attempt_id = :attempt_id ORDER BY created_at`
+	params := map[string]interface{}{
+		"attempt_id": attemptID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	answers := []admin.QuizAnswer{}
+	for rows.Next() {
+		var answer admin.QuizAnswer
+		if err := rows.StructScan(&answer); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		answers = append(answers, answer)
+	}
+	return answers, nil
+}
+
+// ClaimLiveQuizEvents also picks up approved events whose start time has
+// passed before the status scheduler moves them to running.
+func (r *quizRepository) ClaimLiveQuizEvents(ctx context.Context, host string) ([]admin.Event, error) {
+	query := `WITH claimed AS (
+			INSERT INTO quiz_live_sessions (event_id, host)
+			SELECT id, :host FROM events
+			WHERE quiz_mode = :live AND question_set_id <> '' AND status IN (:approved, :running)
+				AND start_time <= NOW() AND end_time > NOW()
+			ON CONFLICT (event_id) DO NOTHING
+			RETURNING event_id
+		)
+		SELECT e.* FROM events e JOIN claimed c ON c.event_id = e.id`
+	params := map[string]interface{}{
+		"host":     host,
+		"live":     admin.QuizLive,
+		"approved": admin.EventApproved,
+		"running":  admin.EventRunning,
+	}
+	rows, err := r.db.NamedExecWithResponse(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrInsertDb, err)
+	}
+	defer rows.Close()
+	events := []admin.Event{}
+	for rows.Next() {
+		var event admin.Event
+		if err := rows.StructScan(&event); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (r *quizRepository) GetLiveQuizHost(ctx context.Context, eventID string) (string, error) {
+	query := `SELECT host FROM quiz_live_sessions WHERE event_id = :event_id`
+	params := map[string]interface{}{
+		"event_id": eventID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return "", errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var host string
+	if rows.Next() {
+		if err := rows.Scan(&host); err != nil {
+			return "", errors.Wrap(ErrSelectDb, err)
+		}
+	}
+	return host, nil
+}
+
+func (r *quizRepository) HeartbeatLiveQuiz(ctx context.Context, eventID string, host string) error {
+	query := `UPDATE quiz_live_sessions SET heartbeat_at = NOW()
+		WHERE event_id = :event_id AND host = :host AND finished_at IS NULL`
+	params := map[string]interface{}{
+		"event_id": eventID,
+		"host":     host,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrLiveSessionLost
+	}
+	return nil
+}
+
+func (r *quizRepository) FinishLiveQuiz(ctx context.Context, eventID string, host string, attempts []admin.QuizAttempt) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(ErrInsertDb, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE quiz_live_sessions SET finished_at = NOW()
+		WHERE event_id = $1 AND host = $2 AND finished_at IS NULL`, eventID, host)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrLiveSessionLost
+	}
+	for _, a := range attempts {
+		res, err := tx.ExecContext(ctx, `INSERT INTO quiz_attempts (event_id, user_id, set_id, position, total, score, correct, finished_at)
+			VALUES ($1, $2, $3, $4, $4, $5, $6, NOW()) ON CONFLICT (event_id, user_id) DO NOTHING`,
+			eventID, a.UserID, a.SetID, a.Total, a.Score, a.Correct)
+		if err != nil {
+			return errors.Wrap(ErrInsertDb, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		score := admin.ScoreEntry{EventID: eventID, UserID: a.UserID, Points: a.Score, Source: admin.ScoreLiveQuiz}
+		if err := recordScore(ctx, tx, score); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(ErrInsertDb, err)
+	}
+	return nil
+}
+
+func (r *quizRepository) RecoverLiveQuizzes(ctx context.Context, timeout time.Duration) (int64, error) {
+	query := `WITH stale AS (
+			SELECT s.event_id, e.end_time > NOW() AS running
+			FROM quiz_live_sessions s JOIN events e ON e.id = s.event_id
+			WHERE s.finished_at IS NULL AND s.heartbeat_at < NOW() - make_interval(secs => :timeout)
+			FOR UPDATE OF s
+		), released AS (
+			DELETE FROM quiz_live_sessions WHERE event_id IN (SELECT event_id FROM stale WHERE running)
+			RETURNING event_id
+		), finished AS (
+			UPDATE quiz_live_sessions SET finished_at = NOW()
+			WHERE event_id IN (SELECT event_id FROM stale WHERE NOT running)
+			RETURNING event_id
+		)
+		SELECT (SELECT COUNT(*) FROM released) + (SELECT COUNT(*) FROM finished)`
+	params := map[string]interface{}{
+		"timeout": timeout.Seconds(),
+	}
+	rows, err := r.db.NamedExecWithResponse(ctx, query, params)
+	if err != nil {
+		return 0, errors.Wrap(ErrUpdateDb, err)
+	}
+	defer rows.Close()
+	var n int64
+	if rows.Next() {
+		if err := rows.Scan(&n); err != nil {
+			return 0, errors.Wrap(ErrUpdateDb, err)
+		}
+	}
+	return n, nil
+}
+
+func (r *quizRepository) insertReturningID(ctx context.Context, query string, params map[string]interface{}) (string, error) {
+	rows, err := r.db.NamedExecWithResponse(ctx, query, params)
+	if err != nil {
+		return "", errors.Wrap(ErrInsertDb, err)
+	}
+	defer rows.Close()
+	var id string
+	if rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			return "", errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	return id, nil
+}
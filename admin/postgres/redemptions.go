@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/db"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+var _ admin.RedemptionRepository = (*redemptionRepository)(nil)
+
+type redemptionRepository struct {
+	db db.Database
+	l  log.Logger
+}
+
+func NewRedemptionRepository(db db.Database, l log.Logger) admin.RedemptionRepository {
+	return &redemptionRepository{
+		db: db,
+		l:  l,
+	}
+}
+
+// RedeemVoucher holds a row lock on the voucher from the check until the
+// status change commits, so of two cashiers scanning the same code only one
+// sees it active.
+func (r *redemptionRepository) RedeemVoucher(ctx context.Context, code string, redemption admin.Redemption, check func(v admin.Voucher, enterpriseID string) error) (admin.Voucher, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return admin.Voucher{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	var voucher admin.Voucher
+	err = tx.GetContext(ctx, &voucher, `SELECT * FROM vouchers WHERE code = $1 FOR UPDATE`, code)
+	if err == sql.ErrNoRows {
+		return admin.Voucher{}, admin.ErrVoucherNotFound
+	}
+	if err != nil {
+		return admin.Voucher{}, errors.Wrap(ErrSelectDb, err)
+	}
+	var enterpriseID string
+	err = tx.GetContext(ctx, &enterpriseID, `SELECT user_id FROM events WHERE id = $1`, voucher.EventID)
+	if err != nil && err != sql.ErrNoRows {
+		return voucher, errors.Wrap(ErrSelectDb, err)
+	}
+	if err := check(voucher, enterpriseID); err != nil {
+		return voucher, err
+	}
+
+	query := `UPDATE vouchers SET status = :status, redeemed_at = NOW(), redeemed_by = :redeemed_by, store_id = :store_id, updated_at = NOW()
+		WHERE id = :id RETURNING *`
+	params := map[string]interface{}{
+		"id":          voucher.ID,
+		"status":      admin.VoucherRedeemed,
+		"redeemed_by": redemption.CashierID,
+		"store_id":    redemption.StoreID,
+	}
+	rows, err := tx.NamedQuery(query, params)
+	if err != nil {
+		return voucher, errors.Wrap(ErrUpdateDb, err)
+	}
+	if rows.Next() {
+		if err := rows.StructScan(&voucher); err != nil {
+			rows.Close()
+			return voucher, errors.Wrap(ErrUpdateDb, err)
+		}
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return voucher, errors.Wrap(ErrUpdateDb, err)
+	}
+	return voucher, nil
+}
+
+func (r *redemptionRepository) LogRedemption(ctx context.Context, redemption admin.Redemption) error {
+	query := `INSERT INTO voucher_redemptions (voucher_id, code, event_id, enterprise_id, cashier_id, store_id, outcome)
+		VALUES (:voucher_id, :code, :event_id, :enterprise_id, :cashier_id, :store_id, :outcome)`
+	params := map[string]interface{}{
+		"voucher_id":    redemption.VoucherID,
+		"code":          redemption.Code,
+		"event_id":      redemption.EventID,
+		"enterprise_id": redemption.EnterpriseID,
+		"cashier_id":    redemption.CashierID,
+		"store_id":      redemption.StoreID,
+		"outcome":       redemption.Outcome,
+	}
+	if _, err := r.db.NamedExecContext(ctx, query, params); err != nil {
+		return errors.Wrap(ErrInsertDb, err)
+	}
+	return nil
+}
+
+func (r *redemptionRepository) GetRedemptionsByVoucherID(ctx context.Context, voucherID string) ([]admin.Redemption, error) {
+	query := `SELECT * FROM voucher_redemptions WHERE voucher_id = :voucher_id ORDER BY created_at`
+	params := map[string]interface{}{
+		"voucher_id": voucherID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var redemptions []admin.Redemption
+	for rows.Next() {
+		var redemption admin.Redemption
+		if err := rows.StructScan(&redemption); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		redemptions = append(redemptions, redemption)
+	}
+	return redemptions, nil
+}
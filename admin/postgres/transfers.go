@@ -0,0 +1,235 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/db"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+var _ admin.TransferRepository = (*transferRepository)(nil)
+
+type transferRepository struct {
+	db db.Database
+	l  log.Logger
+}
+
+func NewTransferRepository(db db.Database, l log.Logger) admin.TransferRepository {
+	return &transferRepository{
+		db: db,
+		l:  l,
+	}
+}
+
+func (r *transferRepository) CreateTransfer(ctx context.Context, transfer admin.Transfer) (admin.Transfer, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return admin.Transfer{}, errors.Wrap(ErrInsertDb, err)
+	}
+	defer tx.Rollback()
+
+	// A lapsed offer must not block a new one.
+	if _, err := tx.ExecContext(ctx, `UPDATE voucher_transfers SET status = $1, updated_at = NOW()
+		WHERE voucher_id = $2 AND status = $3 AND expires_at <= NOW()`,
+		admin.TransferExpired, transfer.VoucherID, admin.TransferPending); err != nil {
+		return admin.Transfer{}, errors.Wrap(ErrUpdateDb, err)
+	}
+
+	query := `INSERT INTO voucher_transfers (voucher_id, from_user_id, to_user_id, claim_token_hash, status, expires_at)
+		VALUES (:voucher_id, :from_user_id, :to_user_id, :claim_token_hash, :status, :expires_at) RETURNING *`
+	params := map[string]interface{}{
+		"voucher_id":       transfer.VoucherID,
+		"from_user_id":     transfer.FromUserID,
+		"to_user_id":       transfer.ToUserID,
+		"claim_token_hash": transfer.ClaimTokenHash,
+		"status":           admin.TransferPending,
+		"expires_at":       transfer.ExpiresAt,
+	}
+	rows, err := tx.NamedQuery(query, params)
+	if isUniqueViolation(err) {
+		return admin.Transfer{}, admin.ErrTransferPending
+	}
+	if err != nil {
+		return admin.Transfer{}, errors.Wrap(ErrInsertDb, err)
+	}
+	var created admin.Transfer
+	if rows.Next() {
+		if err := rows.StructScan(&created); err != nil {
+			rows.Close()
+			return admin.Transfer{}, errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return admin.Transfer{}, errors.Wrap(ErrInsertDb, err)
+	}
+	return created, nil
+}
+
+func (r *transferRepository) GetTransfer(ctx context.Context, id string) (admin.Transfer, error) {
+	query := `SELECT * FROM voucher_transfers WHERE id = :id`
+	params := map[string]interface{}{
+		"id": id,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.Transfer{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var transfer admin.Transfer
+	if rows.Next() {
+		if err := rows.StructScan(&transfer); err != nil {
+			return admin.Transfer{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return transfer, nil
+	} else {
+		return admin.Transfer{}, admin.ErrTransferNotFound
+	}
+}
+
+// AcceptTransfer locks the offer, then moves the voucher with an UPDATE that
+// only matches while the sender still owns it unredeemed and unexpired, so a
+// redemption or another transfer racing the accept makes it fail cleanly.
+func (r *transferRepository) AcceptTransfer(ctx context.Context, id string, tokenHash string, recipientID string, check func(eventID string) error) (admin.Transfer, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return admin.Transfer{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	var transfer admin.Transfer
+	if tokenHash != "" {
+		err = tx.GetContext(ctx, &transfer, `SELECT * FROM voucher_transfers WHERE claim_token_hash = $1 FOR UPDATE`, tokenHash)
+	} else {
+		err = tx.GetContext(ctx, &transfer, `SELECT * FROM voucher_transfers WHERE id = $1 AND to_user_id = $2 FOR UPDATE`, id, recipientID)
+	}
+	if err == sql.ErrNoRows {
+		return admin.Transfer{}, admin.ErrTransferNotFound
+	}
+	if err != nil {
+		return admin.Transfer{}, errors.Wrap(ErrSelectDb, err)
+	}
+	if transfer.Status != admin.TransferPending {
+		return admin.Transfer{}, admin.ErrTransferClosed
+	}
+	if transfer.FromUserID == recipientID {
+		return admin.Transfer{}, admin.ErrSelfTransfer
+	}
+
+	var expired bool
+	if err := tx.GetContext(ctx, &expired, `SELECT expires_at <= NOW() FROM voucher_transfers WHERE id = $1`, transfer.ID); err != nil {
+		return admin.Transfer{}, errors.Wrap(ErrSelectDb, err)
+	}
+	if expired {
+		if err := closeTransfer(ctx, tx, transfer.ID, admin.TransferExpired); err != nil {
+			return admin.Transfer{}, err
+		}
+		if err := tx.Commit(); err != nil {
+			return admin.Transfer{}, errors.Wrap(ErrUpdateDb, err)
+		}
+		return admin.Transfer{}, admin.ErrTransferClosed
+	}
+
+	var eventID string
+	err = tx.GetContext(ctx, &eventID, `SELECT event_id FROM vouchers WHERE id = $1`, transfer.VoucherID)
+	if err == sql.ErrNoRows {
+		return admin.Transfer{}, admin.ErrVoucherNotTransferable
+	}
+	if err != nil {
+		return admin.Transfer{}, errors.Wrap(ErrSelectDb, err)
+	}
+	if err := check(eventID); err != nil {
+		return admin.Transfer{}, err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE vouchers SET owner_id = $1, updated_at = NOW()
+		WHERE id = $2 AND owner_id = $3 AND status = $4 AND expired_time > NOW()`,
+		recipientID, transfer.VoucherID, transfer.FromUserID, admin.VoucherActive)
+	if err != nil {
+		return admin.Transfer{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if err := closeTransfer(ctx, tx, transfer.ID, admin.TransferCancelled); err != nil {
+			return admin.Transfer{}, err
+		}
+		if err := tx.Commit(); err != nil {
+			return admin.Transfer{}, errors.Wrap(ErrUpdateDb, err)
+		}
+		return admin.Transfer{}, admin.ErrVoucherNotTransferable
+	}
+
+	err = tx.GetContext(ctx, &transfer, `UPDATE voucher_transfers SET status = $1, to_user_id = $2, accepted_at = NOW(), updated_at = NOW()
+		WHERE id = $3 RETURNING *`, admin.TransferAccepted, recipientID, transfer.ID)
+	if err != nil {
+		return admin.Transfer{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return admin.Transfer{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	return transfer, nil
+}
+
+func (r *transferRepository) CloseTransfer(ctx context.Context, id string, status string) error {
+	query := `UPDATE voucher_transfers SET status = :status, updated_at = NOW() WHERE id = :id AND status = :pending`
+	params := map[string]interface{}{
+		"id":      id,
+		"status":  status,
+		"pending": admin.TransferPending,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrTransferClosed
+	}
+	return nil
+}
+
+func (r *transferRepository) GetPendingTransfersByUser(ctx context.Context, userID string) ([]admin.Transfer, error) {
+	query := `SELECT * FROM voucher_transfers
+		WHERE (from_user_id = :user_id OR to_user_id = :user_id) AND status = :pending AND expires_at > NOW()
+		ORDER BY created_at DESC`
+	params := map[string]interface{}{
+		"user_id": userID,
+		"pending": admin.TransferPending,
+	}
+	return r.listTransfers(ctx, query, params)
+}
+
+func (r *transferRepository) GetTransfersByVoucher(ctx context.Context, voucherID string) ([]admin.Transfer, error) {
+	query := `SELECT * FROM voucher_transfers WHERE voucher_id = :voucher_id ORDER BY created_at`
+	params := map[string]interface{}{
+		"voucher_id": voucherID,
+	}
+	return r.listTransfers(ctx, query, params)
+}
+
+func (r *transferRepository) listTransfers(ctx context.Context, query string, params map[string]interface{}) ([]admin.Transfer, error) {
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	transfers := []admin.Transfer{}
+	for rows.Next() {
+		var transfer admin.Transfer
+		if err := rows.StructScan(&transfer); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, nil
+}
+
+func closeTransfer(ctx context.Context, tx *sqlx.Tx, id string, status string) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE voucher_transfers SET status = $1, updated_at = NOW() WHERE id = $2`, status, id); err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/db"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+var _ admin.TurnRepository = (*turnRepository)(nil)
+
+type turnRepository struct {
+	db db.Database
+	l  log.Logger
+}
+
+func NewTurnRepository(db db.Database, l log.Logger) admin.TurnRepository {
+	return &turnRepository{
+		db: db,
+		l:  l,
+	}
+}
+
+func (r *turnRepository) GetBalance(ctx context.Context, eventID string, userID string) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, errors.Wrap(ErrSelectDb, err)
+	}
+	defer tx.Rollback()
+
+	balance, err := lockTurns(ctx, tx, eventID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(ErrInsertDb, err)
+	}
+	return balance, nil
+}
+
+func (r *turnRepository) GetEntries(ctx context.Context, eventID string, userID string) ([]admin.TurnEntry, error) {
+	query := `SELECT * FROM play_turns WHERE event_id = :event_id AND user_id = :user_id ORDER BY seq DESC`
+	params := map[string]interface{}{
+		"event_id": eventID,
+		"user_id":  userID,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	entries := []admin.TurnEntry{}
+	for rows.Next() {
+		var entry admin.TurnEntry
+		if err := rows.StructScan(&entry); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (r *turnRepository) Credit(ctx context.Context, eventID string, userID string, amount int, reason string, ref string) (admin.TurnEntry, error) {
+	return r.appendInTx(ctx, eventID, userID, amount, reason, ref)
+}
+
+func (r *turnRepository) Spend(ctx context.Context, eventID string, userID string, reason string, ref string) (admin.TurnEntry, error) {
+	return r.appendInTx(ctx, eventID, userID, -1, reason, ref)
+}
+
+func (r *turnRepository) appendInTx(ctx context.Context, eventID string, userID string, amount int, reason string, ref string) (admin.TurnEntry, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return admin.TurnEntry{}, errors.Wrap(ErrInsertDb, err)
+	}
+	defer tx.Rollback()
+
+	balance, err := lockTurns(ctx, tx, eventID, userID)
+	if err != nil {
+		return admin.TurnEntry{}, err
+	}
+	entry, err := appendTurn(ctx, tx, eventID, userID, balance, amount, reason, ref)
+	if err != nil {
+		return admin.TurnEntry{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return admin.TurnEntry{}, errors.Wrap(ErrInsertDb, err)
+	}
+	return entry, nil
+}
+
+// SaveInviteCode keeps the code already stored for the player, so a player
+// has one code per event however often they ask for it.
+func (r *turnRepository) SaveInviteCode(ctx context.Context, eventID string, userID string, code string) (string, error) {
+	query := `INSERT INTO invite_codes (event_id, user_id, code) VALUES (:event_id, :user_id, :code)
+		ON CONFLICT (event_id, user_id) DO UPDATE SET code = invite_codes.code
+		RETURNING code`
+	params := map[string]interface{}{
+		"event_id": eventID,
+		"user_id":  userID,
+		"code":     code,
+	}
+	rows, err := r.db.NamedExecWithResponse(ctx, query, params)
+	if err != nil {
+		return "", errors.Wrap(ErrInsertDb, err)
+	}
+	defer rows.Close()
+	var saved string
+	if rows.Next() {
+		if err := rows.Scan(&saved); err != nil {
+			return "", errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	return saved, nil
+}
+
+func (r *turnRepository) GetInviter(ctx context.Context, eventID string, code string) (string, error) {
+	query := `SELECT user_id FROM invite_codes WHERE event_id = :event_id AND code = :code`
+	params := map[string]interface{}{
+		"event_id": eventID,
+		"code":     code,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return "", errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return "", admin.ErrInviteCodeNotFound
+	}
+	var userID string
+	if err := rows.Scan(&userID); err != nil {
+		return "", errors.Wrap(ErrSelectDb, err)
+	}
+	return userID, nil
+}
+
+func (r *turnRepository) CreateTurnRequest(ctx context.Context, request admin.TurnRequest) (admin.TurnRequest, error) {
+	query := `INSERT INTO turn_requests (event_id, from_user_id, to_user_id, status)
+		VALUES (:event_id, :from_user_id, :to_user_id, :status) RETURNING *`
+	params := map[string]interface{}{
+		"event_id":     request.EventID,
+		"from_user_id": request.FromUserID,
+		"to_user_id":   request.ToUserID,
+		"status":       admin.TurnRequestPending,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if isUniqueViolation(err) {
+		return admin.TurnRequest{}, admin.ErrTurnRequestPending
+	}
+	if err != nil {
+		return admin.TurnRequest{}, errors.Wrap(ErrInsertDb, err)
+	}
+	defer rows.Close()
+	var created admin.TurnRequest
+	if rows.Next() {
+		if err := rows.StructScan(&created); err != nil {
+			return admin.TurnRequest{}, errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	return created, nil
+}
+
+func (r *turnRepository) GetTurnRequest(ctx context.Context, id string) (admin.TurnRequest, error) {
+	query := `SELECT * FROM turn_requests WHERE id = :id`
+	params := map[string]interface{}{
+		"id": id,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.TurnRequest{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var request admin.TurnRequest
+	if rows.Next() {
+		if err := rows.StructScan(&request); err != nil {
+			return admin.TurnRequest{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return request, nil
+	} else {
+		return admin.TurnRequest{}, admin.ErrTurnRequestNotFound
+	}
+}
+
+func (r *turnRepository) GetPendingTurnRequestsByUser(ctx context.Context, eventID string, userID string) ([]admin.TurnRequest, error) {
+	query := `SELECT * FROM turn_requests
+		WHERE event_id = :event_id AND (from_user_id = :user_id OR to_user_id = :user_id) AND status = :pending
+		ORDER BY created_at DESC`
+	params := map[string]interface{}{
+		"event_id": eventID,
+		"user_id":  userID,
+		"pending":  admin.TurnRequestPending,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	requests := []admin.TurnRequest{}
+	for rows.Next() {
+		var request admin.TurnRequest
+		if err := rows.StructScan(&request); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+// AcceptTurnRequest locks both balances in a fixed order so two friends
+// accepting each other's requests at once cannot deadlock.
+func (r *turnRepository) AcceptTurnRequest(ctx context.Context, id string) (admin.TurnRequest, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return admin.TurnRequest{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	defer tx.Rollback()
+
+	var request admin.TurnRequest
+	err = tx.GetContext(ctx, &request, `SELECT * FROM turn_requests WHERE id = $1 FOR UPDATE`, id)
+	if err == sql.ErrNoRows {
+		return admin.TurnRequest{}, admin.ErrTurnRequestNotFound
+	}
+	if err != nil {
+		return admin.TurnRequest{}, errors.Wrap(ErrSelectDb, err)
+	}
+	if request.Status != admin.TurnRequestPending {
+		return admin.TurnRequest{}, admin.ErrTurnRequestClosed
+	}
+
+	users := []string{request.FromUserID, request.ToUserID}
+	sort.Strings(users)
+	balances := map[string]int{}
+	for _, userID := range users {
+		balance, err := lockTurns(ctx, tx, request.EventID, userID)
+		if err != nil {
+			return admin.TurnRequest{}, err
+		}
+		balances[userID] = balance
+	}
+	if _, err := appendTurn(ctx, tx, request.EventID, request.ToUserID, balances[request.ToUserID], -1, admin.TurnGiftOut, request.ID); err != nil {
+		return admin.TurnRequest{}, err
+	}
+	if _, err := appendTurn(ctx, tx, request.EventID, request.FromUserID, balances[request.FromUserID], 1, admin.TurnGiftIn, request.ID); err != nil {
+		return admin.TurnRequest{}, err
+	}
+
+	err = tx.GetContext(ctx, &request, `UPDATE turn_requests SET status = $1, updated_at = NOW() WHERE id = $2 RETURNING *`, admin.TurnRequestAccepted, request.ID)
+	if err != nil {
+		return admin.TurnRequest{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return admin.TurnRequest{}, errors.Wrap(ErrUpdateDb, err)
+	}
+	return request, nil
+}
+
+func (r *turnRepository) CloseTurnRequest(ctx context.Context, id string, status string) error {
+	query := `UPDATE turn_requests SET status = :status, updated_at = NOW() WHERE id = :id AND status = :pending`
+	params := map[string]interface{}{
+		"id":      id,
+		"status":  status,
+		"pending": admin.TurnRequestPending,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return errors.Wrap(ErrUpdateDb, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrTurnRequestClosed
+	}
+	return nil
+}
+
+// lockTurns takes the transaction-scoped lock on the player's balance in the
+// event, tops it up to the day's free turns if the event is running and the
+// player has not had them yet, and returns the balance. Every write to the ledger
+// happens under this lock, so the balance an entry is built on is the latest.
+func lockTurns(ctx context.Context, tx *sqlx.Tx, eventID string, userID string) (int, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))`, eventID, userID); err != nil {
+		return 0, errors.Wrap(ErrSelectDb, err)
+	}
+	var balance int
+	err := tx.GetContext(ctx, &balance, `SELECT balance FROM play_turns WHERE event_id = $1 AND user_id = $2 ORDER BY seq DESC LIMIT 1`, eventID, userID)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, errors.Wrap(ErrSelectDb, err)
+	}
+
+	var granted int
+	err = tx.GetContext(ctx, &granted, `INSERT INTO play_turns (event_id, game_id, user_id, amount, balance, reason)
+		SELECT id, game_id, $2, GREATEST(play_turns - $3::integer, 0), GREATEST(play_turns, $3::integer), $4
+		FROM events WHERE id = $1 AND status = $5 AND play_turns > 0
+		ON CONFLICT (event_id, user_id, day) WHERE reason = 'daily' DO NOTHING
+		RETURNING balance`,
+		eventID, userID, balance, admin.TurnDaily, admin.EventRunning)
+	if err == sql.ErrNoRows {
+		return balance, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(ErrInsertDb, err)
+	}
+	return granted, nil
+}
+
+// appendTurn writes an entry on top of balance, which must have been read
+// by lockTurns in tx.
+func appendTurn(ctx context.Context, tx *sqlx.Tx, eventID string, userID string, balance int, amount int, reason string, ref string) (admin.TurnEntry, error) {
+	if balance+amount < 0 {
+		return admin.TurnEntry{}, admin.ErrNoTurnsLeft
+	}
+	var entry admin.TurnEntry
+	err := tx.GetContext(ctx, &entry, `INSERT INTO play_turns (event_id, game_id, user_id, amount, balance, reason, ref)
+		SELECT id, game_id, $2, $3::integer, $4::integer, $5, $6 FROM events WHERE id = $1
+		RETURNING *`,
+		eventID, userID, amount, balance+amount, reason, ref)
+	if err == sql.ErrNoRows {
+		return admin.TurnEntry{}, admin.ErrEventNotFound
+	}
+	if isUniqueViolation(err) {
+		return admin.TurnEntry{}, admin.ErrTurnsAlreadyCredited
+	}
+	if err != nil {
+		return admin.TurnEntry{}, errors.Wrap(ErrInsertDb, err)
+	}
+	return entry, nil
+}
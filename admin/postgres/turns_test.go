@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/db"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+func TestDailyTurnsTopUp(t *testing.T) {
+	conn := connectTestDB(t)
+	ctx := context.Background()
+	logger, err := log.New(io.Discard, "error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewTurnRepository(db.NewReadWrite(conn, conn), logger)
+
+	eventID := createTestEvent(t, conn, 10)
+	if _, err := conn.Exec(`UPDATE events SET status = $1, play_turns = 3 WHERE id = $2`, admin.EventRunning, eventID); err != nil {
+		t.Fatal(err)
+	}
+	// Balances left over from yesterday. The ledger is append-only, so these
+	// rows outlive the test in the disposable database.
+	cases := []struct {
+		user      string
+		yesterday int
+		want      int
+	}{
+		{"turns-empty", 0, 3},
+		{"turns-low", 1, 3},
+		{"turns-high", 5, 5},
+	}
+	for _, c := range cases {
+		if _, err := conn.Exec(`INSERT INTO play_turns (event_id, game_id, user_id, amount, balance, reason, day)
+			SELECT id, game_id, $2, $3::integer, $3::integer, $4, CURRENT_DATE - 1 FROM events WHERE id = $1`,
+			eventID, c.user, c.yesterday, admin.TurnDaily); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			balance, err := repo.GetBalance(ctx, eventID, c.user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if balance != c.want {
+				t.Errorf("%s: balance = %d, want %d", c.user, balance, c.want)
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"time"
 
 	"github.com/resrrdttrt/VOU/admin"
 	"github.com/resrrdttrt/VOU/pkg/db"
@@ -63,22 +64,76 @@ func (r *usersRepository) GetUserById(ctx context.Context, id string) (admin.Use
 	}
 }
 
-func (r *usersRepository) CreateUser(ctx context.Context, user admin.User) error {
-	query := `INSERT INTO users (name, username, password, email, phone, role, status) VALUES (:name, :username, :password, :email, :phone, :role, :status) RETURNING id`
+func (r *usersRepository) GetUserByUsername(ctx context.Context, username string) (admin.User, error) {
+	query := `SELECT * FROM users WHERE username = :username`
 	params := map[string]interface{}{
-		"name":     user.Name,
-		"username": user.Username,
-		"password": user.Password,
-		"email":    user.Email,
-		"phone":    user.Phone,
-		"role":     user.Role,
-		"status":   user.Status,
+		"username": username,
 	}
-	_, err := r.db.NamedExecContext(ctx, query, params)
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
 	if err != nil {
-		return errors.Wrap(ErrInsertDb, err)
+		return admin.User{}, errors.Wrap(ErrSelectDb, err)
 	}
-	return nil
+	defer rows.Close()
+	var user admin.User
+	if rows.Next() {
+		if err := rows.StructScan(&user); err != nil {
+			return admin.User{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return user, nil
+	} else {
+		return admin.User{}, errors.Wrap(ErrNoData, err)
+	}
+}
+
+func (r *usersRepository) GetUserByContact(ctx context.Context, contact string) (admin.User, error) {
+	query := `SELECT * FROM users WHERE username = :contact OR email = :contact OR phone = :contact
+		ORDER BY (username = :contact) DESC, created_at LIMIT 1`
+	params := map[string]interface{}{
+		"contact": contact,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.User{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var user admin.User
+	if rows.Next() {
+		if err := rows.StructScan(&user); err != nil {
+			return admin.User{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return user, nil
+	} else {
+		return admin.User{}, admin.ErrUserNotFound
+	}
+}
+
+func (r *usersRepository) CreateUser(ctx context.Context, user admin.User) (string, error) {
+	query := `INSERT INTO users (name, username, password, email, phone, role, status, enterprise_id) VALUES (:name, :username, :password, :email, :phone, :role, :status, :enterprise_id) RETURNING id`
+	params := map[string]interface{}{
+		"name":          user.Name,
+		"username":      user.Username,
+		"password":      user.Password,
+		"email":         user.Email,
+		"phone":         user.Phone,
+		"role":          user.Role,
+		"status":        user.Status,
+		"enterprise_id": user.EnterpriseID,
+	}
+	rows, err := r.db.NamedExecWithResponse(ctx, query, params)
+	if isUniqueViolation(err) {
+		return "", admin.ErrUserExists
+	}
+	if err != nil {
+		return "", errors.Wrap(ErrInsertDb, err)
+	}
+	defer rows.Close()
+	var id string
+	if rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			return "", errors.Wrap(ErrInsertDb, err)
+		}
+	}
+	return id, nil
 }
 
 func (r *usersRepository) UpdateUser(ctx context.Context, user admin.User) error {
@@ -122,8 +177,16 @@ func (r *usersRepository) UpdateUser(ctx context.Context, user admin.User) error
 		params["status"] = user.Status
 	}
 
+	if user.EnterpriseID != "" {
+		query += `enterprise_id = :enterprise_id, `
+		params["enterprise_id"] = user.EnterpriseID
+	}
+
 	query = query[:len(query)-2] + ` WHERE id = :id RETURNING *`
 	_, err := r.db.NamedExecContext(ctx, query, params)
+	if isUniqueViolation(err) {
+		return admin.ErrUserExists
+	}
 	if err != nil {
 		return errors.Wrap(ErrUpdateDb, err)
 	}
@@ -141,3 +204,28 @@ func (r *usersRepository) DeleteUser(ctx context.Context, id string) error {
 	}
 	return nil
 }
+
+func (r *usersRepository) DeletePendingUsers(ctx context.Context, ttl time.Duration) (int64, error) {
+	query := `WITH purged AS (
+			DELETE FROM users WHERE status = :status AND created_at < NOW() - make_interval(secs => :ttl) RETURNING id
+		), codes AS (
+			DELETE FROM one_time_codes WHERE user_id IN (SELECT id::text FROM purged)
+		)
+		SELECT COUNT(*) FROM purged`
+	params := map[string]interface{}{
+		"status": admin.UserPendingVerification,
+		"ttl":    ttl.Seconds(),
+	}
+	rows, err := r.db.NamedExecWithResponse(ctx, query, params)
+	if err != nil {
+		return 0, errors.Wrap(ErrDeleteDb, err)
+	}
+	defer rows.Close()
+	var n int64
+	if rows.Next() {
+		if err := rows.Scan(&n); err != nil {
+			return 0, errors.Wrap(ErrDeleteDb, err)
+		}
+	}
+	return n, nil
+}
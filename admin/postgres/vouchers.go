@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"time"
 
 	"github.com/resrrdttrt/VOU/admin"
 	"github.com/resrrdttrt/VOU/pkg/db"
@@ -24,7 +25,7 @@ func NewVoucherRepository(db db.Database, l log.Logger) admin.VoucherRepository
 }
 
 func (r *voucherRepository) CreateVoucher(ctx context.Context, voucher admin.Voucher) error {
-	query := `INSERT INTO vouchers (code, qrcode, images, value, description, expired_time, status, event_id) VALUES (:code, :qrcode, :images, :value, :description, :expired_time, :status, :event_id) RETURNING id`
+	query := `INSERT INTO vouchers (code, qrcode, images, value, description, expired_time, status, event_id, rule) VALUES (:code, :qrcode, :images, :value, :description, :expired_time, :status, :event_id, :rule) RETURNING id`
 	params := map[string]interface{}{
 		"code":         voucher.Code,
 		"qrcode":       voucher.Qrcode,
@@ -34,11 +35,27 @@ func (r *voucherRepository) CreateVoucher(ctx context.Context, voucher admin.Vou
 		"expired_time": voucher.ExpiredTime,
 		"status":       voucher.Status,
 		"event_id":     voucher.EventID,
+		"rule":         voucher.Rule,
 	}
-	_, err := r.db.NamedExecContext(ctx, query, params)
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return errors.Wrap(ErrInsertDb, err)
 	}
+	defer tx.Rollback()
+
+	if err := checkEventCapacity(ctx, tx, voucher.EventID, "", 1); err != nil {
+		return err
+	}
+	_, err = tx.NamedExecContext(ctx, query, params)
+	if isUniqueViolation(err) {
+		return admin.ErrVoucherCodeTaken
+	}
+	if err != nil {
+		return errors.Wrap(ErrInsertDb, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(ErrInsertDb, err)
+	}
 	return nil
 }
 
@@ -107,6 +124,72 @@ func (r *voucherRepository) GetVoucherByID(ctx context.Context, id string, event
 	}
 }
 
+func (r *voucherRepository) GetVoucher(ctx context.Context, id string) (admin.Voucher, error) {
+	query := `SELECT * FROM vouchers WHERE id = :id`
+	params := map[string]interface{}{
+		"id": id,
+	}
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return admin.Voucher{}, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	var voucher admin.Voucher
+	if rows.Next() {
+		if err := rows.StructScan(&voucher); err != nil {
+			return admin.Voucher{}, errors.Wrap(ErrSelectDb, err)
+		}
+		return voucher, nil
+	} else {
+		return admin.Voucher{}, admin.ErrVoucherNotFound
+	}
+}
+
+func (r *voucherRepository) GetOwnedVouchers(ctx context.Context, q admin.OwnedVoucherQuery) ([]admin.Voucher, error) {
+	query := `SELECT * FROM vouchers WHERE owner_id = :owner_id`
+	params := map[string]interface{}{
+		"owner_id": q.OwnerID,
+		"limit":    q.Limit,
+	}
+	if q.Status != "" {
+		query += ` AND status = :status`
+		params["status"] = q.Status
+	}
+	if q.EventID != "" {
+		query += ` AND event_id = :event_id`
+		params["event_id"] = q.EventID
+	}
+	if !q.ExpiresAfter.IsZero() {
+		query += ` AND expired_time >= :expires_after`
+		params["expires_after"] = q.ExpiresAfter
+	}
+	if !q.ExpiresBefore.IsZero() {
+		query += ` AND expired_time < :expires_before`
+		params["expires_before"] = q.ExpiresBefore
+	}
+	if q.AfterID != "" {
+		query += ` AND (expired_time, id) > (:after_expiry, :after_id)`
+		params["after_expiry"] = q.AfterExpiry
+		params["after_id"] = q.AfterID
+	}
+	query += ` ORDER BY expired_time, id LIMIT :limit`
+
+	rows, err := r.db.NamedQueryContext(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrSelectDb, err)
+	}
+	defer rows.Close()
+	vouchers := []admin.Voucher{}
+	for rows.Next() {
+		var voucher admin.Voucher
+		if err := rows.StructScan(&voucher); err != nil {
+			return nil, errors.Wrap(ErrSelectDb, err)
+		}
+		vouchers = append(vouchers, voucher)
+	}
+	return vouchers, nil
+}
+
 func (r *voucherRepository) UpdateVoucher(ctx context.Context, voucher admin.Voucher) error {
 	query := `UPDATE vouchers SET `
 	params := map[string]interface{}{
@@ -149,11 +232,25 @@ func (r *voucherRepository) UpdateVoucher(ctx context.Context, voucher admin.Vou
 		params["status"] = voucher.Status
 	}
 
-	query = query[:len(query)-2] + ` WHERE id = :id and event_id = :event_id RETURNING *`
-	_, err := r.db.NamedExecContext(ctx, query, params)
+	if voucher.Rule != nil {
+		query += `rule = :rule, `
+		params["rule"] = voucher.Rule
+	}
+
+	// Redeemed, expired and owned vouchers are out in players' hands; their
+	// code and expiry must not change under them.
+	query = query[:len(query)-2] + ` WHERE id = :id and event_id = :event_id
+		AND status <> 'redeemed' AND status <> 'expired' AND owner_id = ''`
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if isUniqueViolation(err) {
+		return admin.ErrVoucherCodeTaken
+	}
 	if err != nil {
 		return errors.Wrap(ErrUpdateDb, err)
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return admin.ErrVoucherNotEditable
+	}
 	return nil
 }
 
@@ -169,3 +266,59 @@ func (r *voucherRepository) DeleteVoucher(ctx context.Context, id string, eventI
 	}
 	return nil
 }
+
+// ExpireVouchers skips rows locked by a running redemption; they are picked
+// up by a later sweep if still unredeemed.
+func (r *voucherRepository) ExpireVouchers(ctx context.Context, limit int) (int64, error) {
+	query := `UPDATE vouchers SET status = :expired, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM vouchers WHERE status = :active AND expired_time <= NOW()
+			LIMIT :limit FOR UPDATE SKIP LOCKED
+		)`
+	params := map[string]interface{}{
+		"expired": admin.VoucherExpired,
+		"active":  admin.VoucherActive,
+		"limit":   limit,
+	}
+	res, err := r.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return 0, errors.Wrap(ErrUpdateDb, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(ErrUpdateDb, err)
+	}
+	return n, nil
+}
+
+func (r *voucherRepository) QueueExpiryReminders(ctx context.Context, kind string, from time.Time, to time.Time, limit int) ([]admin.ExpiryReminder, error) {
+	query := `INSERT INTO voucher_reminders (voucher_id, owner_id, kind, expired_time)
+		SELECT v.id, v.owner_id, :kind, v.expired_time FROM vouchers v
+		WHERE v.status = :active AND v.owner_id <> '' AND v.expired_time > :from AND v.expired_time <= :to
+			AND NOT EXISTS (SELECT 1 FROM voucher_reminders m WHERE m.voucher_id = v.id AND m.kind = :kind)
+		ORDER BY v.expired_time
+		LIMIT :limit
+		ON CONFLICT (voucher_id, kind) DO NOTHING
+		RETURNING *`
+	params := map[string]interface{}{
+		"kind":   kind,
+		"active": admin.VoucherActive,
+		"from":   from,
+		"to":     to,
+		"limit":  limit,
+	}
+	rows, err := r.db.NamedExecWithResponse(ctx, query, params)
+	if err != nil {
+		return nil, errors.Wrap(ErrInsertDb, err)
+	}
+	defer rows.Close()
+	var reminders []admin.ExpiryReminder
+	for rows.Next() {
+		var reminder admin.ExpiryReminder
+		if err := rows.StructScan(&reminder); err != nil {
+			return nil, errors.Wrap(ErrInsertDb, err)
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, nil
+}
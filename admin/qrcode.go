@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+// QR image formats.
+const (
+	QRCodePNG = "png"
+	QRCodeSVG = "svg"
+)
+
+var (
+	// ErrInvalidVoucherSignature indicates a scanned payload that was not
+	// signed by this service or was modified after signing.
+	ErrInvalidVoucherSignature = errors.Wrap(errors.ErrForbidden, errors.New("voucher payload signature is invalid"))
+
+	// ErrMalformedVoucherPayload indicates a scanned payload that cannot be
+	// parsed.
+	ErrMalformedVoucherPayload = errors.Wrap(errors.ErrMalformedEntity, errors.New("voucher payload is malformed"))
+)
+
+// VoucherClaims is what a voucher QR code carries. The signature over the
+// claims lets a scanner trust them without asking the service. OwnerID binds
+// the code of an owned voucher to its owner, so it stops working once the
+// voucher is transferred.
+type VoucherClaims struct {
+	VoucherID string `json:"vid"`
+	Code      string `json:"code"`
+	EventID   string `json:"eid"`
+	OwnerID   string `json:"oid,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// NewVoucherClaims returns the claims of v.
+func NewVoucherClaims(v Voucher) VoucherClaims {
+	return VoucherClaims{
+		VoucherID: v.ID,
+		Code:      v.Code,
+		EventID:   v.EventID,
+		OwnerID:   v.OwnerID,
+		ExpiresAt: v.ExpiredTime.Unix(),
+	}
+}
+
+// Expired reports whether the voucher had expired at t.
+func (c VoucherClaims) Expired(t time.Time) bool {
+	return t.Unix() > c.ExpiresAt
+}
+
+// VoucherSigner signs voucher claims into a compact payload that fits in a
+// QR code and verifies such payloads.
+type VoucherSigner interface {
+	Sign(claims VoucherClaims) (string, error)
+	Verify(payload string) (VoucherClaims, error)
+	// PublicKey returns the key verifiers need, base64 encoded.
+	PublicKey() string
+}
+
+// QRRenderer renders content as a square QR code image of size pixels.
+type QRRenderer interface {
+	PNG(content string, size int) ([]byte, error)
+	SVG(content string, size int) ([]byte, error)
+}
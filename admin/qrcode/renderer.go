@@ -0,0 +1,51 @@
+// Package qrcode provides a QR code renderer implementation.
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/resrrdttrt/VOU/admin"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+var _ admin.QRRenderer = (*renderer)(nil)
+
+type renderer struct {
+	level qrcode.RecoveryLevel
+}
+
+// New instantiates a renderer. Medium error correction keeps codes readable
+// on scuffed phone screens without making them too dense.
+func New() admin.QRRenderer {
+	return &renderer{
+		level: qrcode.Medium,
+	}
+}
+
+func (r *renderer) PNG(content string, size int) ([]byte, error) {
+	return qrcode.Encode(content, r.level, size)
+}
+
+// SVG draws one rect per dark module, including the quiet zone, scaled to
+// size pixels through the viewBox.
+func (r *renderer) SVG(content string, size int) ([]byte, error) {
+	qr, err := qrcode.New(content, r.level)
+	if err != nil {
+		return nil, err
+	}
+	bitmap := qr.Bitmap()
+	n := len(bitmap)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, n, n)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/>`, n, n)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="1" height="1"/>`, x, y)
+			}
+		}
+	}
+	buf.WriteString(`</svg>`)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,308 @@
+package admin
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+// GameTypeQuiz is the game type whose events play a question set.
+const GameTypeQuiz = "quiz"
+
+// Question difficulties.
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// difficultyPoints is what a correct answer is worth before the speed bonus.
+var difficultyPoints = map[string]int{
+	DifficultyEasy:   100,
+	DifficultyMedium: 200,
+	DifficultyHard:   300,
+}
+
+// Bounds of a question.
+const (
+	MinChoices   = 2
+	MaxChoices   = 8
+	MinTimeLimit = 5
+	MaxTimeLimit = 300
+)
+
+// IsQuizGame reports whether a game of type t plays a question set.
+func IsQuizGame(t string) bool {
+	return strings.EqualFold(t, GameTypeQuiz)
+}
+
+// QuestionSet is a bank of questions owned by an enterprise. Events of quiz
+// games play one set, in question position order.
+type QuestionSet struct {
+	ID           string     `db:"id" json:"id,omitempty"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at,omitempty"`
+	EnterpriseID string     `db:"enterprise_id" json:"enterprise_id,omitempty"`
+	Name         string     `db:"name" json:"name"`
+	Description  string     `db:"description" json:"description"`
+	Questions    []Question `db:"-" json:"questions,omitempty"`
+}
+
+// Choice is one of the answers a player can pick.
+type Choice struct {
+	ID    int    `json:"id"`
+	Text  string `json:"text"`
+	Media string `json:"media,omitempty"`
+}
+
+// Choices is stored as JSONB.
+type Choices []Choice
+
+func (c Choices) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *Choices) Scan(src interface{}) error {
+	return scanJSON(src, c)
+}
+
+// ChoiceIDs is a set of picked or correct choices, stored as JSONB.
+type ChoiceIDs []int
+
+func (c ChoiceIDs) Value() (driver.Value, error) {
+	if c == nil {
+		c = ChoiceIDs{}
+	}
+	return json.Marshal(c)
+}
+
+func (c *ChoiceIDs) Scan(src interface{}) error {
+	return scanJSON(src, c)
+}
+
+// Equal reports whether c and o hold the same choices, in any order.
+func (c ChoiceIDs) Equal(o ChoiceIDs) bool {
+	if len(c) != len(o) {
+		return false
+	}
+	a := append([]int(nil), c...)
+	b := append([]int(nil), o...)
+	sort.Ints(a)
+	sort.Ints(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func scanJSON(src interface{}, dst interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", src, dst)
+	}
+}
+
+// Question is a multiple choice question. Correct holds the IDs of every
+// right choice; a player must pick exactly those. TimeLimit is in seconds.
+type Question struct {
+	ID         string    `db:"id" json:"id,omitempty"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt  time.Time `db:"updated_at" json:"updated_at,omitempty"`
+	SetID      string    `db:"set_id" json:"set_id,omitempty"`
+	Position   int       `db:"position" json:"position"`
+	Text       string    `db:"text" json:"text"`
+	Media      string    `db:"media" json:"media"`
+	Choices    Choices   `db:"choices" json:"choices"`
+	Correct    ChoiceIDs `db:"correct" json:"correct"`
+	TimeLimit  int       `db:"time_limit" json:"time_limit"`
+	Difficulty string    `db:"difficulty" json:"difficulty"`
+}
+
+func errInvalidQuestion(reason string) error {
+	return errors.Wrap(errors.ErrMalformedEntity, errors.New("invalid question: "+reason))
+}
+
+// Validate checks that the question can be played and graded.
+func (q Question) Validate() error {
+	if strings.TrimSpace(q.Text) == "" {
+		return errInvalidQuestion("text is required")
+	}
+	if len(q.Choices) < MinChoices || len(q.Choices) > MaxChoices {
+		return errInvalidQuestion(fmt.Sprintf("a question needs %d to %d choices", MinChoices, MaxChoices))
+	}
+	ids := map[int]bool{}
+	for _, c := range q.Choices {
+		if ids[c.ID] {
+			return errInvalidQuestion("choice ids must be unique")
+		}
+		if strings.TrimSpace(c.Text) == "" && c.Media == "" {
+			return errInvalidQuestion("every choice needs text or media")
+		}
+		ids[c.ID] = true
+	}
+	if len(q.Correct) == 0 {
+		return errInvalidQuestion("at least one choice must be correct")
+	}
+	seen := map[int]bool{}
+	for _, id := range q.Correct {
+		if !ids[id] || seen[id] {
+			return errInvalidQuestion("correct must list distinct choice ids of the question")
+		}
+		seen[id] = true
+	}
+	if q.TimeLimit < MinTimeLimit || q.TimeLimit > MaxTimeLimit {
+		return errInvalidQuestion(fmt.Sprintf("time_limit must be between %d and %d seconds", MinTimeLimit, MaxTimeLimit))
+	}
+	if _, ok := difficultyPoints[q.Difficulty]; !ok {
+		return errInvalidQuestion("difficulty must be easy, medium or hard")
+	}
+	return nil
+}
+
+// PlayerQuestion is what a player sees of a question: everything but the
+// correct choices.
+type PlayerQuestion struct {
+	ID         string  `json:"id"`
+	Position   int     `json:"position"`
+	Text       string  `json:"text"`
+	Media      string  `json:"media,omitempty"`
+	Choices    Choices `json:"choices"`
+	Multiple   bool    `json:"multiple"`
+	TimeLimit  int     `json:"time_limit"`
+	Difficulty string  `json:"difficulty"`
+}
+
+func (q Question) ForPlayer() PlayerQuestion {
+	return PlayerQuestion{
+		ID:         q.ID,
+		Position:   q.Position,
+		Text:       q.Text,
+		Media:      q.Media,
+		Choices:    q.Choices,
+		Multiple:   len(q.Correct) > 1,
+		TimeLimit:  q.TimeLimit,
+		Difficulty: q.Difficulty,
+	}
+}
+
+// Grade scores choices picked elapsed after q was shown. A correct answer in
+// time earns the points of its difficulty plus up to half again for speed;
+// anything else earns nothing.
+func (q Question) Grade(choices ChoiceIDs, elapsed time.Duration) QuizAnswer {
+	limit := time.Duration(q.TimeLimit) * time.Second
+	a := QuizAnswer{
+		QuestionID: q.ID,
+		Choices:    choices,
+		ElapsedMS:  elapsed.Milliseconds(),
+		TimedOut:   elapsed > limit,
+	}
+	if a.TimedOut || !choices.Equal(q.Correct) {
+		return a
+	}
+	base := difficultyPoints[q.Difficulty]
+	a.Correct = true
+	a.Points = base + int(int64(base)*int64(limit-elapsed)/int64(2*limit))
+	return a
+}
+
+// QuizAttempt is one player's run through the question set of an event.
+// Position is the question being shown, from ServedAt, until FinishedAt.
+type QuizAttempt struct {
+	ID         string     `db:"id" json:"id"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updated_at"`
+	EventID    string     `db:"event_id" json:"event_id"`
+	UserID     string     `db:"user_id" json:"user_id"`
+	SetID      string     `db:"set_id" json:"set_id"`
+	Position   int        `db:"position" json:"position"`
+	Total      int        `db:"total" json:"total"`
+	Score      int        `db:"score" json:"score"`
+	Correct    int        `db:"correct" json:"correct"`
+	ServedAt   *time.Time `db:"served_at" json:"served_at,omitempty"`
+	FinishedAt *time.Time `db:"finished_at" json:"finished_at,omitempty"`
+}
+
+// QuizAnswer is a graded answer.
+type QuizAnswer struct {
+	AttemptID  string    `db:"attempt_id" json:"attempt_id,omitempty"`
+	QuestionID string    `db:"question_id" json:"question_id"`
+	Choices    ChoiceIDs `db:"choices" json:"choices"`
+	Correct    bool      `db:"correct" json:"correct"`
+	TimedOut   bool      `db:"timed_out" json:"timed_out"`
+	Points     int       `db:"points" json:"points"`
+	ElapsedMS  int64     `db:"elapsed_ms" json:"elapsed_ms"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at,omitempty"`
+}
+
+// QuizState is what a player gets back while playing: the attempt, the
+// question now on screen and when it must be answered by, and the grade of
+// the answer just submitted.
+type QuizState struct {
+	Attempt  QuizAttempt     `json:"attempt"`
+	Question *PlayerQuestion `json:"question,omitempty"`
+	Deadline *time.Time      `json:"deadline,omitempty"`
+	Answer   *QuizAnswer     `json:"answer,omitempty"`
+}
+
+type QuizRepository interface {
+	CreateQuestionSet(ctx context.Context, set QuestionSet) (string, error)
+	GetQuestionSet(ctx context.Context, id string) (QuestionSet, error)
+	GetQuestionSetsByEnterpriseID(ctx context.Context, enterpriseID string) ([]QuestionSet, error)
+	UpdateQuestionSet(ctx context.Context, set QuestionSet) error
+	// QuestionSetInPlay reports whether a running event plays the set.
+	QuestionSetInPlay(ctx context.Context, id string) (bool, error)
+	// AttachQuestionSet makes the event play the set in mode, handing out
+	// prizes to the top scorers of a live session.
+	AttachQuestionSet(ctx context.Context, eventID string, setID string, mode string, prizes QuizPrizes) error
+
+	CreateQuestion(ctx context.Context, question Question) (string, error)
+	GetQuestion(ctx context.Context, id string, setID string) (Question, error)
+	GetQuestionsBySetID(ctx context.Context, setID string) ([]Question, error)
+	UpdateQuestion(ctx context.Context, question Question) error
+	DeleteQuestion(ctx context.Context, id string, setID string) error
+
+	// StartQuizAttempt starts the player's attempt at the event, serving the
+	// first question, or returns the attempt already started.
+	StartQuizAttempt(ctx context.Context, attempt QuizAttempt) (QuizAttempt, error)
+	GetQuizAttempt(ctx context.Context, eventID string, userID string) (QuizAttempt, error)
+	// AnswerQuizQuestion locks the attempt, lets grade score the answer to
+	// the question being shown and the time since it was served, stores the
+	// answer, adds its points to the event's leaderboards and serves the next
+	// question, all in one transaction.
+	AnswerQuizQuestion(ctx context.Context, eventID string, userID string, grade func(a QuizAttempt, elapsed time.Duration) (QuizAnswer, error)) (QuizAttempt, QuizAnswer, error)
+	GetQuizAnswers(ctx context.Context, attemptID string) ([]QuizAnswer, error)
+
+	// ClaimLiveQuizEvents marks the live quiz events that are due to start as
+	// opened by host, the URL of the claiming replica, and returns them. No
+	// event is returned twice unless its claim was released by
+	// RecoverLiveQuizzes, so a session is never hosted or rewarded twice.
+	ClaimLiveQuizEvents(ctx context.Context, host string) ([]Event, error)
+	// GetLiveQuizHost returns the URL of the replica that claimed the event's
+	// session, or "" when none did.
+	GetLiveQuizHost(ctx context.Context, eventID string) (string, error)
+	// HeartbeatLiveQuiz renews host's claim on the event's session. It fails
+	// with ErrLiveSessionLost once the claim is gone.
+	HeartbeatLiveQuiz(ctx context.Context, eventID string, host string) error
+	// FinishLiveQuiz stores the final standing of a live session as the
+	// players' attempts, adds their points to the event's leaderboards and
+	// marks the session finished, all in one transaction. It fails with
+	// ErrLiveSessionLost unless host still holds the claim.
+	FinishLiveQuiz(ctx context.Context, eventID string, host string, attempts []QuizAttempt) error
+	// RecoverLiveQuizzes handles the unfinished sessions whose claim was not
+	// renewed within timeout: those of events still running are released to
+	// be claimed again, the others are marked finished. It returns how many
+	// it handled.
+	RecoverLiveQuizzes(ctx context.Context, timeout time.Duration) (int64, error)
+}
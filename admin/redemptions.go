@@ -0,0 +1,60 @@
+package admin
+
+import (
+	"context"
+	"time"
+)
+
+// Outcomes of a redemption attempt.
+const (
+	RedemptionRedeemed         = "redeemed"
+	RedemptionInvalidSignature = "invalid_signature"
+	RedemptionNotFound         = "not_found"
+	RedemptionWrongEnterprise  = "wrong_enterprise"
+	RedemptionNotOwner         = "not_owner"
+	RedemptionInactive         = "inactive"
+	RedemptionExpired          = "expired"
+	RedemptionAlreadyRedeemed  = "already_redeemed"
+	RedemptionWrongStore       = "wrong_store"
+	RedemptionWrongCurrency    = "wrong_currency"
+	RedemptionBelowMinSpend    = "below_min_spend"
+	RedemptionBasketRequired   = "basket_required"
+	RedemptionError            = "error"
+)
+
+// Redemption records one attempt of a cashier to redeem a voucher, whatever
+// its outcome, so disputes can be settled later.
+type Redemption struct {
+	ID           string    `db:"id" json:"id,omitempty"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at,omitempty"`
+	VoucherID    string    `db:"voucher_id" json:"voucher_id,omitempty"`
+	Code         string    `db:"code" json:"code"`
+	EventID      string    `db:"event_id" json:"event_id,omitempty"`
+	EnterpriseID string    `db:"enterprise_id" json:"enterprise_id,omitempty"`
+	CashierID    string    `db:"cashier_id" json:"cashier_id"`
+	StoreID      string    `db:"store_id" json:"store_id"`
+	Outcome      string    `db:"outcome" json:"outcome"`
+}
+
+// RedeemRequest is what a cashier submits at the till: either a typed
+// voucher Code or a scanned QR Payload. OwnerID is the customer presenting
+// the voucher; a typed code of an owned voucher needs it. Basket is checked
+// against the currency and minimum spend of the voucher's discount rule, and
+// is required when the rule has either.
+type RedeemRequest struct {
+	Code    string
+	Payload string
+	StoreID string
+	OwnerID string
+	Basket  *Basket
+}
+
+type RedemptionRepository interface {
+	// RedeemVoucher locks the voucher with code, lets check decide whether it
+	// may be redeemed and marks it redeemed by the redemption's cashier at its
+	// store, all in one transaction. The voucher is returned even when check
+	// fails.
+	RedeemVoucher(ctx context.Context, code string, redemption Redemption, check func(v Voucher, enterpriseID string) error) (Voucher, error)
+	LogRedemption(ctx context.Context, redemption Redemption) error
+	GetRedemptionsByVoucherID(ctx context.Context, voucherID string) ([]Redemption, error)
+}
@@ -4,18 +4,342 @@ import (
 	"context"
 	"time"
 
+	"github.com/resrrdttrt/VOU/pkg/auth"
+	"github.com/resrrdttrt/VOU/pkg/errors"
 	log "github.com/resrrdttrt/VOU/pkg/logger"
 )
 
+var (
+	// ErrInvalidCredentials indicates that the username or password is wrong.
+	ErrInvalidCredentials = errors.Wrap(errors.ErrUnauthorized, errors.New("username or password is incorrect"))
+
+	// ErrInvalidToken indicates that the token is unknown, expired or revoked.
+	ErrInvalidToken = errors.Wrap(errors.ErrUnauthorized, errors.New("token is invalid, expired or revoked"))
+
+	// ErrEventNotFound indicates that the event does not exist or belongs to
+	// another enterprise.
+	ErrEventNotFound = errors.Wrap(errors.ErrNotFound, errors.New("event not found"))
+
+	// ErrInvalidTransition indicates that the event cannot move to the
+	// requested status from its current one.
+	ErrInvalidTransition = errors.Wrap(errors.ErrConflict, errors.New("event status transition is not allowed"))
+
+	// ErrEventLocked indicates an attempt to change voucher_num or game_id of
+	// an event that has already started.
+	ErrEventLocked = errors.Wrap(errors.ErrConflict, errors.New("voucher_num and game_id cannot change once the event has started"))
+
+	// ErrEventClosed indicates an attempt to edit an ended or cancelled
+	// event.
+	ErrEventClosed = errors.Wrap(errors.ErrConflict, errors.New("ended and cancelled events cannot be edited"))
+
+	// ErrEventNotRunning indicates an action that is only allowed while the
+	// event is running.
+	ErrEventNotRunning = errors.Wrap(errors.ErrConflict, errors.New("event is not running"))
+
+	// ErrTemplateNotFound indicates that the voucher template does not exist
+	// in the event.
+	ErrTemplateNotFound = errors.Wrap(errors.ErrNotFound, errors.New("voucher template not found"))
+
+	// ErrInventoryExceeded indicates that the templates, vouchers and batches
+	// of an event would hold more vouchers than its voucher_num, or that a
+	// template would hold fewer than were already issued.
+	ErrInventoryExceeded = errors.Wrap(errors.ErrConflict, errors.New("vouchers must fit in voucher_num and template quantities must cover issued vouchers"))
+
+	// ErrOutOfStock indicates that a voucher template has nothing left to issue.
+	ErrOutOfStock = errors.Wrap(errors.ErrConflict, errors.New("voucher template is out of stock"))
+
+	// ErrVoucherCodeTaken indicates that another voucher already uses the code.
+	ErrVoucherCodeTaken = errors.Wrap(errors.ErrConflict, errors.New("voucher code already exists"))
+
+	// ErrVoucherNotFound indicates that no voucher of the caller's enterprise
+	// has the code.
+	ErrVoucherNotFound = errors.Wrap(errors.ErrNotFound, errors.New("voucher not found"))
+
+	// ErrVoucherRedeemed indicates that the voucher was already redeemed.
+	ErrVoucherRedeemed = errors.Wrap(errors.ErrConflict, errors.New("voucher has already been redeemed"))
+
+	// ErrVoucherNotEditable indicates an edit of a voucher that is redeemed,
+	// expired or owned by a player, whose code may already be printed on a QR
+	// code.
+	ErrVoucherNotEditable = errors.Wrap(errors.ErrConflict, errors.New("voucher is redeemed, expired or owned and cannot be edited"))
+
+	// ErrVoucherExpired indicates that the voucher is past its expiry.
+	ErrVoucherExpired = errors.Wrap(errors.ErrConflict, errors.New("voucher has expired"))
+
+	// ErrVoucherInactive indicates that the voucher is not active.
+	ErrVoucherInactive = errors.Wrap(errors.ErrConflict, errors.New("voucher is not active"))
+
+	// ErrVoucherNotOwned indicates that the voucher belongs to another user.
+	ErrVoucherNotOwned = errors.Wrap(errors.ErrForbidden, errors.New("voucher belongs to another user"))
+
+	// ErrVoucherOwnerRequired indicates a typed code of an owned voucher
+	// without the owner it is being redeemed for.
+	ErrVoucherOwnerRequired = errors.Wrap(errors.ErrForbidden, errors.New("voucher has an owner: scan its QR code or give the owner's user_id"))
+
+	// ErrVoucherWrongStore indicates that the voucher's discount rule does not
+	// allow the store it is being redeemed at.
+	ErrVoucherWrongStore = errors.Wrap(errors.ErrForbidden, errors.New("voucher is not valid at this store"))
+
+	// ErrVoucherWrongCurrency indicates a basket in another currency than the
+	// voucher's discount rule.
+	ErrVoucherWrongCurrency = errors.Wrap(errors.ErrConflict, errors.New("basket is not in the voucher's currency"))
+
+	// ErrVoucherBelowMinSpend indicates a basket below the minimum spend of
+	// the voucher's discount rule.
+	ErrVoucherBelowMinSpend = errors.Wrap(errors.ErrConflict, errors.New("basket is below the voucher's minimum spend"))
+
+	// ErrVoucherBasketRequired indicates a redemption without a basket of a
+	// voucher whose discount rule has a currency or minimum spend.
+	ErrVoucherBasketRequired = errors.Wrap(errors.ErrMalformedEntity, errors.New("voucher needs the basket it is redeemed against"))
+
+	// ErrUserNotFound indicates that no user matches.
+	ErrUserNotFound = errors.Wrap(errors.ErrNotFound, errors.New("user not found"))
+
+	// ErrTransferNotFound indicates that the transfer does not exist or does
+	// not involve the caller.
+	ErrTransferNotFound = errors.Wrap(errors.ErrNotFound, errors.New("transfer not found"))
+
+	// ErrTransferPending indicates that the voucher already has an open offer.
+	ErrTransferPending = errors.Wrap(errors.ErrConflict, errors.New("voucher already has a pending transfer"))
+
+	// ErrTransferClosed indicates that the offer was already accepted,
+	// declined, cancelled or has expired.
+	ErrTransferClosed = errors.Wrap(errors.ErrConflict, errors.New("transfer is no longer pending"))
+
+	// ErrSelfTransfer indicates an attempt to gift a voucher to oneself.
+	ErrSelfTransfer = errors.Wrap(errors.ErrConflict, errors.New("voucher cannot be transferred to its owner"))
+
+	// ErrVoucherNotTransferable indicates a voucher that is redeemed, expired
+	// or no longer owned by the sender.
+	ErrVoucherNotTransferable = errors.Wrap(errors.ErrConflict, errors.New("voucher can no longer be transferred"))
+
+	// ErrExchangeNotAllowed indicates a voucher won in a game that does not
+	// allow exchanges.
+	ErrExchangeNotAllowed = errors.Wrap(errors.ErrForbidden, errors.New("vouchers of this game cannot be transferred"))
+
+	// ErrQuestionSetNotFound indicates that the question set does not exist
+	// or belongs to another enterprise.
+	ErrQuestionSetNotFound = errors.Wrap(errors.ErrNotFound, errors.New("question set not found"))
+
+	// ErrQuestionNotFound indicates that the question is not in the set.
+	ErrQuestionNotFound = errors.Wrap(errors.ErrNotFound, errors.New("question not found"))
+
+	// ErrQuestionSetLocked indicates a change to a question set, or to which
+	// set an event plays, while the event is running or over.
+	ErrQuestionSetLocked = errors.Wrap(errors.ErrConflict, errors.New("question set cannot change while an event plays it"))
+
+	// ErrNotQuizGame indicates that the event's game is not a quiz.
+	ErrNotQuizGame = errors.Wrap(errors.ErrConflict, errors.New("event does not play a quiz game"))
+
+	// ErrNoQuestionSet indicates a quiz event without questions to play.
+	ErrNoQuestionSet = errors.Wrap(errors.ErrNotFound, errors.New("event has no question set"))
+
+	// ErrQuizNotStarted indicates an answer before the player started the
+	// quiz.
+	ErrQuizNotStarted = errors.Wrap(errors.ErrNotFound, errors.New("quiz has not been started"))
+
+	// ErrQuizFinished indicates an answer after the last question.
+	ErrQuizFinished = errors.Wrap(errors.ErrConflict, errors.New("quiz is already finished"))
+
+	// ErrQuestionAnswered indicates a second answer to the same question.
+	ErrQuestionAnswered = errors.Wrap(errors.ErrConflict, errors.New("question has already been answered"))
+
+	// ErrWrongQuestion indicates an answer to a question other than the one
+	// being shown.
+	ErrWrongQuestion = errors.Wrap(errors.ErrConflict, errors.New("answer is not for the current question"))
+
+	// ErrQuizIsLive indicates a self-paced quiz call on an event whose quiz is
+	// hosted live.
+	ErrQuizIsLive = errors.Wrap(errors.ErrConflict, errors.New("quiz is hosted live, join the live session"))
+
+	// ErrLiveSessionNotFound indicates that no live session is open for the
+	// event.
+	ErrLiveSessionNotFound = errors.Wrap(errors.ErrNotFound, errors.New("no live session is open for this event"))
+
+	// ErrLiveSessionLost indicates that the replica hosting a live session no
+	// longer holds its claim, because the session was recovered elsewhere.
+	ErrLiveSessionLost = errors.Wrap(errors.ErrConflict, errors.New("live session is no longer hosted by this replica"))
+
+	// ErrLiveHostUnreachable indicates that the replica hosting a live
+	// session did not answer a forwarded request.
+	ErrLiveHostUnreachable = errors.Wrap(errors.ErrInternalServer, errors.New("the replica hosting the live session cannot be reached"))
+
+	// ErrLiveSessionOpen indicates an attempt to open a session twice.
+	ErrLiveSessionOpen = errors.Wrap(errors.ErrConflict, errors.New("live session is already open"))
+
+	// ErrAnswerWindowClosed indicates an answer outside the time the question
+	// is open.
+	ErrAnswerWindowClosed = errors.Wrap(errors.ErrConflict, errors.New("answers to this question are closed"))
+
+	// ErrNotJoined indicates an answer from a player who has not joined the
+	// live session.
+	ErrNotJoined = errors.Wrap(errors.ErrConflict, errors.New("player has not joined the live session"))
+
+	// ErrNotShakeGame indicates that the event's game is not a shake game.
+	ErrNotShakeGame = errors.Wrap(errors.ErrConflict, errors.New("event does not play a shake game"))
+
+	// ErrPrizeTableLocked indicates a change to the prize table of an event
+	// that is running, over or already has draws.
+	ErrPrizeTableLocked = errors.Wrap(errors.ErrConflict, errors.New("prize table cannot change once the event has started"))
+
+	// ErrNoTurnsLeft indicates a shake by a player who has used every turn.
+	ErrNoTurnsLeft = errors.Wrap(errors.ErrConflict, errors.New("no play turns left"))
+
+	// ErrPrizesExhausted indicates a shake when no prize is left in stock.
+	ErrPrizesExhausted = errors.Wrap(errors.ErrConflict, errors.New("every prize has been won"))
+
+	// ErrTurnsAlreadyCredited indicates a share bonus already credited today,
+	// or an invitee who already named an inviter in the event.
+	ErrTurnsAlreadyCredited = errors.Wrap(errors.ErrConflict, errors.New("turns have already been credited for this"))
+
+	// ErrTurnRequestNotFound indicates that the turn request does not exist
+	// or does not involve the caller.
+	ErrTurnRequestNotFound = errors.Wrap(errors.ErrNotFound, errors.New("turn request not found"))
+
+	// ErrTurnRequestPending indicates that the friend was already asked for a
+	// turn in the event.
+	ErrTurnRequestPending = errors.Wrap(errors.ErrConflict, errors.New("friend has already been asked for a turn"))
+
+	// ErrTurnRequestClosed indicates that the request was already accepted,
+	// declined or cancelled.
+	ErrTurnRequestClosed = errors.Wrap(errors.ErrConflict, errors.New("turn request is no longer pending"))
+
+	// ErrInviteCodeNotFound indicates an invite code that was not issued in
+	// the event.
+	ErrInviteCodeNotFound = errors.Wrap(errors.ErrNotFound, errors.New("invite code not found"))
+
+	// ErrSelfTurnRequest indicates a player asking or naming themselves.
+	ErrSelfTurnRequest = errors.Wrap(errors.ErrConflict, errors.New("players cannot ask or invite themselves"))
+
+	// ErrItemNotFound indicates that the item is not in the event's catalog.
+	ErrItemNotFound = errors.Wrap(errors.ErrNotFound, errors.New("item not found"))
+
+	// ErrItemCodeTaken indicates that the event already has an item with the
+	// code.
+	ErrItemCodeTaken = errors.Wrap(errors.ErrConflict, errors.New("item code already exists in the event"))
+
+	// ErrRecipeNotFound indicates that the recipe does not exist in the event.
+	ErrRecipeNotFound = errors.Wrap(errors.ErrNotFound, errors.New("recipe not found"))
+
+	// ErrNotEnoughItems indicates that a player does not hold the items an
+	// exchange, gift or trade needs.
+	ErrNotEnoughItems = errors.Wrap(errors.ErrConflict, errors.New("not enough items"))
+
+	// ErrItemTradeNotFound indicates that the trade does not exist or the
+	// caller is not a party to it.
+	ErrItemTradeNotFound = errors.Wrap(errors.ErrNotFound, errors.New("item trade not found"))
+
+	// ErrItemTradeClosed indicates that the trade was already accepted,
+	// declined or cancelled.
+	ErrItemTradeClosed = errors.Wrap(errors.ErrConflict, errors.New("item trade is no longer pending"))
+
+	// ErrItemExchangeNotAllowed indicates items of an event whose game does
+	// not allow exchanges.
+	ErrItemExchangeNotAllowed = errors.Wrap(errors.ErrForbidden, errors.New("items of this game cannot be traded"))
+
+	// ErrNotRanked indicates a player with no points on the leaderboard.
+	ErrNotRanked = errors.Wrap(errors.ErrNotFound, errors.New("player is not on the leaderboard"))
+
+	// ErrStandingsNotFrozen indicates that the event has not ended, or its
+	// final standings have not been taken yet.
+	ErrStandingsNotFrozen = errors.Wrap(errors.ErrNotFound, errors.New("final standings are not available yet"))
+
+	// ErrUserExists indicates a username, email or phone that another user
+	// already has.
+	ErrUserExists = errors.Wrap(errors.ErrConflict, errors.New("username, email or phone is already registered"))
+
+	// ErrAccountNotVerified indicates a login to an account whose email or
+	// phone has not been verified yet.
+	ErrAccountNotVerified = errors.Wrap(errors.ErrForbidden, errors.New("account is not verified yet"))
+
+	// ErrAccountInactive indicates a login or token refresh of an account an
+	// admin deactivated.
+	ErrAccountInactive = errors.Wrap(errors.ErrForbidden, errors.New("account is deactivated"))
+
+	// ErrCodeThrottled indicates a code requested too soon after the last one,
+	// or too many codes in the last hour.
+	ErrCodeThrottled = errors.Wrap(errors.ErrTooManyRequests, errors.New("please wait before requesting another code"))
+
+	// ErrNoContact indicates a code requested on a channel the user has no
+	// email address or phone for.
+	ErrNoContact = errors.Wrap(errors.ErrMalformedEntity, errors.New("user has no address on this channel"))
+
+	// ErrInvalidResetToken indicates a password reset token that is unknown,
+	// expired or already used.
+	ErrInvalidResetToken = errors.Wrap(errors.ErrUnauthorized, errors.New("reset token is invalid, expired or used"))
+
+	// ErrMFANotAllowed indicates an MFA request from a role that cannot use
+	// it.
+	ErrMFANotAllowed = errors.Wrap(errors.ErrForbidden, errors.New("two-factor authentication is only available to admin and enterprise accounts"))
+
+	// ErrMFANotEnrolled indicates a user who has not set up two-factor
+	// authentication.
+	ErrMFANotEnrolled = errors.Wrap(errors.ErrNotFound, errors.New("two-factor authentication is not set up"))
+
+	// ErrMFAAlreadyEnabled indicates an attempt to enroll a user who already
+	// uses two-factor authentication.
+	ErrMFAAlreadyEnabled = errors.Wrap(errors.ErrConflict, errors.New("two-factor authentication is already enabled"))
+
+	// ErrMFARequired indicates an attempt to turn off two-factor
+	// authentication that the user's role enforces, or to refresh a session
+	// that did not pass it although the user needs it.
+	ErrMFARequired = errors.Wrap(errors.ErrForbidden, errors.New("two-factor authentication is required for this role"))
+
+	// ErrInvalidMFACode indicates a TOTP or recovery code that is wrong or
+	// was already used.
+	ErrInvalidMFACode = errors.Wrap(errors.ErrUnauthorized, errors.New("authentication code is invalid"))
+
+	// ErrInvalidChallenge indicates an MFA challenge token that is unknown,
+	// expired, used or failed too many times.
+	ErrInvalidChallenge = errors.Wrap(errors.ErrUnauthorized, errors.New("login challenge is invalid or expired, please log in again"))
+
+	// ErrInvalidCode indicates a one-time code that is wrong, expired, used or
+	// guessed at too many times.
+	ErrInvalidCode = errors.Wrap(errors.ErrUnauthorized, errors.New("code is invalid or expired"))
+
+	// ErrBatchNotFound indicates that the voucher batch does not exist in the
+	// event.
+	ErrBatchNotFound = errors.Wrap(errors.ErrNotFound, errors.New("voucher batch not found"))
+
+	// ErrBatchTakenOver indicates that a stalled voucher batch was resumed by
+	// another run.
+	ErrBatchTakenOver = errors.Wrap(errors.ErrConflict, errors.New("voucher batch was resumed by another run"))
+
+	// ErrBatchAbandoned indicates that a voucher batch kept stalling and was
+	// given up.
+	ErrBatchAbandoned = errors.Wrap(errors.ErrInternalServer, errors.New("voucher batch stopped making progress"))
+)
+
 type adminService struct {
-	log        log.Logger
-	users      UserRepository
-	games      GameRepository
-	statistic  StatisticRepository
-	auth       AuthRepository
-	enterprise EnterpriseRepository
-	event      EventRepository
-	voucher    VoucherRepository
+	log         log.Logger
+	users       UserRepository
+	games       GameRepository
+	statistic   StatisticRepository
+	auth        AuthRepository
+	enterprise  EnterpriseRepository
+	event       EventRepository
+	voucher     VoucherRepository
+	inventory   InventoryRepository
+	batches     VoucherBatchRepository
+	signer      VoucherSigner
+	qr          QRRenderer
+	redemptions RedemptionRepository
+	transfers   TransferRepository
+	quiz        QuizRepository
+	live        LiveSessionStore
+	hub         LiveHub
+	draws       DrawRepository
+	turns       TurnRepository
+	items       ItemRepository
+	scores      LeaderboardRepository
+	codes       OneTimeCodeRepository
+	notifier    Notifier
+	mfa         MFARepository
+	hasher      PasswordHasher
+	hashers     []PasswordHasher
+	tokens      TokenConfig
+	issuer      TokenIssuer
+	replica     string
 }
 
 type Service interface {
@@ -26,6 +350,23 @@ type Service interface {
 	enterpriseService
 	eventService
 	voucherService
+	inventoryService
+	batchService
+	qrcodeService
+	redemptionService
+	expiryService
+	quoteService
+	walletService
+	transferService
+	quizService
+	liveService
+	drawService
+	turnService
+	itemService
+	leaderboardService
+	registrationService
+	passwordService
+	mfaService
 }
 
 type userService interface {
@@ -64,13 +405,6 @@ type statisticService interface {
 	GetTotalNewEnterprisesInWeek(ctx context.Context) ([]Statistic, error)
 }
 
-type authService interface {
-	// Auth
-	Login(ctx context.Context, username, password string) (Token, error)
-	GetUserIDByAccessToken(accessToken string) (string, error)
-	GetUserRoleByID(userID string) (string, error)
-}
-
 type enterpriseService interface {
 	RegisterEnterprise(ctx context.Context, enterprise Enterprise) error
 	GetEnterpriseInfo(ctx context.Context) (Enterprise, error)
@@ -83,6 +417,12 @@ type eventService interface {
 	GetEventByTime(ctx context.Context, start time.Time, end time.Time) ([]Event, error)
 	CreateEvent(ctx context.Context, event Event) error
 	UpdateEvent(ctx context.Context, event Event) error
+	SubmitEvent(ctx context.Context, id string) error
+	CancelEvent(ctx context.Context, id string, reason string) error
+	GetEventsByStatus(ctx context.Context, status string) ([]Event, error)
+	ApproveEvent(ctx context.Context, id string) error
+	RejectEvent(ctx context.Context, id string, reason string) error
+	AdvanceEvents(ctx context.Context) (int64, error)
 }
 
 type voucherService interface {
@@ -94,15 +434,123 @@ type voucherService interface {
 	DeleteVoucher(ctx context.Context, id string, eventID string) error
 }
 
-func NewAdminService(log log.Logger, users UserRepository, games GameRepository, statistic StatisticRepository, auth AuthRepository, enterprise EnterpriseRepository, event EventRepository, voucher VoucherRepository) Service {
+type inventoryService interface {
+	GetVoucherTemplates(ctx context.Context, eventID string) ([]VoucherTemplate, error)
+	CreateVoucherTemplate(ctx context.Context, template VoucherTemplate) (string, error)
+	UpdateVoucherTemplateQuantity(ctx context.Context, id string, eventID string, quantity int) error
+	IssueVoucher(ctx context.Context, templateID string, eventID string, userID string) (Voucher, error)
+}
+
+type batchService interface {
+	GenerateVouchers(ctx context.Context, batch VoucherBatch) (string, error)
+	GetVoucherBatch(ctx context.Context, id string, eventID string) (VoucherBatch, error)
+	// RecoverVoucherBatches resumes or fails the batches whose run stalled.
+	RecoverVoucherBatches(ctx context.Context) (int64, error)
+}
+
+type qrcodeService interface {
+	GetVoucherQRCode(ctx context.Context, id string, eventID string, format string, size int) ([]byte, error)
+	VoucherPublicKey(ctx context.Context) string
+}
+
+type redemptionService interface {
+	RedeemVoucher(ctx context.Context, req RedeemRequest) (Voucher, error)
+	GetVoucherRedemptions(ctx context.Context, id string, eventID string) ([]Redemption, error)
+}
+
+type expiryService interface {
+	ExpireVouchers(ctx context.Context) (int64, error)
+	SendExpiryReminders(ctx context.Context) (int64, error)
+}
+
+type quoteService interface {
+	QuoteVoucher(ctx context.Context, id string, basket Basket) (Quote, error)
+}
+
+type walletService interface {
+	GetMyVouchers(ctx context.Context, filter WalletFilter) (WalletPage, error)
+	GetMyVoucher(ctx context.Context, id string) (Voucher, error)
+	GetMyVoucherQRCode(ctx context.Context, id string, format string, size int) ([]byte, error)
+	GetMyVoucherRedemptions(ctx context.Context, id string) ([]Redemption, error)
+}
+
+type transferService interface {
+	OfferVoucher(ctx context.Context, voucherID string, recipient string) (Transfer, error)
+	AcceptTransfer(ctx context.Context, id string) (Transfer, error)
+	ClaimTransfer(ctx context.Context, token string) (Transfer, error)
+	DeclineTransfer(ctx context.Context, id string) error
+	CancelTransfer(ctx context.Context, id string) error
+	GetMyTransfers(ctx context.Context) ([]Transfer, error)
+	GetMyVoucherTransfers(ctx context.Context, voucherID string) ([]Transfer, error)
+}
+
+// Deps are the repositories and collaborators the service is built from.
+type Deps struct {
+	Users       UserRepository
+	Games       GameRepository
+	Statistic   StatisticRepository
+	Auth        AuthRepository
+	Enterprise  EnterpriseRepository
+	Event       EventRepository
+	Voucher     VoucherRepository
+	Inventory   InventoryRepository
+	Batches     VoucherBatchRepository
+	Signer      VoucherSigner
+	QR          QRRenderer
+	Redemptions RedemptionRepository
+	Transfers   TransferRepository
+	Quiz        QuizRepository
+	Live        LiveSessionStore
+	Hub         LiveHub
+	Draws       DrawRepository
+	Turns       TurnRepository
+	Items       ItemRepository
+	Scores      LeaderboardRepository
+	Codes       OneTimeCodeRepository
+	Notifier    Notifier
+	MFA         MFARepository
+	// Hasher hashes new passwords. Hashers verify passwords stored by the
+	// other hashers the service has been configured with before.
+	Hasher  PasswordHasher
+	Hashers []PasswordHasher
+	Tokens  TokenConfig
+	Issuer  TokenIssuer
+	// Replica is the URL other replicas reach this one at. Live quiz
+	// requests are forwarded there while it hosts the session.
+	Replica string
+}
+
+func NewAdminService(log log.Logger, deps Deps) Service {
 	return &adminService{
-		log:       log,
-		users:     users,
-		games:     games,
-		statistic: statistic,
-		auth:      auth,
-		enterprise: enterprise,
-		event:     event,
+		log:         log,
+		users:       deps.Users,
+		games:       deps.Games,
+		statistic:   deps.Statistic,
+		auth:        deps.Auth,
+		enterprise:  deps.Enterprise,
+		event:       deps.Event,
+		voucher:     deps.Voucher,
+		inventory:   deps.Inventory,
+		batches:     deps.Batches,
+		signer:      deps.Signer,
+		qr:          deps.QR,
+		redemptions: deps.Redemptions,
+		transfers:   deps.Transfers,
+		quiz:        deps.Quiz,
+		live:        deps.Live,
+		hub:         deps.Hub,
+		draws:       deps.Draws,
+		turns:       deps.Turns,
+		items:       deps.Items,
+		scores:      deps.Scores,
+		codes:       deps.Codes,
+		notifier:    deps.Notifier,
+		mfa:         deps.MFA,
+		hasher:      deps.Hasher,
+		hashers:     deps.Hashers,
+		tokens:      deps.Tokens,
+		issuer:      deps.Issuer,
+		replica:     deps.Replica,
 	}
 }
 
@@ -115,11 +563,30 @@ func (s *adminService) GetUserById(ctx context.Context, id string) (User, error)
 }
 
 func (s *adminService) CreateUser(ctx context.Context, user User) error {
-	return s.users.CreateUser(ctx, user)
+	hash, err := s.hasher.Hash(user.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = hash
+	_, err = s.users.CreateUser(ctx, user)
+	return err
 }
 
 func (s *adminService) UpdateUser(ctx context.Context, user User) error {
-	return s.users.UpdateUser(ctx, user)
+	if user.Password != "" {
+		hash, err := s.hasher.Hash(user.Password)
+		if err != nil {
+			return err
+		}
+		user.Password = hash
+	}
+	if err := s.users.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	if user.Status != "" && user.Status != UserActive {
+		return s.auth.RevokeAllAccessTokens(ctx, user.ID)
+	}
+	return nil
 }
 
 func (s *adminService) DeleteUser(ctx context.Context, id string) error {
@@ -134,12 +601,16 @@ func (s *adminService) ActiveUser(ctx context.Context, id string) error {
 	return s.users.UpdateUser(ctx, user)
 }
 
+// DeactiveUser also logs the user out everywhere.
 func (s *adminService) DeactiveUser(ctx context.Context, id string) error {
 	user := User{
 		ID:     id,
 		Status: "inactive",
 	}
-	return s.users.UpdateUser(ctx, user)
+	if err := s.users.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	return s.auth.RevokeAllAccessTokens(ctx, id)
 }
 
 func (s *adminService) GetAllGames(ctx context.Context) ([]Game, error) {
@@ -206,52 +677,153 @@ func (s *adminService) GetTotalNewEnterprisesInWeek(ctx context.Context) ([]Stat
 	return s.statistic.GetTotalNewEnterprisesInTime(ctx, start, now)
 }
 
-func (s *adminService) Login(ctx context.Context, username, password string) (Token, error) {
-	return s.auth.Login(ctx, username, password)
-}
-
-func (s *adminService) GetUserIDByAccessToken(accessToken string) (string, error) {
-	return s.auth.GetUserIDByAccessToken(accessToken)
-}
-
-func (s *adminService) GetUserRoleByID(userID string) (string, error) {
-	return s.auth.GetUserRoleByID(userID)
-}
-
 func (s *adminService) RegisterEnterprise(ctx context.Context, enterprise Enterprise) error {
 	return s.enterprise.CreateEnterprise(ctx, enterprise)
 }
 
 func (s *adminService) GetEnterpriseInfo(ctx context.Context) (Enterprise, error) {
-	enterpriseID := ctx.Value("userID").(string)
-	return s.enterprise.GetEnterpriseByID(ctx, enterpriseID)
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return Enterprise{}, auth.ErrUnauthenticated
+	}
+	return s.enterprise.GetEnterpriseByID(ctx, p.EnterpriseID)
 }
 
 func (s *adminService) UpdateEnterpriseInfo(ctx context.Context, enterprise Enterprise) error {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+	enterprise.ID = p.EnterpriseID
 	return s.enterprise.UpdateEnterprise(ctx, enterprise)
 }
 
 func (s *adminService) GetAllEvents(ctx context.Context) ([]Event, error) {
-	enterpriseID := ctx.Value("userID").(string)
-	return s.event.GetAllEventsByEnterpriseID(ctx, enterpriseID)
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	return s.event.GetAllEventsByEnterpriseID(ctx, p.EnterpriseID)
 }
 
 func (s *adminService) GetEventByID(ctx context.Context, id string) (Event, error) {
-	enterpriseID := ctx.Value("userID").(string)
-	return s.event.GetEventByID(ctx, id, enterpriseID)
+	return s.authorizeEvent(ctx, id)
 }
 
 func (s *adminService) GetEventByTime(ctx context.Context, start time.Time, end time.Time) ([]Event, error) {
-	enterpriseID := ctx.Value("userID").(string)
-	return s.event.GetEventByTime(ctx,enterpriseID, start, end)
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	return s.event.GetEventByTime(ctx, p.EnterpriseID, start, end)
 }
 
 func (s *adminService) CreateEvent(ctx context.Context, event Event) error {
+	event.Status = EventDraft
 	return s.event.CreateEvent(ctx, event)
 }
 
+// UpdateEvent edits an event. An approved event goes back to review, since
+// it was approved as it was.
 func (s *adminService) UpdateEvent(ctx context.Context, event Event) error {
-	return s.event.UpdateEvent(ctx, event)
+	if _, err := s.authorizeEvent(ctx, event.ID); err != nil {
+		return err
+	}
+	now := time.Now()
+	return s.event.UpdateEvent(ctx, event.ID, func(current Event) (Event, error) {
+		return editEvent(current, event, now)
+	})
+}
+
+// editEvent returns the changes that edit current into event. Ended and
+// cancelled events are final, and the game and voucher count of an event are
+// fixed once its start time has passed.
+func editEvent(current Event, event Event, now time.Time) (Event, error) {
+	if current.Status == EventEnded || current.Status == EventCancelled {
+		return Event{}, ErrEventClosed
+	}
+	started := current.Status == EventRunning || !now.Before(current.StartTime)
+	if started && ((event.VoucherNum != 0 && event.VoucherNum != current.VoucherNum) || (event.GameID != "" && event.GameID != current.GameID)) {
+		return Event{}, ErrEventLocked
+	}
+	changes := Event{
+		Name:       event.Name,
+		Images:     event.Images,
+		VoucherNum: event.VoucherNum,
+		StartTime:  event.StartTime,
+		EndTime:    event.EndTime,
+		GameID:     event.GameID,
+	}
+	if current.Status == EventApproved && eventChanged(current, changes) {
+		changes.Status = EventPendingReview
+		changes.StatusReason = "edited after approval"
+	}
+	return changes, nil
+}
+
+// eventChanged reports whether applying changes to current alters it.
+func eventChanged(current Event, changes Event) bool {
+	return (changes.Name != "" && changes.Name != current.Name) ||
+		(changes.Images != "" && changes.Images != current.Images) ||
+		(changes.VoucherNum != 0 && changes.VoucherNum != current.VoucherNum) ||
+		(!changes.StartTime.IsZero() && !changes.StartTime.Equal(current.StartTime)) ||
+		(!changes.EndTime.IsZero() && !changes.EndTime.Equal(current.EndTime)) ||
+		(changes.GameID != "" && changes.GameID != current.GameID)
+}
+
+func (s *adminService) SubmitEvent(ctx context.Context, id string) error {
+	event, err := s.authorizeEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.transitionEvent(ctx, event, EventPendingReview, "")
+}
+
+func (s *adminService) CancelEvent(ctx context.Context, id string, reason string) error {
+	event, err := s.authorizeEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.transitionEvent(ctx, event, EventCancelled, reason)
+}
+
+func (s *adminService) GetEventsByStatus(ctx context.Context, status string) ([]Event, error) {
+	return s.event.GetEventsByStatus(ctx, status)
+}
+
+func (s *adminService) ApproveEvent(ctx context.Context, id string) error {
+	event, err := s.event.GetEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if event.Status != EventPendingReview {
+		return ErrInvalidTransition
+	}
+	return s.transitionEvent(ctx, event, EventApproved, "")
+}
+
+// RejectEvent sends an event under review back to draft so the enterprise can
+// address reason and submit it again.
+func (s *adminService) RejectEvent(ctx context.Context, id string, reason string) error {
+	event, err := s.event.GetEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if event.Status != EventPendingReview {
+		return ErrInvalidTransition
+	}
+	return s.transitionEvent(ctx, event, EventDraft, reason)
+}
+
+func (s *adminService) AdvanceEvents(ctx context.Context) (int64, error) {
+	return s.event.AdvanceEventStatuses(ctx)
+}
+
+func (s *adminService) transitionEvent(ctx context.Context, event Event, to string, reason string) error {
+	if !CanTransition(event.Status, to) {
+		return ErrInvalidTransition
+	}
+	return s.event.UpdateEventStatus(ctx, event.ID, event.Status, to, reason)
 }
 
 // func (s *adminService) GetAllVouchers(ctx context.Context) ([]Voucher, error) {
@@ -259,23 +831,752 @@ func (s *adminService) UpdateEvent(ctx context.Context, event Event) error {
 // }
 
 func (s *adminService) GetAllVouchersByEventID(ctx context.Context, eventID string) ([]Voucher, error) {
-	return s.voucher.GetAllVouchersByEventID(ctx, eventID)
+	if _, err := s.authorizeEvent(ctx, eventID); err != nil {
+		return nil, err
+	}
+	vouchers, err := s.voucher.GetAllVouchersByEventID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range vouchers {
+		if err := s.signVoucher(&vouchers[i]); err != nil {
+			return nil, err
+		}
+	}
+	return vouchers, nil
 }
 
 func (s *adminService) GetVoucherByID(ctx context.Context, id string, eventID string) (Voucher, error) {
-	return s.voucher.GetVoucherByID(ctx, id, eventID)
+	if _, err := s.authorizeEvent(ctx, eventID); err != nil {
+		return Voucher{}, err
+	}
+	voucher, err := s.voucher.GetVoucherByID(ctx, id, eventID)
+	if err != nil {
+		return Voucher{}, err
+	}
+	if err := s.signVoucher(&voucher); err != nil {
+		return Voucher{}, err
+	}
+	return voucher, nil
 }
 
 func (s *adminService) CreateVoucher(ctx context.Context, voucher Voucher) error {
+	if _, err := s.authorizeEvent(ctx, voucher.EventID); err != nil {
+		return err
+	}
 	return s.voucher.CreateVoucher(ctx, voucher)
 }
 
+// UpdateVoucher only edits vouchers nobody holds yet: a redeemed, expired or
+// owned voucher may already be on a player's QR code.
 func (s *adminService) UpdateVoucher(ctx context.Context, voucher Voucher) error {
+	if _, err := s.authorizeEvent(ctx, voucher.EventID); err != nil {
+		return err
+	}
+	current, err := s.voucher.GetVoucherByID(ctx, voucher.ID, voucher.EventID)
+	if err != nil {
+		return err
+	}
+	if current.Status == VoucherRedeemed || current.Status == VoucherExpired || current.OwnerID != "" || !current.ExpiredTime.After(time.Now()) {
+		return ErrVoucherNotEditable
+	}
 	return s.voucher.UpdateVoucher(ctx, voucher)
 }
 
 func (s *adminService) DeleteVoucher(ctx context.Context, id string, eventID string) error {
+	if _, err := s.authorizeEvent(ctx, eventID); err != nil {
+		return err
+	}
 	return s.voucher.DeleteVoucher(ctx, id, eventID)
 }
 
+func (s *adminService) GetVoucherTemplates(ctx context.Context, eventID string) ([]VoucherTemplate, error) {
+	if _, err := s.authorizeEvent(ctx, eventID); err != nil {
+		return nil, err
+	}
+	return s.inventory.GetTemplatesByEventID(ctx, eventID)
+}
+
+func (s *adminService) CreateVoucherTemplate(ctx context.Context, template VoucherTemplate) (string, error) {
+	if _, err := s.authorizeEvent(ctx, template.EventID); err != nil {
+		return "", err
+	}
+	return s.inventory.CreateTemplate(ctx, template)
+}
+
+func (s *adminService) UpdateVoucherTemplateQuantity(ctx context.Context, id string, eventID string, quantity int) error {
+	if _, err := s.authorizeEvent(ctx, eventID); err != nil {
+		return err
+	}
+	if _, err := s.inventory.GetTemplateByID(ctx, id, eventID); err != nil {
+		return err
+	}
+	return s.inventory.UpdateTemplateQuantity(ctx, id, eventID, quantity)
+}
+
+// IssueVoucher hands one voucher of the template to userID. Stock is taken
+// atomically by the repository, so concurrent calls never oversell.
+func (s *adminService) IssueVoucher(ctx context.Context, templateID string, eventID string, userID string) (Voucher, error) {
+	event, err := s.authorizeEvent(ctx, eventID)
+	if err != nil {
+		return Voucher{}, err
+	}
+	if event.Status != EventRunning {
+		return Voucher{}, ErrEventNotRunning
+	}
+	if _, err := s.inventory.GetTemplateByID(ctx, templateID, eventID); err != nil {
+		return Voucher{}, err
+	}
+	code, err := GenerateVoucherCode()
+	if err != nil {
+		return Voucher{}, err
+	}
+	voucher, err := s.inventory.IssueVoucher(ctx, templateID, eventID, userID, code)
+	if err != nil {
+		return Voucher{}, err
+	}
+	if err := s.signVoucher(&voucher); err != nil {
+		return Voucher{}, err
+	}
+	return voucher, nil
+}
+
+// GetVoucherQRCode renders the signed payload of a voucher as a QR code image
+// in format, png or svg.
+func (s *adminService) GetVoucherQRCode(ctx context.Context, id string, eventID string, format string, size int) ([]byte, error) {
+	voucher, err := s.GetVoucherByID(ctx, id, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if format == QRCodeSVG {
+		return s.qr.SVG(voucher.Qrcode, size)
+	}
+	return s.qr.PNG(voucher.Qrcode, size)
+}
+
+func (s *adminService) VoucherPublicKey(ctx context.Context) string {
+	return s.signer.PublicKey()
+}
+
+// signVoucher replaces the stored qrcode of v with a freshly signed payload,
+// so the QR content always matches the current code and expiry.
+func (s *adminService) signVoucher(v *Voucher) error {
+	payload, err := s.signer.Sign(NewVoucherClaims(*v))
+	if err != nil {
+		return err
+	}
+	v.Qrcode = payload
+	return nil
+}
+
+// RedeemVoucher marks the voucher presented at a till as redeemed. A scanned
+// payload must carry a valid signature. Every attempt is logged with its
+// outcome, successful or not.
+func (s *adminService) RedeemVoucher(ctx context.Context, req RedeemRequest) (Voucher, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return Voucher{}, auth.ErrUnauthenticated
+	}
+	attempt := Redemption{
+		Code:      req.Code,
+		CashierID: p.UserID,
+		StoreID:   req.StoreID,
+	}
+	voucher, err := s.redeemVoucher(ctx, p, req, &attempt)
+	if attempt.Outcome == "" {
+		attempt.Outcome = redemptionOutcome(err)
+	}
+	if err := s.redemptions.LogRedemption(ctx, attempt); err != nil {
+		s.log.Errorf("failed to log redemption of voucher %s: %s", attempt.Code, err)
+	}
+	if err != nil {
+		return Voucher{}, err
+	}
+	if err := s.signVoucher(&voucher); err != nil {
+		return Voucher{}, err
+	}
+	return voucher, nil
+}
+
+func (s *adminService) redeemVoucher(ctx context.Context, p auth.Principal, req RedeemRequest, attempt *Redemption) (Voucher, error) {
+	var claims *VoucherClaims
+	if req.Payload != "" {
+		c, err := s.signer.Verify(req.Payload)
+		if err != nil {
+			return Voucher{}, err
+		}
+		claims = &c
+		req.Code = c.Code
+		attempt.Code = c.Code
+	}
+	now := time.Now()
+	check := func(v Voucher, enterpriseID string) error {
+		attempt.VoucherID = v.ID
+		attempt.EventID = v.EventID
+		attempt.EnterpriseID = enterpriseID
+		if claims != nil && (claims.VoucherID != v.ID || claims.EventID != v.EventID) {
+			return ErrInvalidVoucherSignature
+		}
+		if p.Role != auth.RoleAdmin && enterpriseID != p.EnterpriseID {
+			// Reported as missing so codes of other enterprises cannot be probed.
+			attempt.Outcome = RedemptionWrongEnterprise
+			return ErrVoucherNotFound
+		}
+		if v.OwnerID != "" {
+			// An owned voucher is only redeemed for its owner: a scanned
+			// code proves it by its signed owner, a typed code needs the
+			// owner named.
+			if claims != nil && claims.OwnerID != v.OwnerID {
+				return ErrVoucherNotOwned
+			}
+			if claims == nil && req.OwnerID == "" {
+				return ErrVoucherOwnerRequired
+			}
+			if req.OwnerID != "" && req.OwnerID != v.OwnerID {
+				return ErrVoucherNotOwned
+			}
+		}
+		if v.Status == VoucherRedeemed {
+			return ErrVoucherRedeemed
+		}
+		if v.Status == VoucherExpired {
+			return ErrVoucherExpired
+		}
+		if v.Status != VoucherActive {
+			return ErrVoucherInactive
+		}
+		if !v.ExpiredTime.After(now) {
+			return ErrVoucherExpired
+		}
+		if v.Rule == nil {
+			return nil
+		}
+		if len(v.Rule.Stores) > 0 && !contains(v.Rule.Stores, req.StoreID) {
+			return ErrVoucherWrongStore
+		}
+		if req.Basket == nil {
+			if v.Rule.MinSpend > 0 || v.Rule.Currency != "" {
+				return ErrVoucherBasketRequired
+			}
+			return nil
+		}
+		if v.Rule.Currency != "" && v.Rule.Currency != req.Basket.Currency {
+			return ErrVoucherWrongCurrency
+		}
+		if req.Basket.Subtotal() < v.Rule.MinSpend {
+			return ErrVoucherBelowMinSpend
+		}
+		return nil
+	}
+	return s.redemptions.RedeemVoucher(ctx, req.Code, *attempt, check)
+}
+
+func redemptionOutcome(err error) string {
+	switch err {
+	case nil:
+		return RedemptionRedeemed
+	case ErrInvalidVoucherSignature, ErrMalformedVoucherPayload:
+		return RedemptionInvalidSignature
+	case ErrVoucherNotFound:
+		return RedemptionNotFound
+	case ErrVoucherNotOwned, ErrVoucherOwnerRequired:
+		return RedemptionNotOwner
+	case ErrVoucherRedeemed:
+		return RedemptionAlreadyRedeemed
+	case ErrVoucherInactive:
+		return RedemptionInactive
+	case ErrVoucherExpired:
+		return RedemptionExpired
+	case ErrVoucherWrongStore:
+		return RedemptionWrongStore
+	case ErrVoucherWrongCurrency:
+		return RedemptionWrongCurrency
+	case ErrVoucherBelowMinSpend:
+		return RedemptionBelowMinSpend
+	case ErrVoucherBasketRequired:
+		return RedemptionBasketRequired
+	default:
+		return RedemptionError
+	}
+}
+
+// GetVoucherRedemptions lists every redemption attempt of a voucher, oldest
+// first.
+func (s *adminService) GetVoucherRedemptions(ctx context.Context, id string, eventID string) ([]Redemption, error) {
+	if _, err := s.authorizeEvent(ctx, eventID); err != nil {
+		return nil, err
+	}
+	if _, err := s.voucher.GetVoucherByID(ctx, id, eventID); err != nil {
+		return nil, err
+	}
+	return s.redemptions.GetRedemptionsByVoucherID(ctx, id)
+}
+
+// QuoteVoucher tells a cashier what the voucher is worth against basket
+// without redeeming it.
+func (s *adminService) QuoteVoucher(ctx context.Context, id string, basket Basket) (Quote, error) {
+	voucher, err := s.voucher.GetVoucher(ctx, id)
+	if err != nil {
+		return Quote{}, err
+	}
+	if _, err := s.authorizeEvent(ctx, voucher.EventID); err != nil {
+		if err == ErrEventNotFound {
+			return Quote{}, ErrVoucherNotFound
+		}
+		return Quote{}, err
+	}
+	quote := Quote{
+		Subtotal: basket.Subtotal(),
+		Currency: basket.Currency,
+	}
+	quote.Total = quote.Subtotal
+	switch {
+	case voucher.Status != VoucherActive:
+		quote.Reason = "voucher is " + voucher.Status
+	case !voucher.ExpiredTime.After(time.Now()):
+		quote.Reason = "voucher has expired"
+	case voucher.Rule == nil:
+		quote.Reason = "voucher has no discount rule"
+	default:
+		quote = voucher.Rule.Apply(basket)
+	}
+	return quote, nil
+}
+
+// GetMyVouchers lists the vouchers the caller owns, one page at a time.
+func (s *adminService) GetMyVouchers(ctx context.Context, filter WalletFilter) (WalletPage, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return WalletPage{}, auth.ErrUnauthenticated
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefWalletLimit
+	}
+	if limit > MaxWalletLimit {
+		limit = MaxWalletLimit
+	}
+	q := OwnedVoucherQuery{
+		OwnerID:       p.UserID,
+		Status:        filter.Status,
+		EventID:       filter.EventID,
+		ExpiresAfter:  filter.ExpiresAfter,
+		ExpiresBefore: filter.ExpiresBefore,
+		// One extra row tells whether another page follows.
+		Limit: limit + 1,
+	}
+	if filter.Cursor != "" {
+		expiry, id, err := decodeWalletCursor(filter.Cursor)
+		if err != nil {
+			return WalletPage{}, err
+		}
+		q.AfterExpiry, q.AfterID = expiry, id
+	}
+	vouchers, err := s.voucher.GetOwnedVouchers(ctx, q)
+	if err != nil {
+		return WalletPage{}, err
+	}
+	page := WalletPage{Vouchers: vouchers}
+	if len(vouchers) > limit {
+		page.Vouchers = vouchers[:limit]
+		page.Next = encodeWalletCursor(page.Vouchers[limit-1])
+	}
+	for i := range page.Vouchers {
+		if err := s.signVoucher(&page.Vouchers[i]); err != nil {
+			return WalletPage{}, err
+		}
+	}
+	return page, nil
+}
+
+func (s *adminService) GetMyVoucher(ctx context.Context, id string) (Voucher, error) {
+	voucher, err := s.ownedVoucher(ctx, id)
+	if err != nil {
+		return Voucher{}, err
+	}
+	if err := s.signVoucher(&voucher); err != nil {
+		return Voucher{}, err
+	}
+	return voucher, nil
+}
+
+func (s *adminService) GetMyVoucherQRCode(ctx context.Context, id string, format string, size int) ([]byte, error) {
+	voucher, err := s.GetMyVoucher(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if format == QRCodeSVG {
+		return s.qr.SVG(voucher.Qrcode, size)
+	}
+	return s.qr.PNG(voucher.Qrcode, size)
+}
+
+func (s *adminService) GetMyVoucherRedemptions(ctx context.Context, id string) ([]Redemption, error) {
+	if _, err := s.ownedVoucher(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.redemptions.GetRedemptionsByVoucherID(ctx, id)
+}
+
+// ownedVoucher returns the voucher if the caller owns it. Vouchers of other
+// users are reported as missing.
+func (s *adminService) ownedVoucher(ctx context.Context, id string) (Voucher, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return Voucher{}, auth.ErrUnauthenticated
+	}
+	voucher, err := s.voucher.GetVoucher(ctx, id)
+	if err != nil {
+		return Voucher{}, err
+	}
+	if voucher.OwnerID != p.UserID {
+		return Voucher{}, ErrVoucherNotFound
+	}
+	return voucher, nil
+}
+
+// OfferVoucher offers a voucher the caller owns to another player, named by
+// username, email or phone. With an empty recipient the offer is a claim
+// link: the returned transfer carries the one-time ClaimToken.
+func (s *adminService) OfferVoucher(ctx context.Context, voucherID string, recipient string) (Transfer, error) {
+	voucher, err := s.ownedVoucher(ctx, voucherID)
+	if err != nil {
+		return Transfer{}, err
+	}
+	if voucher.Status != VoucherActive || !voucher.ExpiredTime.After(time.Now()) {
+		return Transfer{}, ErrVoucherNotTransferable
+	}
+	if err := s.checkExchangeAllowed(ctx, voucher.EventID); err != nil {
+		return Transfer{}, err
+	}
+
+	transfer := Transfer{
+		VoucherID:  voucher.ID,
+		FromUserID: voucher.OwnerID,
+		ExpiresAt:  time.Now().Add(TransferTTL),
+	}
+	token := ""
+	if recipient != "" {
+		user, err := s.users.GetUserByContact(ctx, recipient)
+		if err != nil {
+			return Transfer{}, err
+		}
+		if user.Role != auth.RoleEndUser || user.Status != "active" {
+			return Transfer{}, ErrUserNotFound
+		}
+		if user.ID == voucher.OwnerID {
+			return Transfer{}, ErrSelfTransfer
+		}
+		transfer.ToUserID = user.ID
+	} else {
+		if token, err = GenerateClaimToken(); err != nil {
+			return Transfer{}, err
+		}
+		transfer.ClaimTokenHash = HashClaimToken(token)
+	}
+
+	created, err := s.transfers.CreateTransfer(ctx, transfer)
+	if err != nil {
+		return Transfer{}, err
+	}
+	created.ClaimToken = token
+	return created, nil
+}
+
+// checkExchangeAllowed rejects vouchers of events whose game forbids
+// exchanges. It runs again when an offer is accepted, since the game may have
+// changed since the offer was made.
+func (s *adminService) checkExchangeAllowed(ctx context.Context, eventID string) error {
+	event, err := s.event.GetEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if event.GameID == "" {
+		return nil
+	}
+	game, err := s.games.GetGameById(ctx, event.GameID)
+	if err != nil {
+		return err
+	}
+	if !game.ExchangeAllow {
+		return ErrExchangeNotAllowed
+	}
+	return nil
+}
+
+func (s *adminService) AcceptTransfer(ctx context.Context, id string) (Transfer, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return Transfer{}, auth.ErrUnauthenticated
+	}
+	return s.transfers.AcceptTransfer(ctx, id, "", p.UserID, func(eventID string) error {
+		return s.checkExchangeAllowed(ctx, eventID)
+	})
+}
+
+// ClaimTransfer accepts the link offer token was issued for.
+func (s *adminService) ClaimTransfer(ctx context.Context, token string) (Transfer, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return Transfer{}, auth.ErrUnauthenticated
+	}
+	return s.transfers.AcceptTransfer(ctx, "", HashClaimToken(token), p.UserID, func(eventID string) error {
+		return s.checkExchangeAllowed(ctx, eventID)
+	})
+}
+
+// DeclineTransfer lets the named recipient turn an offer down.
+func (s *adminService) DeclineTransfer(ctx context.Context, id string) error {
+	transfer, err := s.myTransfer(ctx, id, func(t Transfer, userID string) bool { return t.ToUserID == userID })
+	if err != nil {
+		return err
+	}
+	return s.transfers.CloseTransfer(ctx, transfer.ID, TransferDeclined)
+}
+
+// CancelTransfer lets the sender withdraw an offer.
+func (s *adminService) CancelTransfer(ctx context.Context, id string) error {
+	transfer, err := s.myTransfer(ctx, id, func(t Transfer, userID string) bool { return t.FromUserID == userID })
+	if err != nil {
+		return err
+	}
+	return s.transfers.CloseTransfer(ctx, transfer.ID, TransferCancelled)
+}
+
+// GetMyTransfers lists the open offers the caller sent or received.
+func (s *adminService) GetMyTransfers(ctx context.Context) ([]Transfer, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	return s.transfers.GetPendingTransfersByUser(ctx, p.UserID)
+}
+
+// GetMyVoucherTransfers returns the full transfer history of a voucher the
+// caller owns.
+func (s *adminService) GetMyVoucherTransfers(ctx context.Context, voucherID string) ([]Transfer, error) {
+	if _, err := s.ownedVoucher(ctx, voucherID); err != nil {
+		return nil, err
+	}
+	return s.transfers.GetTransfersByVoucher(ctx, voucherID)
+}
+
+// myTransfer returns the transfer if party says the caller may act on it.
+// Transfers of other players are reported as missing.
+func (s *adminService) myTransfer(ctx context.Context, id string, party func(t Transfer, userID string) bool) (Transfer, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return Transfer{}, auth.ErrUnauthenticated
+	}
+	transfer, err := s.transfers.GetTransfer(ctx, id)
+	if err != nil {
+		return Transfer{}, err
+	}
+	if !party(transfer, p.UserID) {
+		return Transfer{}, ErrTransferNotFound
+	}
+	return transfer, nil
+}
+
+// expiryBatchSize is the number of vouchers the expiry jobs handle per query.
+const expiryBatchSize = 1000
+
+// ExpireVouchers moves every active voucher past its expiry to expired, one
+// batch at a time so no single statement locks too many rows.
+func (s *adminService) ExpireVouchers(ctx context.Context) (int64, error) {
+	var total int64
+	for {
+		n, err := s.voucher.ExpireVouchers(ctx, expiryBatchSize)
+		total += n
+		if err != nil || n < expiryBatchSize {
+			return total, err
+		}
+	}
+}
+
+// SendExpiryReminders emits the 3 day reminder for owned vouchers expiring
+// in 24 to 72 hours and the 24 hour reminder for those expiring sooner.
+func (s *adminService) SendExpiryReminders(ctx context.Context) (int64, error) {
+	now := time.Now()
+	windows := []struct {
+		kind     string
+		from, to time.Time
+	}{
+		{Reminder24h, now, now.Add(24 * time.Hour)},
+		{Reminder3Days, now.Add(24 * time.Hour), now.Add(72 * time.Hour)},
+	}
+	var total int64
+	for _, w := range windows {
+		for {
+			reminders, err := s.voucher.QueueExpiryReminders(ctx, w.kind, w.from, w.to, expiryBatchSize)
+			if err != nil {
+				return total, err
+			}
+			for _, r := range reminders {
+				s.log.Infof("voucher %s of user %s expires at %s (%s reminder)", r.VoucherID, r.OwnerID, r.ExpiredTime.Format(time.RFC3339), r.Kind)
+			}
+			total += int64(len(reminders))
+			if len(reminders) < expiryBatchSize {
+				break
+			}
+		}
+	}
+	return total, nil
+}
+
+// GenerateVouchers records a batch job for the event and starts generating
+// its vouchers in the background. The returned batch ID can be polled with
+// GetVoucherBatch.
+func (s *adminService) GenerateVouchers(ctx context.Context, batch VoucherBatch) (string, error) {
+	if _, err := s.authorizeEvent(ctx, batch.EventID); err != nil {
+		return "", err
+	}
+	format := batch.Format()
+	if err := format.Validate(); err != nil {
+		return "", err
+	}
+	// Leave plenty of headroom so random codes rarely collide.
+	if format.Capacity()/batchCodeHeadroom < int64(batch.Count) {
+		return "", ErrCodeSpaceTooSmall
+	}
+	batch.Status = BatchPending
+	id, err := s.batches.CreateBatch(ctx, batch)
+	if err != nil {
+		return "", err
+	}
+	batch.ID = id
+	go s.runBatch(context.Background(), batch)
+	return id, nil
+}
 
+func (s *adminService) GetVoucherBatch(ctx context.Context, id string, eventID string) (VoucherBatch, error) {
+	if _, err := s.authorizeEvent(ctx, eventID); err != nil {
+		return VoucherBatch{}, err
+	}
+	return s.batches.GetBatch(ctx, id, eventID)
+}
+
+const (
+	// batchChunkSize is the number of vouchers written per INSERT.
+	batchChunkSize = 1000
+	// batchCodeHeadroom is how many times larger than the batch the code space
+	// of its format must be.
+	batchCodeHeadroom = 10
+	// batchMaxStalls is how many chunks in a row may insert nothing before the
+	// batch gives up.
+	batchMaxStalls = 5
+	// batchStaleAfter is how long a pending or running batch may go without
+	// progress before it is resumed, and batchMaxResumes how many times it is
+	// resumed before failing.
+	batchStaleAfter = 5 * time.Minute
+	batchMaxResumes = 3
+)
+
+// RecoverVoucherBatches resumes the batches whose run stopped making
+// progress, typically because the replica running them died. A resumed run
+// continues from the vouchers already stored.
+func (s *adminService) RecoverVoucherBatches(ctx context.Context) (int64, error) {
+	batches, err := s.batches.ClaimStaleBatches(ctx, batchStaleAfter)
+	if err != nil {
+		return 0, err
+	}
+	for _, batch := range batches {
+		if batch.Attempt > batchMaxResumes {
+			s.log.Errorf("voucher batch %s failed after %d vouchers: %s", batch.ID, batch.Generated, ErrBatchAbandoned)
+			if err := s.batches.UpdateBatch(ctx, batch.ID, batch.Attempt, BatchFailed, batch.Generated, ErrBatchAbandoned.Error()); err != nil {
+				s.log.Errorf("failed to record failure of voucher batch %s: %s", batch.ID, err)
+			}
+			continue
+		}
+		s.log.Warnf("resuming voucher batch %s after %d vouchers", batch.ID, batch.Generated)
+		go s.runBatch(context.Background(), batch)
+	}
+	return int64(len(batches)), nil
+}
+
+// runBatch generates the vouchers of batch chunk by chunk, starting from the
+// Generated already stored. Codes that collide with existing vouchers are
+// skipped by the repository and regenerated in the next chunk, so the batch
+// ends with exactly Count new vouchers. The run stops as soon as another one
+// resumes the batch.
+func (s *adminService) runBatch(ctx context.Context, batch VoucherBatch) {
+	format := batch.Format()
+	generated, stalls := batch.Generated, 0
+	fail := func(err error) {
+		if err == ErrBatchTakenOver {
+			s.log.Warnf("voucher batch %s was resumed by another run, stopping", batch.ID)
+			return
+		}
+		s.log.Errorf("voucher batch %s failed after %d vouchers: %s", batch.ID, generated, err)
+		if err := s.batches.UpdateBatch(ctx, batch.ID, batch.Attempt, BatchFailed, generated, err.Error()); err != nil {
+			s.log.Errorf("failed to record failure of voucher batch %s: %s", batch.ID, err)
+		}
+	}
+	if err := s.batches.UpdateBatch(ctx, batch.ID, batch.Attempt, BatchRunning, generated, ""); err != nil {
+		fail(err)
+		return
+	}
+	for generated < batch.Count {
+		size := batch.Count - generated
+		if size > batchChunkSize {
+			size = batchChunkSize
+		}
+		seen := make(map[string]struct{}, size)
+		codes := make([]string, 0, size)
+		for len(codes) < size {
+			code, err := format.Generate()
+			if err != nil {
+				fail(err)
+				return
+			}
+			if _, ok := seen[code]; ok {
+				continue
+			}
+			seen[code] = struct{}{}
+			codes = append(codes, code)
+		}
+		inserted, err := s.batches.InsertBatchVouchers(ctx, batch, codes)
+		if err != nil {
+			fail(err)
+			return
+		}
+		if inserted == 0 {
+			stalls++
+			if stalls >= batchMaxStalls {
+				fail(ErrCodeSpaceTooSmall)
+				return
+			}
+			continue
+		}
+		stalls = 0
+		generated += inserted
+		if err := s.batches.UpdateBatch(ctx, batch.ID, batch.Attempt, BatchRunning, generated, ""); err != nil {
+			fail(err)
+			return
+		}
+	}
+	if err := s.batches.UpdateBatch(ctx, batch.ID, batch.Attempt, BatchCompleted, generated, ""); err != nil {
+		fail(err)
+	}
+}
+
+// authorizeEvent checks that the event belongs to the caller's enterprise.
+// Admins may act on any event. Events of other enterprises are reported as
+// missing so their IDs cannot be probed.
+func (s *adminService) authorizeEvent(ctx context.Context, eventID string) (Event, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return Event{}, auth.ErrUnauthenticated
+	}
+	event, err := s.event.GetEvent(ctx, eventID)
+	if err != nil {
+		return Event{}, err
+	}
+	if p.Role == auth.RoleAdmin {
+		return event, nil
+	}
+	if p.EnterpriseID == "" || p.EnterpriseID != event.UserID {
+		return Event{}, ErrEventNotFound
+	}
+	return event, nil
+}
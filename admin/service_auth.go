@@ -0,0 +1,663 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/resrrdttrt/VOU/pkg/auth"
+)
+
+type authService interface {
+	// Auth
+	Login(ctx context.Context, username, password string) (Token, error)
+	RefreshToken(ctx context.Context, refreshToken string) (Token, error)
+	Logout(ctx context.Context, accessToken string) error
+	LogoutAll(ctx context.Context, accessToken string) error
+	PurgeAccessTokens(ctx context.Context) (int64, error)
+	SyncRevokedTokens(ctx context.Context) (int64, error)
+	Identify(ctx context.Context, accessToken string) (auth.Principal, error)
+	GetUserIDByAccessToken(accessToken string) (string, error)
+	GetUserRoleByID(userID string) (string, error)
+}
+
+type registrationService interface {
+	Register(ctx context.Context, user User, channel string) (Registration, error)
+	VerifyRegistration(ctx context.Context, contact string, code string) error
+	ResendVerification(ctx context.Context, contact string, channel string) error
+	PurgeUnverifiedUsers(ctx context.Context) (int64, error)
+}
+
+type passwordService interface {
+	ForgotPassword(ctx context.Context, contact string, channel string) error
+	ResetPassword(ctx context.Context, token string, password string) error
+}
+
+type mfaService interface {
+	LoginMFA(ctx context.Context, challenge string, code string) (Token, error)
+	EnrollMFALogin(ctx context.Context, challenge string) (MFAEnrollment, error)
+	EnrollMFA(ctx context.Context) (MFAEnrollment, error)
+	ConfirmMFA(ctx context.Context, code string) ([]string, error)
+	DisableMFA(ctx context.Context, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error)
+	GetMFAPolicies(ctx context.Context) ([]MFAPolicy, error)
+	SetMFAPolicy(ctx context.Context, role string, enforced bool) error
+}
+
+func (s *adminService) Login(ctx context.Context, username, password string) (Token, error) {
+	user, err := s.users.GetUserByUsername(ctx, username)
+	if err != nil {
+		return Token{}, ErrInvalidCredentials
+	}
+	if err := s.verifyPassword(ctx, user, password); err != nil {
+		return Token{}, err
+	}
+	if err := checkAccountStatus(user); err != nil {
+		return Token{}, err
+	}
+	if MFARole(user.Role) {
+		enroll, required, err := s.mfaRequired(ctx, user)
+		if err != nil {
+			return Token{}, err
+		}
+		if required {
+			return s.challenge(ctx, user, enroll)
+		}
+	}
+	token, err := s.auth.CreateAccessToken(ctx, user.ID, false, s.tokens)
+	if err != nil {
+		return Token{}, err
+	}
+	return s.issue(user, token)
+}
+
+// RefreshToken refuses to extend the session of a user who is no longer
+// active, or a session that did not pass two-factor authentication once the
+// user needs it, because they turned it on or their role enforces it now.
+// They have to log in again.
+func (s *adminService) RefreshToken(ctx context.Context, refreshToken string) (Token, error) {
+	var user User
+	_, token, err := s.auth.RefreshAccessToken(ctx, refreshToken, s.tokens, func(userID string, mfa bool) error {
+		var err error
+		if user, err = s.users.GetUserById(ctx, userID); err != nil {
+			return err
+		}
+		if err := checkAccountStatus(user); err != nil {
+			return err
+		}
+		if mfa || !MFARole(user.Role) {
+			return nil
+		}
+		_, required, err := s.mfaRequired(ctx, user)
+		if err != nil {
+			return err
+		}
+		if required {
+			return ErrMFARequired
+		}
+		return nil
+	})
+	if err != nil {
+		return Token{}, err
+	}
+	return s.issue(user, token)
+}
+
+// checkAccountStatus tells whether user may have a session.
+func checkAccountStatus(user User) error {
+	switch user.Status {
+	case UserActive:
+		return nil
+	case UserPendingVerification:
+		return ErrAccountNotVerified
+	default:
+		return ErrAccountInactive
+	}
+}
+
+// issue replaces the stored token ID in token with the access token minted
+// by the configured issuer.
+func (s *adminService) issue(user User, token Token) (Token, error) {
+	p := auth.Principal{
+		UserID:       user.ID,
+		Role:         user.Role,
+		EnterpriseID: user.Enterprise(),
+		TokenID:      token.AccessToken,
+	}
+	accessToken, err := s.issuer.Issue(p, s.tokens.AccessTTL)
+	if err != nil {
+		return Token{}, err
+	}
+	token.AccessToken = accessToken
+	return token, nil
+}
+
+func (s *adminService) Logout(ctx context.Context, accessToken string) error {
+	p, err := s.issuer.Parse(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	return s.auth.RevokeAccessToken(ctx, p.TokenID)
+}
+
+func (s *adminService) LogoutAll(ctx context.Context, accessToken string) error {
+	p, err := s.issuer.Parse(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	return s.auth.RevokeAllAccessTokens(ctx, p.UserID)
+}
+
+func (s *adminService) Identify(ctx context.Context, accessToken string) (auth.Principal, error) {
+	return s.issuer.Parse(ctx, accessToken)
+}
+
+func (s *adminService) PurgeAccessTokens(ctx context.Context) (int64, error) {
+	return s.auth.DeleteDeadAccessTokens(ctx)
+}
+
+// SyncRevokedTokens refreshes the revoked tokens known to the issuer, for
+// issuers that do not look every token up. It does nothing otherwise.
+func (s *adminService) SyncRevokedTokens(ctx context.Context) (int64, error) {
+	syncer, ok := s.issuer.(RevocationSyncer)
+	if !ok {
+		return 0, nil
+	}
+	return syncer.SyncRevoked(ctx)
+}
+
+// verifyPassword checks password against the stored value with the hasher
+// that produced it, so switching PASSWORD_HASHER keeps old hashes working.
+// Rows created before passwords were hashed still hold plain text; only values
+// in no known hash format are compared directly, so a stored hash can never be
+// used as the password. Passwords not hashed by the current hasher are
+// re-hashed once they are known to be correct.
+func (s *adminService) verifyPassword(ctx context.Context, user User, password string) error {
+	var hasher PasswordHasher
+	for _, h := range append([]PasswordHasher{s.hasher}, s.hashers...) {
+		if h.Recognizes(user.Password) {
+			hasher = h
+			break
+		}
+	}
+	if hasher != nil {
+		if err := hasher.Compare(password, user.Password); err != nil {
+			return ErrInvalidCredentials
+		}
+		if hasher == s.hasher {
+			return nil
+		}
+	} else if subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) != 1 {
+		return ErrInvalidCredentials
+	}
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		s.log.Warnf("failed to rehash password of user %s: %s", user.ID, err)
+		return nil
+	}
+	if err := s.users.UpdateUser(ctx, User{ID: user.ID, Password: hash}); err != nil {
+		s.log.Warnf("failed to rehash password of user %s: %s", user.ID, err)
+	}
+	return nil
+}
+
+func (s *adminService) GetUserIDByAccessToken(accessToken string) (string, error) {
+	return s.auth.GetUserIDByAccessToken(accessToken)
+}
+
+func (s *adminService) GetUserRoleByID(userID string) (string, error) {
+	return s.auth.GetUserRoleByID(userID)
+}
+
+// Register signs a player up. The account stays pending, and cannot log in,
+// until the code sent on channel is verified.
+func (s *adminService) Register(ctx context.Context, user User, channel string) (Registration, error) {
+	for _, contact := range []string{user.Username, user.Email, user.Phone} {
+		if contact == "" {
+			continue
+		}
+		_, err := s.users.GetUserByContact(ctx, contact)
+		if err == nil {
+			return Registration{}, ErrUserExists
+		}
+		if err != ErrUserNotFound {
+			return Registration{}, err
+		}
+	}
+	hash, err := s.hasher.Hash(user.Password)
+	if err != nil {
+		return Registration{}, err
+	}
+	user.Password = hash
+	user.Role = auth.RoleEndUser
+	user.Status = UserPendingVerification
+	user.EnterpriseID = ""
+	user.ID, err = s.users.CreateUser(ctx, user)
+	if err != nil {
+		return Registration{}, err
+	}
+	return s.sendVerification(ctx, user, channel)
+}
+
+func (s *adminService) VerifyRegistration(ctx context.Context, contact string, code string) error {
+	user, err := s.users.GetUserByContact(ctx, contact)
+	if err == ErrUserNotFound || (err == nil && user.Status != UserPendingVerification) {
+		return ErrInvalidCode
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.codes.UseCode(ctx, user.ID, PurposeVerify, HashOTP(user.ID, code), OTPMaxAttempts); err != nil {
+		return err
+	}
+	return s.users.UpdateUser(ctx, User{ID: user.ID, Status: UserActive})
+}
+
+// ResendVerification succeeds quietly for contacts that are unknown or
+// already verified, so it cannot be used to find out who has an account.
+func (s *adminService) ResendVerification(ctx context.Context, contact string, channel string) error {
+	user, err := s.users.GetUserByContact(ctx, contact)
+	if err == ErrUserNotFound || (err == nil && user.Status != UserPendingVerification) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.sendVerification(ctx, user, channel)
+	return err
+}
+
+// PurgeUnverifiedUsers deletes accounts that were not verified within
+// PendingUserTTL, so their username, email and phone can be registered again.
+func (s *adminService) PurgeUnverifiedUsers(ctx context.Context) (int64, error) {
+	return s.users.DeletePendingUsers(ctx, PendingUserTTL)
+}
+
+func (s *adminService) sendVerification(ctx context.Context, user User, channel string) (Registration, error) {
+	to := address(user, channel)
+	if to == "" {
+		return Registration{}, ErrNoContact
+	}
+	code, err := GenerateOTP()
+	if err != nil {
+		return Registration{}, err
+	}
+	otp := OneTimeCode{
+		UserID:   user.ID,
+		Purpose:  PurposeVerify,
+		Channel:  channel,
+		CodeHash: HashOTP(user.ID, code),
+	}
+	created, err := s.codes.CreateCode(ctx, otp, OTPTTL, OTPResendInterval, OTPMaxPerHour)
+	if err != nil {
+		return Registration{}, err
+	}
+	msg := Message{
+		Channel: channel,
+		To:      to,
+		Subject: "Verify your VOU account",
+		Body:    fmt.Sprintf("Your VOU verification code is %s. It expires in %d minutes.", code, int(OTPTTL.Minutes())),
+	}
+	if err := s.notifier.Notify(ctx, msg); err != nil {
+		return Registration{}, err
+	}
+	return Registration{
+		UserID:      user.ID,
+		Status:      user.Status,
+		Channel:     channel,
+		ExpiresAt:   created.ExpiresAt,
+		ResendAfter: created.CreatedAt.Add(OTPResendInterval),
+	}, nil
+}
+
+// ForgotPassword sends a reset token to the user's address on channel. Like
+// ResendVerification it succeeds quietly when there is no one to send it to.
+// A throttled request succeeds quietly too, so the answer never tells whether
+// the contact belongs to an account.
+func (s *adminService) ForgotPassword(ctx context.Context, contact string, channel string) error {
+	user, err := s.users.GetUserByContact(ctx, contact)
+	if err == ErrUserNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	to := address(user, channel)
+	if to == "" {
+		return nil
+	}
+	token, err := GenerateClaimToken()
+	if err != nil {
+		return err
+	}
+	reset := OneTimeCode{
+		UserID:   user.ID,
+		Purpose:  PurposePasswordReset,
+		Channel:  channel,
+		CodeHash: HashClaimToken(token),
+	}
+	_, err = s.codes.CreateCode(ctx, reset, PasswordResetTTL, OTPResendInterval, OTPMaxPerHour)
+	if err == ErrCodeThrottled {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	msg := Message{
+		Channel: channel,
+		To:      to,
+		Subject: "Reset your VOU password",
+		Body: fmt.Sprintf("Use this token to reset your VOU password: %s\nIt expires in %d minutes. If you did not ask for it, ignore this message.",
+			token, int(PasswordResetTTL.Minutes())),
+	}
+	return s.notifier.Notify(ctx, msg)
+}
+
+// ResetPassword also logs the user out everywhere, so whoever knew the old
+// password loses their sessions.
+func (s *adminService) ResetPassword(ctx context.Context, token string, password string) error {
+	userID, err := s.codes.UseToken(ctx, PurposePasswordReset, HashClaimToken(token))
+	if err == ErrInvalidCode {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	if err := s.users.UpdateUser(ctx, User{ID: userID, Password: hash}); err != nil {
+		return err
+	}
+	return s.auth.RevokeAllAccessTokens(ctx, userID)
+}
+
+// address returns where a message on channel reaches user, or "" when they
+// have no such address.
+func address(user User, channel string) string {
+	if channel == ChannelPhone {
+		return user.Phone
+	}
+	return user.Email
+}
+
+// mfaRequired reports whether user needs a second step to log in, and whether
+// that step must first set MFA up because the role enforces it.
+func (s *adminService) mfaRequired(ctx context.Context, user User) (bool, bool, error) {
+	mfa, err := s.mfa.GetMFA(ctx, user.ID)
+	if err != nil && err != ErrMFANotEnrolled {
+		return false, false, err
+	}
+	if err == nil && mfa.Enabled() {
+		return false, true, nil
+	}
+	enforced, err := s.mfa.MFAEnforced(ctx, user.Role)
+	if err != nil {
+		return false, false, err
+	}
+	return enforced, enforced, nil
+}
+
+func (s *adminService) challenge(ctx context.Context, user User, enroll bool) (Token, error) {
+	token, err := GenerateClaimToken()
+	if err != nil {
+		return Token{}, err
+	}
+	code := OneTimeCode{
+		UserID:   user.ID,
+		Purpose:  PurposeMFAChallenge,
+		Channel:  "totp",
+		CodeHash: HashClaimToken(token),
+	}
+	if _, err := s.codes.CreateCode(ctx, code, MFAChallengeTTL, 0, MFAMaxChallengesPerHour); err != nil {
+		return Token{}, err
+	}
+	return Token{
+		MFA: &MFAChallenge{
+			Token:              token,
+			ExpiresIn:          int64(MFAChallengeTTL.Seconds()),
+			EnrollmentRequired: enroll,
+		},
+	}, nil
+}
+
+// challengedUser returns the user a live challenge was issued to.
+func (s *adminService) challengedUser(ctx context.Context, challenge string) (OneTimeCode, User, error) {
+	code, err := s.codes.GetToken(ctx, PurposeMFAChallenge, HashClaimToken(challenge), OTPMaxAttempts)
+	if err == ErrInvalidCode {
+		return OneTimeCode{}, User{}, ErrInvalidChallenge
+	}
+	if err != nil {
+		return OneTimeCode{}, User{}, err
+	}
+	user, err := s.users.GetUserById(ctx, code.UserID)
+	if err != nil {
+		return OneTimeCode{}, User{}, err
+	}
+	return code, user, nil
+}
+
+// LoginMFA is the second step of a login. A user enrolling because their
+// role enforces MFA turns it on here, and gets their recovery codes with the
+// token.
+func (s *adminService) LoginMFA(ctx context.Context, challenge string, code string) (Token, error) {
+	otp, user, err := s.challengedUser(ctx, challenge)
+	if err != nil {
+		return Token{}, err
+	}
+	mfa, err := s.mfa.GetMFA(ctx, user.ID)
+	if err != nil {
+		return Token{}, err
+	}
+	if err := s.checkMFACode(ctx, mfa, code); err != nil {
+		if err == ErrInvalidMFACode {
+			if err := s.codes.FailCode(ctx, otp.ID); err != nil {
+				s.log.Warnf("failed to count MFA attempt of user %s: %s", user.ID, err)
+			}
+		}
+		return Token{}, err
+	}
+	if _, err := s.codes.UseToken(ctx, PurposeMFAChallenge, HashClaimToken(challenge)); err != nil {
+		if err == ErrInvalidCode {
+			return Token{}, ErrInvalidChallenge
+		}
+		return Token{}, err
+	}
+
+	var recovery []string
+	if !mfa.Enabled() {
+		if recovery, err = s.enableMFA(ctx, user.ID); err != nil {
+			return Token{}, err
+		}
+	}
+	token, err := s.auth.CreateAccessToken(ctx, user.ID, true, s.tokens)
+	if err != nil {
+		return Token{}, err
+	}
+	issued, err := s.issue(user, token)
+	if err != nil {
+		return Token{}, err
+	}
+	issued.RecoveryCodes = recovery
+	return issued, nil
+}
+
+// EnrollMFALogin sets up MFA for a user whose role enforces it before they
+// could log in, using the challenge in place of an access token.
+func (s *adminService) EnrollMFALogin(ctx context.Context, challenge string) (MFAEnrollment, error) {
+	_, user, err := s.challengedUser(ctx, challenge)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	return s.enroll(ctx, user)
+}
+
+func (s *adminService) EnrollMFA(ctx context.Context) (MFAEnrollment, error) {
+	user, err := s.mfaUser(ctx)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	return s.enroll(ctx, user)
+}
+
+func (s *adminService) ConfirmMFA(ctx context.Context, code string) ([]string, error) {
+	user, err := s.mfaUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := s.mfa.GetMFA(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if err := s.checkMFACode(ctx, mfa, code); err != nil {
+		return nil, err
+	}
+	return s.enableMFA(ctx, user.ID)
+}
+
+// DisableMFA needs a TOTP or recovery code, unless the user never finished
+// enrolling.
+func (s *adminService) DisableMFA(ctx context.Context, code string) error {
+	user, err := s.mfaUser(ctx)
+	if err != nil {
+		return err
+	}
+	enforced, err := s.mfa.MFAEnforced(ctx, user.Role)
+	if err != nil {
+		return err
+	}
+	if enforced {
+		return ErrMFARequired
+	}
+	mfa, err := s.mfa.GetMFA(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if mfa.Enabled() {
+		if err := s.checkMFACode(ctx, mfa, code); err != nil {
+			return err
+		}
+	}
+	return s.mfa.DeleteMFA(ctx, user.ID)
+}
+
+func (s *adminService) RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error) {
+	user, err := s.mfaUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := s.mfa.GetMFA(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.Enabled() {
+		return nil, ErrMFANotEnrolled
+	}
+	if err := s.checkMFACode(ctx, mfa, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfa.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *adminService) GetMFAPolicies(ctx context.Context) ([]MFAPolicy, error) {
+	return s.mfa.GetMFAPolicies(ctx)
+}
+
+// SetMFAPolicy affects later logins and refreshes; access tokens already
+// issued to users without MFA stay valid until they expire.
+func (s *adminService) SetMFAPolicy(ctx context.Context, role string, enforced bool) error {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+	if !MFARole(role) {
+		return ErrMFANotAllowed
+	}
+	return s.mfa.SetMFAPolicy(ctx, MFAPolicy{Role: role, Enforced: enforced, UpdatedBy: p.UserID})
+}
+
+// mfaUser returns the calling user if their role can use MFA.
+func (s *adminService) mfaUser(ctx context.Context) (User, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return User{}, auth.ErrUnauthenticated
+	}
+	user, err := s.users.GetUserById(ctx, p.UserID)
+	if err != nil {
+		return User{}, err
+	}
+	if !MFARole(user.Role) {
+		return User{}, ErrMFANotAllowed
+	}
+	return user, nil
+}
+
+// enroll gives user a new secret. It is not used for logins until a code
+// from it has been confirmed.
+func (s *adminService) enroll(ctx context.Context, user User) (MFAEnrollment, error) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	if err := s.mfa.SaveSecret(ctx, user.ID, secret); err != nil {
+		return MFAEnrollment{}, err
+	}
+	uri := TOTPProvisioningURI(user.Username, secret)
+	png, err := s.qr.PNG(uri, 256)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	return MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: uri,
+		QRCode:          "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+func (s *adminService) enableMFA(ctx context.Context, userID string) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfa.EnableMFA(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkMFACode accepts a TOTP code of the secret, or once MFA is enabled, one
+// of the recovery codes.
+func (s *adminService) checkMFACode(ctx context.Context, mfa MFA, code string) error {
+	if step, ok := MatchTOTP(mfa.Secret, code, time.Now()); ok {
+		return s.mfa.UseStep(ctx, mfa.UserID, step)
+	}
+	if mfa.Enabled() && len(code) > TOTPDigits {
+		return s.mfa.UseRecoveryCode(ctx, mfa.UserID, HashRecoveryCode(code))
+	}
+	return ErrInvalidMFACode
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,277 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/resrrdttrt/VOU/pkg/auth"
+)
+
+type itemService interface {
+	GetItems(ctx context.Context, eventID string) ([]Item, error)
+	CreateItem(ctx context.Context, item Item) (string, error)
+	UpdateItem(ctx context.Context, item Item) error
+	GetRecipes(ctx context.Context, eventID string) ([]Recipe, error)
+	CreateRecipe(ctx context.Context, recipe Recipe) (string, error)
+	UpdateRecipe(ctx context.Context, recipe Recipe) error
+	DeleteRecipe(ctx context.Context, id string, eventID string) error
+	GetItemCatalog(ctx context.Context, eventID string) (ItemCatalog, error)
+	GetMyItems(ctx context.Context, eventID string) ([]InventoryItem, error)
+	ExchangeRecipe(ctx context.Context, eventID string, recipeID string) (Voucher, error)
+	GiftItems(ctx context.Context, eventID string, friend string, items ItemStacks) error
+	OfferItemTrade(ctx context.Context, eventID string, friend string, give ItemStacks, want ItemStacks) (ItemTrade, error)
+	GetMyItemTrades(ctx context.Context, eventID string) ([]ItemTrade, error)
+	AcceptItemTrade(ctx context.Context, id string) (ItemTrade, error)
+	DeclineItemTrade(ctx context.Context, id string) error
+	CancelItemTrade(ctx context.Context, id string) error
+}
+
+func (s *adminService) GetItems(ctx context.Context, eventID string) ([]Item, error) {
+	if _, err := s.authorizeEvent(ctx, eventID); err != nil {
+		return nil, err
+	}
+	return s.items.GetItems(ctx, eventID)
+}
+
+func (s *adminService) CreateItem(ctx context.Context, item Item) (string, error) {
+	if _, err := s.authorizeEvent(ctx, item.EventID); err != nil {
+		return "", err
+	}
+	return s.items.CreateItem(ctx, item)
+}
+
+func (s *adminService) UpdateItem(ctx context.Context, item Item) error {
+	if _, err := s.authorizeEvent(ctx, item.EventID); err != nil {
+		return err
+	}
+	return s.items.UpdateItem(ctx, item)
+}
+
+func (s *adminService) GetRecipes(ctx context.Context, eventID string) ([]Recipe, error) {
+	if _, err := s.authorizeEvent(ctx, eventID); err != nil {
+		return nil, err
+	}
+	return s.items.GetRecipes(ctx, eventID)
+}
+
+func (s *adminService) CreateRecipe(ctx context.Context, recipe Recipe) (string, error) {
+	if err := s.checkRecipe(ctx, recipe); err != nil {
+		return "", err
+	}
+	return s.items.CreateRecipe(ctx, recipe)
+}
+
+func (s *adminService) UpdateRecipe(ctx context.Context, recipe Recipe) error {
+	if err := s.checkRecipe(ctx, recipe); err != nil {
+		return err
+	}
+	return s.items.UpdateRecipe(ctx, recipe)
+}
+
+func (s *adminService) DeleteRecipe(ctx context.Context, id string, eventID string) error {
+	if _, err := s.authorizeEvent(ctx, eventID); err != nil {
+		return err
+	}
+	return s.items.DeleteRecipe(ctx, id, eventID)
+}
+
+// checkRecipe checks that the recipe pays out a template of its own event
+// and only asks for items of the event's catalog.
+func (s *adminService) checkRecipe(ctx context.Context, recipe Recipe) error {
+	event, err := s.authorizeEvent(ctx, recipe.EventID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.inventory.GetTemplateByID(ctx, recipe.TemplateID, event.ID); err != nil {
+		return err
+	}
+	return s.checkItemCodes(ctx, event.ID, recipe.Ingredients)
+}
+
+// checkItemCodes checks that every item of stacks is in the event's catalog.
+func (s *adminService) checkItemCodes(ctx context.Context, eventID string, stacks ItemStacks) error {
+	items, err := s.items.GetItems(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	codes := map[string]bool{}
+	for _, item := range items {
+		codes[item.Code] = true
+	}
+	for _, stack := range stacks {
+		if !codes[stack.ItemCode] {
+			return ErrItemNotFound
+		}
+	}
+	return nil
+}
+
+// GetItemCatalog returns the items and recipes players of the event can
+// collect and exchange.
+func (s *adminService) GetItemCatalog(ctx context.Context, eventID string) (ItemCatalog, error) {
+	event, err := s.event.GetEvent(ctx, eventID)
+	if err != nil {
+		return ItemCatalog{}, err
+	}
+	items, err := s.items.GetItems(ctx, event.ID)
+	if err != nil {
+		return ItemCatalog{}, err
+	}
+	recipes, err := s.items.GetRecipes(ctx, event.ID)
+	if err != nil {
+		return ItemCatalog{}, err
+	}
+	return ItemCatalog{Items: items, Recipes: recipes}, nil
+}
+
+func (s *adminService) GetMyItems(ctx context.Context, eventID string) ([]InventoryItem, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	return s.items.GetInventory(ctx, eventID, p.UserID)
+}
+
+// ExchangeRecipe trades the recipe's ingredients from the caller's inventory
+// for a voucher of its template. Either both happen or neither does.
+func (s *adminService) ExchangeRecipe(ctx context.Context, eventID string, recipeID string) (Voucher, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return Voucher{}, auth.ErrUnauthenticated
+	}
+	event, err := s.runningEvent(ctx, eventID)
+	if err != nil {
+		return Voucher{}, err
+	}
+	recipe, err := s.items.GetRecipe(ctx, recipeID, event.ID)
+	if err != nil {
+		return Voucher{}, err
+	}
+	code, err := GenerateVoucherCode()
+	if err != nil {
+		return Voucher{}, err
+	}
+	voucher, err := s.items.ExchangeRecipe(ctx, recipe, p.UserID, code)
+	if err != nil {
+		return Voucher{}, err
+	}
+	if err := s.signVoucher(&voucher); err != nil {
+		return Voucher{}, err
+	}
+	return voucher, nil
+}
+
+// GiftItems gives items of the caller's inventory to a friend, named by
+// username, email or phone.
+func (s *adminService) GiftItems(ctx context.Context, eventID string, friend string, items ItemStacks) error {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+	event, err := s.tradingEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	user, err := s.friend(ctx, friend, p.UserID)
+	if err != nil {
+		return err
+	}
+	return s.items.GiftItems(ctx, event.ID, p.UserID, user.ID, items)
+}
+
+// OfferItemTrade offers a friend the give items of the caller's inventory for
+// the want items of theirs. Either side may be empty, to ask for or offer
+// items for nothing.
+func (s *adminService) OfferItemTrade(ctx context.Context, eventID string, friend string, give ItemStacks, want ItemStacks) (ItemTrade, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return ItemTrade{}, auth.ErrUnauthenticated
+	}
+	event, err := s.tradingEvent(ctx, eventID)
+	if err != nil {
+		return ItemTrade{}, err
+	}
+	user, err := s.friend(ctx, friend, p.UserID)
+	if err != nil {
+		return ItemTrade{}, err
+	}
+	return s.items.CreateItemTrade(ctx, ItemTrade{
+		EventID:    event.ID,
+		FromUserID: p.UserID,
+		ToUserID:   user.ID,
+		Give:       give,
+		Want:       want,
+	})
+}
+
+// GetMyItemTrades lists the open trades the caller offered or was offered in
+// the event.
+func (s *adminService) GetMyItemTrades(ctx context.Context, eventID string) ([]ItemTrade, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	return s.items.GetPendingItemTradesByUser(ctx, eventID, p.UserID)
+}
+
+// AcceptItemTrade lets the friend offered a trade take it.
+func (s *adminService) AcceptItemTrade(ctx context.Context, id string) (ItemTrade, error) {
+	trade, err := s.myItemTrade(ctx, id, func(t ItemTrade, userID string) bool { return t.ToUserID == userID })
+	if err != nil {
+		return ItemTrade{}, err
+	}
+	if _, err := s.tradingEvent(ctx, trade.EventID); err != nil {
+		return ItemTrade{}, err
+	}
+	return s.items.AcceptItemTrade(ctx, trade.ID)
+}
+
+// DeclineItemTrade lets the friend offered a trade turn it down.
+func (s *adminService) DeclineItemTrade(ctx context.Context, id string) error {
+	trade, err := s.myItemTrade(ctx, id, func(t ItemTrade, userID string) bool { return t.ToUserID == userID })
+	if err != nil {
+		return err
+	}
+	return s.items.CloseItemTrade(ctx, trade.ID, ItemTradeDeclined)
+}
+
+// CancelItemTrade lets the player who offered a trade withdraw it.
+func (s *adminService) CancelItemTrade(ctx context.Context, id string) error {
+	trade, err := s.myItemTrade(ctx, id, func(t ItemTrade, userID string) bool { return t.FromUserID == userID })
+	if err != nil {
+		return err
+	}
+	return s.items.CloseItemTrade(ctx, trade.ID, ItemTradeCancelled)
+}
+
+// myItemTrade returns the trade if party says the caller may act on it.
+func (s *adminService) myItemTrade(ctx context.Context, id string, party func(t ItemTrade, userID string) bool) (ItemTrade, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return ItemTrade{}, auth.ErrUnauthenticated
+	}
+	trade, err := s.items.GetItemTrade(ctx, id)
+	if err != nil {
+		return ItemTrade{}, err
+	}
+	if !party(trade, p.UserID) {
+		return ItemTrade{}, ErrItemTradeNotFound
+	}
+	return trade, nil
+}
+
+// tradingEvent returns the event if it is running and its game lets players
+// trade what they win.
+func (s *adminService) tradingEvent(ctx context.Context, eventID string) (Event, error) {
+	event, err := s.runningEvent(ctx, eventID)
+	if err != nil {
+		return Event{}, err
+	}
+	game, err := s.games.GetGameById(ctx, event.GameID)
+	if err != nil {
+		return Event{}, err
+	}
+	if !game.ExchangeAllow {
+		return Event{}, ErrItemExchangeNotAllowed
+	}
+	return event, nil
+}
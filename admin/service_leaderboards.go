@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"github.com/resrrdttrt/VOU/pkg/auth"
+)
+
+type leaderboardService interface {
+	GetLeaderboard(ctx context.Context, q LeaderboardQuery) (Leaderboard, error)
+	GetMyRank(ctx context.Context, q LeaderboardQuery) (Leaderboard, error)
+	GetStandings(ctx context.Context, eventID string, limit int, offset int) (Standings, error)
+	GetEventLeaderboard(ctx context.Context, q LeaderboardQuery) (Leaderboard, error)
+	GetEventStandings(ctx context.Context, eventID string, limit int, offset int) (Standings, error)
+	FreezeStandings(ctx context.Context) (int64, error)
+}
+
+// GetLeaderboard returns the top of a board of the event.
+func (s *adminService) GetLeaderboard(ctx context.Context, q LeaderboardQuery) (Leaderboard, error) {
+	board, lb, err := s.leaderboard(ctx, q)
+	if err != nil {
+		return Leaderboard{}, err
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefLeaderboardLimit
+	}
+	if limit > MaxLeaderboardLimit {
+		limit = MaxLeaderboardLimit
+	}
+	lb.Rows, err = s.scores.GetTop(ctx, board, limit)
+	if err != nil {
+		return Leaderboard{}, err
+	}
+	return lb, nil
+}
+
+// GetMyRank returns the caller's place on a board of the event, with the
+// players just ahead of and behind them.
+func (s *adminService) GetMyRank(ctx context.Context, q LeaderboardQuery) (Leaderboard, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return Leaderboard{}, auth.ErrUnauthenticated
+	}
+	board, lb, err := s.leaderboard(ctx, q)
+	if err != nil {
+		return Leaderboard{}, err
+	}
+	radius := q.Radius
+	if radius <= 0 {
+		radius = DefLeaderboardRadius
+	}
+	if radius > MaxLeaderboardRadius {
+		radius = MaxLeaderboardRadius
+	}
+	me, rows, err := s.scores.GetAround(ctx, board, p.UserID, radius)
+	if err != nil {
+		return Leaderboard{}, err
+	}
+	lb.Me = &me
+	lb.Rows = rows
+	return lb, nil
+}
+
+// GetStandings returns a page of the final standings of an ended event.
+func (s *adminService) GetStandings(ctx context.Context, eventID string, limit int, offset int) (Standings, error) {
+	if limit <= 0 {
+		limit = DefStandingsLimit
+	}
+	if limit > MaxStandingsLimit {
+		limit = MaxStandingsLimit
+	}
+	return s.scores.GetStandings(ctx, eventID, limit, offset)
+}
+
+func (s *adminService) GetEventLeaderboard(ctx context.Context, q LeaderboardQuery) (Leaderboard, error) {
+	if _, err := s.authorizeEvent(ctx, q.EventID); err != nil {
+		return Leaderboard{}, err
+	}
+	return s.GetLeaderboard(ctx, q)
+}
+
+func (s *adminService) GetEventStandings(ctx context.Context, eventID string, limit int, offset int) (Standings, error) {
+	if _, err := s.authorizeEvent(ctx, eventID); err != nil {
+		return Standings{}, err
+	}
+	return s.GetStandings(ctx, eventID, limit, offset)
+}
+
+// FreezeStandings takes the final standings of events that have ended.
+func (s *adminService) FreezeStandings(ctx context.Context) (int64, error) {
+	return s.scores.FreezeStandings(ctx)
+}
+
+// leaderboard resolves the board q asks for. The all-time board is the
+// game's, shared by all its events.
+func (s *adminService) leaderboard(ctx context.Context, q LeaderboardQuery) (Board, Leaderboard, error) {
+	event, err := s.event.GetEvent(ctx, q.EventID)
+	if err != nil {
+		return Board{}, Leaderboard{}, err
+	}
+	day := q.Date
+	if day.IsZero() {
+		day = time.Now().UTC()
+	}
+	board := Board{ScopeID: event.ID, Period: q.Period, Start: PeriodStart(q.Period, day)}
+	if q.Period == PeriodAllTime {
+		board.ScopeID = event.GameID
+	}
+	lb := Leaderboard{EventID: event.ID, GameID: event.GameID, Period: q.Period}
+	if !board.Start.IsZero() {
+		lb.PeriodStart = &board.Start
+	}
+	return board, lb, nil
+}
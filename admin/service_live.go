@@ -0,0 +1,337 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/resrrdttrt/VOU/pkg/auth"
+)
+
+type liveService interface {
+	OpenLiveQuizzes(ctx context.Context) (int64, error)
+	JoinLiveQuiz(ctx context.Context, eventID string) (<-chan LiveMessage, func(), error)
+	AnswerLiveQuiz(ctx context.Context, eventID string, round int, choices ChoiceIDs) (QuizAnswer, error)
+	GetLiveLeaderboard(ctx context.Context, eventID string) ([]LiveScore, error)
+	// LiveQuizHost returns the URL of the replica hosting the event's
+	// session, or "" when this replica hosts it or none does.
+	LiveQuizHost(ctx context.Context, eventID string) (string, error)
+	// RecoverLiveQuizzes releases or finishes the sessions whose host
+	// stopped renewing its claim.
+	RecoverLiveQuizzes(ctx context.Context) (int64, error)
+}
+
+// OpenLiveQuizzes opens the sessions of live quiz events whose start time
+// has come and hosts each in the background.
+func (s *adminService) OpenLiveQuizzes(ctx context.Context) (int64, error) {
+	events, err := s.quiz.ClaimLiveQuizEvents(ctx, s.replica)
+	if err != nil {
+		return 0, err
+	}
+	var opened int64
+	for _, event := range events {
+		questions, err := s.quiz.GetQuestionsBySetID(ctx, event.QuestionSetID)
+		if err != nil {
+			s.log.Error(fmt.Sprintf("Failed to load questions of live quiz %s: %s", event.ID, err))
+			continue
+		}
+		if len(questions) == 0 {
+			s.log.Warn(fmt.Sprintf("Live quiz %s has no questions, not hosting it", event.ID))
+			continue
+		}
+		now := time.Now()
+		session := LiveSession{
+			EventID:        event.ID,
+			SetID:          event.QuestionSetID,
+			Status:         LiveLobby,
+			Questions:      questions,
+			OpenedAt:       now,
+			RoundStartedAt: now,
+			Deadline:       now.Add(LiveCountdown),
+		}
+		if err := s.live.CreateSession(ctx, session); err != nil {
+			s.log.Error(fmt.Sprintf("Failed to open live quiz %s: %s", event.ID, err))
+			continue
+		}
+		go s.hostLiveQuiz(event, session)
+		opened++
+	}
+	return opened, nil
+}
+
+// hostLiveQuiz runs a session: a countdown, then each question open for its
+// time limit followed by the leaderboard, then prizes for the top scorers.
+// Everyone connected gets each question at the same moment, and answers are
+// only accepted until its deadline. The claim on the session is renewed
+// meanwhile; once it is lost the session is abandoned to the replica that
+// recovered it.
+func (s *adminService) hostLiveQuiz(event Event, session LiveSession) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.keepLiveQuizClaimed(ctx, cancel, event.ID)
+
+	session, ok := s.playLiveQuiz(ctx, event, session)
+	cancel()
+	if !ok {
+		s.abandonLiveQuiz(event.ID)
+		return
+	}
+
+	ctx = context.Background()
+	board, err := s.live.Leaderboard(ctx, event.ID)
+	if err != nil {
+		s.log.Error(fmt.Sprintf("Failed to rank live quiz %s: %s", event.ID, err))
+		s.abandonLiveQuiz(event.ID)
+		return
+	}
+	attempts := make([]QuizAttempt, len(board))
+	for i, score := range board {
+		attempts[i] = QuizAttempt{
+			UserID:  score.UserID,
+			SetID:   session.SetID,
+			Total:   len(session.Questions),
+			Score:   score.Score,
+			Correct: score.Correct,
+		}
+	}
+	// Prizes are only awarded by the replica that gets to finish the
+	// session, so a recovered session is never rewarded twice.
+	if err := s.quiz.FinishLiveQuiz(ctx, event.ID, s.replica, attempts); err != nil {
+		s.log.Error(fmt.Sprintf("Failed to save results of live quiz %s: %s", event.ID, err))
+		s.abandonLiveQuiz(event.ID)
+		return
+	}
+	prizes := s.awardLivePrizes(ctx, event, board)
+	s.pushLeaderboard(session, LiveMsgFinished, board, prizes)
+
+	time.AfterFunc(LiveRetention, func() {
+		s.live.DeleteSession(context.Background(), event.ID)
+	})
+}
+
+// playLiveQuiz runs the rounds of a session and leaves it finished. It
+// reports false when ctx is cancelled or the session cannot be updated.
+func (s *adminService) playLiveQuiz(ctx context.Context, event Event, session LiveSession) (LiveSession, bool) {
+	s.hub.Broadcast(event.ID, liveStateMessage(session))
+	if !sleepUntil(ctx, session.Deadline) {
+		return session, false
+	}
+
+	for i, q := range session.Questions {
+		now := time.Now()
+		session.Status = LiveQuestion
+		session.Round = i
+		session.RoundStartedAt = now
+		session.Deadline = now.Add(time.Duration(q.TimeLimit) * time.Second)
+		if err := s.live.UpdateSession(ctx, session); err != nil {
+			s.log.Error(fmt.Sprintf("Failed to open round %d of live quiz %s: %s", i, event.ID, err))
+			return session, false
+		}
+		s.hub.Broadcast(event.ID, liveStateMessage(session))
+		if !sleepUntil(ctx, session.Deadline) {
+			return session, false
+		}
+
+		if i == len(session.Questions)-1 {
+			break
+		}
+		now = time.Now()
+		session.Status = LiveReveal
+		session.RoundStartedAt = now
+		session.Deadline = now.Add(LiveRevealTime)
+		if err := s.live.UpdateSession(ctx, session); err != nil {
+			s.log.Error(fmt.Sprintf("Failed to close round %d of live quiz %s: %s", i, event.ID, err))
+			return session, false
+		}
+		if board, err := s.live.Leaderboard(ctx, event.ID); err == nil {
+			s.pushLeaderboard(session, LiveMsgLeaderboard, board, nil)
+		}
+		if !sleepUntil(ctx, session.Deadline) {
+			return session, false
+		}
+	}
+
+	session.Status = LiveFinished
+	session.RoundStartedAt = time.Now()
+	session.Deadline = session.RoundStartedAt
+	if err := s.live.UpdateSession(ctx, session); err != nil {
+		s.log.Error(fmt.Sprintf("Failed to finish live quiz %s: %s", event.ID, err))
+		return session, false
+	}
+	return session, true
+}
+
+// keepLiveQuizClaimed renews the claim on the event's session every
+// LiveHeartbeat until ctx is done, and calls stop once the claim is lost.
+func (s *adminService) keepLiveQuizClaimed(ctx context.Context, stop context.CancelFunc, eventID string) {
+	ticker := time.NewTicker(LiveHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := s.quiz.HeartbeatLiveQuiz(ctx, eventID, s.replica)
+		switch {
+		case err == nil:
+		case err == ErrLiveSessionLost:
+			s.log.Warn(fmt.Sprintf("Live quiz %s was recovered by another replica, stopping", eventID))
+			stop()
+			return
+		case ctx.Err() == nil:
+			s.log.Error(fmt.Sprintf("Failed to renew live quiz %s: %s", eventID, err))
+		}
+	}
+}
+
+// abandonLiveQuiz tells the connected players the session was interrupted
+// and drops it from this replica.
+func (s *adminService) abandonLiveQuiz(eventID string) {
+	s.hub.Broadcast(eventID, LiveMessage{Type: LiveMsgError, EventID: eventID, Error: "live session was interrupted"})
+	s.live.DeleteSession(context.Background(), eventID)
+}
+
+// sleepUntil waits until t and reports false if ctx is done first.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// awardLivePrizes issues the prize of each rank to the player holding it.
+// Players without points win nothing, and a prize out of stock is skipped.
+func (s *adminService) awardLivePrizes(ctx context.Context, event Event, board []LiveScore) []LivePrize {
+	prizes := []LivePrize{}
+	for i, templateID := range event.QuizPrizes {
+		if i >= len(board) || board[i].Score == 0 {
+			break
+		}
+		code, err := GenerateVoucherCode()
+		if err != nil {
+			s.log.Error(fmt.Sprintf("Failed to generate prize code for live quiz %s: %s", event.ID, err))
+			continue
+		}
+		voucher, err := s.inventory.IssueVoucher(ctx, templateID, event.ID, board[i].UserID, code)
+		if err != nil {
+			s.log.Error(fmt.Sprintf("Failed to issue prize %d of live quiz %s: %s", i+1, event.ID, err))
+			continue
+		}
+		prizes = append(prizes, LivePrize{
+			Rank:       board[i].Rank,
+			UserID:     board[i].UserID,
+			TemplateID: templateID,
+			VoucherID:  voucher.ID,
+		})
+	}
+	return prizes
+}
+
+// pushLeaderboard sends every player the top of the board along with their
+// own standing.
+func (s *adminService) pushLeaderboard(session LiveSession, kind string, board []LiveScore, prizes []LivePrize) {
+	top := board
+	if len(top) > LiveLeaderboardSize {
+		top = top[:LiveLeaderboardSize]
+	}
+	for i := range board {
+		msg := liveStateMessage(session)
+		msg.Type = kind
+		msg.Leaderboard = top
+		msg.Me = &board[i]
+		msg.Prizes = prizes
+		s.hub.Send(session.EventID, board[i].UserID, msg)
+	}
+}
+
+// JoinLiveQuiz adds the caller to the event's open session. The returned
+// channel carries the session's messages, starting with its current state,
+// until the returned function is called.
+func (s *adminService) JoinLiveQuiz(ctx context.Context, eventID string) (<-chan LiveMessage, func(), error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, nil, auth.ErrUnauthenticated
+	}
+	session, err := s.live.GetSession(ctx, eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session.Status != LiveFinished {
+		if err := s.live.Join(ctx, eventID, p.UserID); err != nil {
+			return nil, nil, err
+		}
+	}
+	msgs, leave := s.hub.Subscribe(eventID, p.UserID)
+	// Read the state again: a round may have opened while subscribing.
+	if session, err = s.live.GetSession(ctx, eventID); err != nil {
+		leave()
+		return nil, nil, err
+	}
+	s.hub.Send(eventID, p.UserID, liveStateMessage(session))
+	return msgs, leave, nil
+}
+
+// AnswerLiveQuiz grades the caller's answer to round on the server clock.
+// The points reach the event's leaderboards when the session finishes.
+func (s *adminService) AnswerLiveQuiz(ctx context.Context, eventID string, round int, choices ChoiceIDs) (QuizAnswer, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return QuizAnswer{}, auth.ErrUnauthenticated
+	}
+	session, err := s.live.GetSession(ctx, eventID)
+	if err != nil {
+		return QuizAnswer{}, err
+	}
+	now := time.Now()
+	if !session.Accepts(round, now) {
+		return QuizAnswer{}, ErrAnswerWindowClosed
+	}
+	answer := session.Questions[round].Grade(choices, now.Sub(session.RoundStartedAt))
+	if err := s.live.RecordAnswer(ctx, eventID, p.UserID, round, answer); err != nil {
+		return QuizAnswer{}, err
+	}
+	return answer, nil
+}
+
+func (s *adminService) GetLiveLeaderboard(ctx context.Context, eventID string) ([]LiveScore, error) {
+	return s.live.Leaderboard(ctx, eventID)
+}
+
+func (s *adminService) LiveQuizHost(ctx context.Context, eventID string) (string, error) {
+	host, err := s.quiz.GetLiveQuizHost(ctx, eventID)
+	if err != nil || host == s.replica {
+		return "", err
+	}
+	return host, nil
+}
+
+// RecoverLiveQuizzes lets sessions whose host died be claimed again while
+// their event runs, and closes the others.
+func (s *adminService) RecoverLiveQuizzes(ctx context.Context) (int64, error) {
+	return s.quiz.RecoverLiveQuizzes(ctx, LiveHostTimeout)
+}
+
+// liveStateMessage describes the session as a player joining now would need
+// to see it. The question is only included while it is open.
+func liveStateMessage(session LiveSession) LiveMessage {
+	deadline := session.Deadline
+	msg := LiveMessage{
+		Type:     LiveMsgState,
+		EventID:  session.EventID,
+		Status:   session.Status,
+		Round:    session.Round,
+		Total:    len(session.Questions),
+		Deadline: &deadline,
+	}
+	if session.Status == LiveQuestion {
+		q := session.Questions[session.Round].ForPlayer()
+		msg.Type = LiveMsgQuestion
+		msg.Question = &q
+	}
+	return msg
+}
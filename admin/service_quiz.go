@@ -0,0 +1,258 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"github.com/resrrdttrt/VOU/pkg/auth"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+type quizService interface {
+	GetQuestionSets(ctx context.Context) ([]QuestionSet, error)
+	GetQuestionSet(ctx context.Context, id string) (QuestionSet, error)
+	CreateQuestionSet(ctx context.Context, set QuestionSet) (string, error)
+	UpdateQuestionSet(ctx context.Context, set QuestionSet) error
+	CreateQuestion(ctx context.Context, question Question) (string, error)
+	UpdateQuestion(ctx context.Context, question Question) error
+	DeleteQuestion(ctx context.Context, id string, setID string) error
+	AttachQuestionSet(ctx context.Context, eventID string, setID string, mode string, prizes QuizPrizes) error
+	StartQuiz(ctx context.Context, eventID string) (QuizState, error)
+	GetQuiz(ctx context.Context, eventID string) (QuizState, error)
+	AnswerQuiz(ctx context.Context, eventID string, questionID string, choices ChoiceIDs) (QuizState, error)
+	GetQuizAnswers(ctx context.Context, eventID string) ([]QuizAnswer, error)
+}
+
+func (s *adminService) GetQuestionSets(ctx context.Context) ([]QuestionSet, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	return s.quiz.GetQuestionSetsByEnterpriseID(ctx, p.EnterpriseID)
+}
+
+// GetQuestionSet returns the set with its questions, correct answers
+// included; players only ever see questions through the quiz calls.
+func (s *adminService) GetQuestionSet(ctx context.Context, id string) (QuestionSet, error) {
+	set, err := s.authorizeQuestionSet(ctx, id, false)
+	if err != nil {
+		return QuestionSet{}, err
+	}
+	set.Questions, err = s.quiz.GetQuestionsBySetID(ctx, id)
+	if err != nil {
+		return QuestionSet{}, err
+	}
+	return set, nil
+}
+
+func (s *adminService) CreateQuestionSet(ctx context.Context, set QuestionSet) (string, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return "", auth.ErrUnauthenticated
+	}
+	if p.EnterpriseID == "" {
+		return "", errors.ErrForbidden
+	}
+	set.EnterpriseID = p.EnterpriseID
+	return s.quiz.CreateQuestionSet(ctx, set)
+}
+
+func (s *adminService) UpdateQuestionSet(ctx context.Context, set QuestionSet) error {
+	if _, err := s.authorizeQuestionSet(ctx, set.ID, false); err != nil {
+		return err
+	}
+	return s.quiz.UpdateQuestionSet(ctx, set)
+}
+
+func (s *adminService) CreateQuestion(ctx context.Context, question Question) (string, error) {
+	if _, err := s.authorizeQuestionSet(ctx, question.SetID, true); err != nil {
+		return "", err
+	}
+	return s.quiz.CreateQuestion(ctx, question)
+}
+
+func (s *adminService) UpdateQuestion(ctx context.Context, question Question) error {
+	if _, err := s.authorizeQuestionSet(ctx, question.SetID, true); err != nil {
+		return err
+	}
+	if _, err := s.quiz.GetQuestion(ctx, question.ID, question.SetID); err != nil {
+		return err
+	}
+	return s.quiz.UpdateQuestion(ctx, question)
+}
+
+func (s *adminService) DeleteQuestion(ctx context.Context, id string, setID string) error {
+	if _, err := s.authorizeQuestionSet(ctx, setID, true); err != nil {
+		return err
+	}
+	return s.quiz.DeleteQuestion(ctx, id, setID)
+}
+
+// AttachQuestionSet makes a quiz event play the set in mode. Prizes name
+// voucher templates of the event, handed out by rank at the end of a live
+// session. None of it can change once the event is running.
+func (s *adminService) AttachQuestionSet(ctx context.Context, eventID string, setID string, mode string, prizes QuizPrizes) error {
+	event, err := s.authorizeEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	game, err := s.games.GetGameById(ctx, event.GameID)
+	if err != nil {
+		return err
+	}
+	if !IsQuizGame(game.Type) {
+		return ErrNotQuizGame
+	}
+	set, err := s.authorizeQuestionSet(ctx, setID, false)
+	if err != nil {
+		return err
+	}
+	if set.EnterpriseID != event.UserID {
+		return ErrQuestionSetNotFound
+	}
+	for _, templateID := range prizes {
+		if _, err := s.inventory.GetTemplateByID(ctx, templateID, eventID); err != nil {
+			return err
+		}
+	}
+	return s.quiz.AttachQuestionSet(ctx, eventID, setID, mode, prizes)
+}
+
+// StartQuiz starts the caller's attempt at a running quiz event and shows the
+// first question. Starting again resumes the attempt where it was; the clock
+// of the question on screen keeps running.
+func (s *adminService) StartQuiz(ctx context.Context, eventID string) (QuizState, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return QuizState{}, auth.ErrUnauthenticated
+	}
+	event, questions, err := s.quizEvent(ctx, eventID)
+	if err != nil {
+		return QuizState{}, err
+	}
+	attempt, err := s.quiz.StartQuizAttempt(ctx, QuizAttempt{
+		EventID: event.ID,
+		UserID:  p.UserID,
+		SetID:   event.QuestionSetID,
+		Total:   len(questions),
+	})
+	if err != nil {
+		return QuizState{}, err
+	}
+	return quizState(attempt, questions, nil), nil
+}
+
+func (s *adminService) GetQuiz(ctx context.Context, eventID string) (QuizState, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return QuizState{}, auth.ErrUnauthenticated
+	}
+	attempt, err := s.quiz.GetQuizAttempt(ctx, eventID, p.UserID)
+	if err != nil {
+		return QuizState{}, err
+	}
+	if attempt.FinishedAt != nil {
+		return quizState(attempt, nil, nil), nil
+	}
+	questions, err := s.quiz.GetQuestionsBySetID(ctx, attempt.SetID)
+	if err != nil {
+		return QuizState{}, err
+	}
+	return quizState(attempt, questions, nil), nil
+}
+
+// AnswerQuiz grades the caller's answer to the question on screen and shows
+// the next one. The grade only says whether the answer was right, never what
+// the right choices were. Empty choices skip the question.
+func (s *adminService) AnswerQuiz(ctx context.Context, eventID string, questionID string, choices ChoiceIDs) (QuizState, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return QuizState{}, auth.ErrUnauthenticated
+	}
+	event, questions, err := s.quizEvent(ctx, eventID)
+	if err != nil {
+		return QuizState{}, err
+	}
+	attempt, answer, err := s.quiz.AnswerQuizQuestion(ctx, event.ID, p.UserID, func(a QuizAttempt, elapsed time.Duration) (QuizAnswer, error) {
+		if a.Position >= len(questions) || questions[a.Position].ID != questionID {
+			return QuizAnswer{}, ErrWrongQuestion
+		}
+		return questions[a.Position].Grade(choices, elapsed), nil
+	})
+	if err != nil {
+		return QuizState{}, err
+	}
+	return quizState(attempt, questions, &answer), nil
+}
+
+func (s *adminService) GetQuizAnswers(ctx context.Context, eventID string) ([]QuizAnswer, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	attempt, err := s.quiz.GetQuizAttempt(ctx, eventID, p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.quiz.GetQuizAnswers(ctx, attempt.ID)
+}
+
+// quizEvent returns a running quiz event and the questions it plays.
+func (s *adminService) quizEvent(ctx context.Context, eventID string) (Event, []Question, error) {
+	event, err := s.event.GetEvent(ctx, eventID)
+	if err != nil {
+		return Event{}, nil, err
+	}
+	if event.Status != EventRunning {
+		return Event{}, nil, ErrEventNotRunning
+	}
+	if event.QuestionSetID == "" {
+		return Event{}, nil, ErrNoQuestionSet
+	}
+	if event.QuizMode == QuizLive {
+		return Event{}, nil, ErrQuizIsLive
+	}
+	questions, err := s.quiz.GetQuestionsBySetID(ctx, event.QuestionSetID)
+	if err != nil {
+		return Event{}, nil, err
+	}
+	return event, questions, nil
+}
+
+func quizState(attempt QuizAttempt, questions []Question, answer *QuizAnswer) QuizState {
+	state := QuizState{Attempt: attempt, Answer: answer}
+	if attempt.FinishedAt == nil && attempt.ServedAt != nil && attempt.Position < len(questions) {
+		q := questions[attempt.Position].ForPlayer()
+		deadline := attempt.ServedAt.Add(time.Duration(q.TimeLimit) * time.Second)
+		state.Question = &q
+		state.Deadline = &deadline
+	}
+	return state
+}
+
+// authorizeQuestionSet checks that the set belongs to the caller's
+// enterprise, the same way authorizeEvent does for events. Sets about to be
+// changed must not be in play.
+func (s *adminService) authorizeQuestionSet(ctx context.Context, id string, change bool) (QuestionSet, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return QuestionSet{}, auth.ErrUnauthenticated
+	}
+	set, err := s.quiz.GetQuestionSet(ctx, id)
+	if err != nil {
+		return QuestionSet{}, err
+	}
+	if p.Role != auth.RoleAdmin && (p.EnterpriseID == "" || p.EnterpriseID != set.EnterpriseID) {
+		return QuestionSet{}, ErrQuestionSetNotFound
+	}
+	if change {
+		inPlay, err := s.quiz.QuestionSetInPlay(ctx, id)
+		if err != nil {
+			return QuestionSet{}, err
+		}
+		if inPlay {
+			return QuestionSet{}, ErrQuestionSetLocked
+		}
+	}
+	return set, nil
+}
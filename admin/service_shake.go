@@ -0,0 +1,151 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/resrrdttrt/VOU/pkg/auth"
+)
+
+type drawService interface {
+	GetPrizeTable(ctx context.Context, eventID string) (PrizeTable, error)
+	SetPrizeTable(ctx context.Context, table PrizeTable) error
+	Shake(ctx context.Context, eventID string) (ShakeResult, error)
+	GetShake(ctx context.Context, eventID string) (ShakeState, error)
+	GetDraws(ctx context.Context, eventID string) ([]Draw, error)
+	AuditDraws(ctx context.Context, eventID string) (DrawAudit, error)
+}
+
+func (s *adminService) GetPrizeTable(ctx context.Context, eventID string) (PrizeTable, error) {
+	event, err := s.authorizeEvent(ctx, eventID)
+	if err != nil {
+		return PrizeTable{}, err
+	}
+	prizes, err := s.draws.GetPrizes(ctx, eventID)
+	if err != nil {
+		return PrizeTable{}, err
+	}
+	return PrizeTable{EventID: eventID, Turns: event.PlayTurns, Prizes: prizes}, nil
+}
+
+// SetPrizeTable replaces the prize table of an event of a shake game. Voucher
+// prizes must come from the event's own templates and item prizes from its
+// item catalog.
+func (s *adminService) SetPrizeTable(ctx context.Context, table PrizeTable) error {
+	event, err := s.authorizeEvent(ctx, table.EventID)
+	if err != nil {
+		return err
+	}
+	game, err := s.games.GetGameById(ctx, event.GameID)
+	if err != nil {
+		return err
+	}
+	if !IsShakeGame(game.Type) {
+		return ErrNotShakeGame
+	}
+	var items ItemStacks
+	for _, prize := range table.Prizes {
+		switch prize.Kind {
+		case PrizeVoucher:
+			if _, err := s.inventory.GetTemplateByID(ctx, prize.TemplateID, event.ID); err != nil {
+				return err
+			}
+		case PrizeItem:
+			items = append(items, ItemStack{ItemCode: prize.ItemCode, Quantity: 1})
+		}
+	}
+	if err := s.checkItemCodes(ctx, event.ID, items); err != nil {
+		return err
+	}
+	return s.draws.ReplacePrizeTable(ctx, event.ID, table.Turns, table.Prizes)
+}
+
+// Shake spends one of the caller's turns at a running shake event and draws a
+// prize.
+func (s *adminService) Shake(ctx context.Context, eventID string) (ShakeResult, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return ShakeResult{}, auth.ErrUnauthenticated
+	}
+	event, err := s.shakeEvent(ctx, eventID)
+	if err != nil {
+		return ShakeResult{}, err
+	}
+	code, err := GenerateVoucherCode()
+	if err != nil {
+		return ShakeResult{}, err
+	}
+	draw, err := s.draws.Draw(ctx, event.ID, p.UserID, code, Roll)
+	if err != nil {
+		return ShakeResult{}, err
+	}
+	left, err := s.turns.GetBalance(ctx, event.ID, p.UserID)
+	if err != nil {
+		return ShakeResult{}, err
+	}
+	return ShakeResult{Draw: draw, TurnsLeft: left}, nil
+}
+
+func (s *adminService) GetShake(ctx context.Context, eventID string) (ShakeState, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return ShakeState{}, auth.ErrUnauthenticated
+	}
+	event, err := s.shakeEvent(ctx, eventID)
+	if err != nil {
+		return ShakeState{}, err
+	}
+	mine, err := s.draws.GetDrawsByUser(ctx, event.ID, p.UserID)
+	if err != nil {
+		return ShakeState{}, err
+	}
+	left, err := s.turns.GetBalance(ctx, event.ID, p.UserID)
+	if err != nil {
+		return ShakeState{}, err
+	}
+	return ShakeState{
+		EventID:   event.ID,
+		Turns:     event.PlayTurns,
+		TurnsLeft: left,
+		Draws:     mine,
+	}, nil
+}
+
+func (s *adminService) GetDraws(ctx context.Context, eventID string) ([]Draw, error) {
+	if _, err := s.authorizeEvent(ctx, eventID); err != nil {
+		return nil, err
+	}
+	return s.draws.GetDraws(ctx, eventID)
+}
+
+// AuditDraws replays the event's draw log to check that it is intact and
+// that every prize was won fairly.
+func (s *adminService) AuditDraws(ctx context.Context, eventID string) (DrawAudit, error) {
+	if _, err := s.authorizeEvent(ctx, eventID); err != nil {
+		return DrawAudit{}, err
+	}
+	prizes, err := s.draws.GetPrizes(ctx, eventID)
+	if err != nil {
+		return DrawAudit{}, err
+	}
+	draws, err := s.draws.GetDraws(ctx, eventID)
+	if err != nil {
+		return DrawAudit{}, err
+	}
+	return ReplayDraws(eventID, prizes, draws), nil
+}
+
+// shakeEvent returns the event if it plays a shake game.
+func (s *adminService) shakeEvent(ctx context.Context, eventID string) (Event, error) {
+	event, err := s.event.GetEvent(ctx, eventID)
+	if err != nil {
+		return Event{}, err
+	}
+	game, err := s.games.GetGameById(ctx, event.GameID)
+	if err != nil {
+		return Event{}, err
+	}
+	if !IsShakeGame(game.Type) {
+		return Event{}, ErrNotShakeGame
+	}
+	return event, nil
+}
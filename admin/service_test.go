@@ -0,0 +1,900 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/resrrdttrt/VOU/pkg/auth"
+	log "github.com/resrrdttrt/VOU/pkg/logger"
+)
+
+type fakeEventRepository struct {
+	EventRepository
+	events map[string]Event
+}
+
+func (r *fakeEventRepository) GetEvent(ctx context.Context, id string) (Event, error) {
+	event, ok := r.events[id]
+	if !ok {
+		return Event{}, ErrEventNotFound
+	}
+	return event, nil
+}
+
+func (r *fakeEventRepository) UpdateEvent(ctx context.Context, id string, update func(current Event) (Event, error)) error {
+	current, ok := r.events[id]
+	if !ok {
+		return ErrEventNotFound
+	}
+	changes, err := update(current)
+	if err != nil {
+		return err
+	}
+	if changes.Name != "" {
+		current.Name = changes.Name
+	}
+	if changes.VoucherNum != 0 {
+		current.VoucherNum = changes.VoucherNum
+	}
+	if changes.Status != "" {
+		current.Status = changes.Status
+	}
+	r.events[id] = current
+	return nil
+}
+
+func (r *fakeEventRepository) UpdateEventStatus(ctx context.Context, id, from, to, reason string) error {
+	event := r.events[id]
+	event.Status = to
+	r.events[id] = event
+	return nil
+}
+
+type fakeVoucherRepository struct {
+	VoucherRepository
+	vouchers map[string]Voucher
+}
+
+func (r *fakeVoucherRepository) GetAllVouchersByEventID(ctx context.Context, eventID string) ([]Voucher, error) {
+	vouchers := []Voucher{}
+	for _, v := range r.vouchers {
+		if v.EventID == eventID {
+			vouchers = append(vouchers, v)
+		}
+	}
+	return vouchers, nil
+}
+
+func (r *fakeVoucherRepository) GetVoucherByID(ctx context.Context, id string, eventID string) (Voucher, error) {
+	v, ok := r.vouchers[id]
+	if !ok || v.EventID != eventID {
+		return Voucher{}, ErrVoucherNotFound
+	}
+	return v, nil
+}
+
+func (r *fakeVoucherRepository) CreateVoucher(ctx context.Context, voucher Voucher) error {
+	r.vouchers[voucher.ID] = voucher
+	return nil
+}
+
+func (r *fakeVoucherRepository) UpdateVoucher(ctx context.Context, voucher Voucher) error {
+	r.vouchers[voucher.ID] = voucher
+	return nil
+}
+
+func (r *fakeVoucherRepository) DeleteVoucher(ctx context.Context, id string, eventID string) error {
+	delete(r.vouchers, id)
+	return nil
+}
+
+type fakeInventoryRepository struct {
+	InventoryRepository
+	templates map[string]VoucherTemplate
+}
+
+func (r *fakeInventoryRepository) GetTemplatesByEventID(ctx context.Context, eventID string) ([]VoucherTemplate, error) {
+	templates := []VoucherTemplate{}
+	for _, t := range r.templates {
+		if t.EventID == eventID {
+			templates = append(templates, t)
+		}
+	}
+	return templates, nil
+}
+
+func (r *fakeInventoryRepository) GetTemplateByID(ctx context.Context, id string, eventID string) (VoucherTemplate, error) {
+	t, ok := r.templates[id]
+	if !ok || t.EventID != eventID {
+		return VoucherTemplate{}, ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+func (r *fakeInventoryRepository) CreateTemplate(ctx context.Context, template VoucherTemplate) (string, error) {
+	template.ID = "template-new"
+	r.templates[template.ID] = template
+	return template.ID, nil
+}
+
+func (r *fakeInventoryRepository) UpdateTemplateQuantity(ctx context.Context, id string, eventID string, quantity int) error {
+	t := r.templates[id]
+	t.Quantity = quantity
+	r.templates[id] = t
+	return nil
+}
+
+func (r *fakeInventoryRepository) IssueVoucher(ctx context.Context, templateID string, eventID string, userID string, code string) (Voucher, error) {
+	return Voucher{ID: "voucher-issued", EventID: eventID, Code: code}, nil
+}
+
+type fakeVoucherSigner struct {
+	VoucherSigner
+}
+
+func (fakeVoucherSigner) Sign(claims VoucherClaims) (string, error) {
+	data, err := json.Marshal(claims)
+	return string(data), err
+}
+
+func (fakeVoucherSigner) Verify(payload string) (VoucherClaims, error) {
+	var claims VoucherClaims
+	if err := json.Unmarshal([]byte(payload), &claims); err != nil {
+		return VoucherClaims{}, ErrMalformedVoucherPayload
+	}
+	return claims, nil
+}
+
+func newTenantTestService() *adminService {
+	return &adminService{
+		event: &fakeEventRepository{events: map[string]Event{
+			"event-b": {ID: "event-b", UserID: "enterprise-b", Status: EventRunning, VoucherNum: 10},
+		}},
+		voucher: &fakeVoucherRepository{vouchers: map[string]Voucher{
+			"voucher-b": {ID: "voucher-b", EventID: "event-b", Status: VoucherActive, ExpiredTime: time.Now().Add(time.Hour)},
+		}},
+		inventory: &fakeInventoryRepository{templates: map[string]VoucherTemplate{
+			"template-b": {ID: "template-b", EventID: "event-b", Quantity: 5},
+		}},
+		signer: fakeVoucherSigner{},
+	}
+}
+
+func TestEventTenantIsolation(t *testing.T) {
+	calls := []struct {
+		name string
+		call func(ctx context.Context, s *adminService) error
+	}{
+		{"get event", func(ctx context.Context, s *adminService) error {
+			_, err := s.GetEventByID(ctx, "event-b")
+			return err
+		}},
+		{"update event", func(ctx context.Context, s *adminService) error {
+			return s.UpdateEvent(ctx, Event{ID: "event-b", Name: "renamed"})
+		}},
+		{"cancel event", func(ctx context.Context, s *adminService) error {
+			return s.CancelEvent(ctx, "event-b", "")
+		}},
+		{"list vouchers", func(ctx context.Context, s *adminService) error {
+			_, err := s.GetAllVouchersByEventID(ctx, "event-b")
+			return err
+		}},
+		{"get voucher", func(ctx context.Context, s *adminService) error {
+			_, err := s.GetVoucherByID(ctx, "voucher-b", "event-b")
+			return err
+		}},
+		{"create voucher", func(ctx context.Context, s *adminService) error {
+			return s.CreateVoucher(ctx, Voucher{ID: "voucher-new", EventID: "event-b"})
+		}},
+		{"update voucher", func(ctx context.Context, s *adminService) error {
+			return s.UpdateVoucher(ctx, Voucher{ID: "voucher-b", EventID: "event-b"})
+		}},
+		{"delete voucher", func(ctx context.Context, s *adminService) error {
+			return s.DeleteVoucher(ctx, "voucher-b", "event-b")
+		}},
+		{"list templates", func(ctx context.Context, s *adminService) error {
+			_, err := s.GetVoucherTemplates(ctx, "event-b")
+			return err
+		}},
+		{"create template", func(ctx context.Context, s *adminService) error {
+			_, err := s.CreateVoucherTemplate(ctx, VoucherTemplate{EventID: "event-b", Quantity: 1})
+			return err
+		}},
+		{"update template quantity", func(ctx context.Context, s *adminService) error {
+			return s.UpdateVoucherTemplateQuantity(ctx, "template-b", "event-b", 6)
+		}},
+		{"issue voucher", func(ctx context.Context, s *adminService) error {
+			_, err := s.IssueVoucher(ctx, "template-b", "event-b", "player")
+			return err
+		}},
+	}
+	principals := []struct {
+		name      string
+		principal auth.Principal
+		err       error
+	}{
+		{"other enterprise", auth.Principal{UserID: "user-a", Role: auth.RoleEnterprise, EnterpriseID: "enterprise-a"}, ErrEventNotFound},
+		{"staff of other enterprise", auth.Principal{UserID: "staff-a", Role: auth.RoleEnterpriseStaff, EnterpriseID: "enterprise-a"}, ErrEventNotFound},
+		{"enterprise without id", auth.Principal{UserID: "user-x", Role: auth.RoleEnterprise}, ErrEventNotFound},
+		{"owner", auth.Principal{UserID: "user-b", Role: auth.RoleEnterprise, EnterpriseID: "enterprise-b"}, nil},
+		{"admin", auth.Principal{UserID: "admin", Role: auth.RoleAdmin}, nil},
+	}
+	for _, p := range principals {
+		for _, c := range calls {
+			t.Run(p.name+"/"+c.name, func(t *testing.T) {
+				s := newTenantTestService()
+				ctx := auth.WithPrincipal(context.Background(), p.principal)
+				err := c.call(ctx, s)
+				if p.err == nil {
+					if err != nil {
+						t.Fatalf("expected access, got %v", err)
+					}
+					return
+				}
+				if err != p.err {
+					t.Fatalf("expected %v, got %v", p.err, err)
+				}
+			})
+		}
+	}
+}
+
+func TestEventTenantIsolationLeavesDataUntouched(t *testing.T) {
+	s := newTenantTestService()
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-a", Role: auth.RoleEnterprise, EnterpriseID: "enterprise-a"})
+
+	s.UpdateEvent(ctx, Event{ID: "event-b", Name: "renamed"})
+	s.CancelEvent(ctx, "event-b", "")
+	s.CreateVoucher(ctx, Voucher{ID: "voucher-new", EventID: "event-b"})
+	s.DeleteVoucher(ctx, "voucher-b", "event-b")
+	s.UpdateVoucherTemplateQuantity(ctx, "template-b", "event-b", 100)
+
+	event := s.event.(*fakeEventRepository).events["event-b"]
+	if event.Name != "" || event.Status != EventRunning {
+		t.Errorf("event was changed: %+v", event)
+	}
+	vouchers := s.voucher.(*fakeVoucherRepository).vouchers
+	if _, ok := vouchers["voucher-new"]; ok {
+		t.Error("voucher was created")
+	}
+	if _, ok := vouchers["voucher-b"]; !ok {
+		t.Error("voucher was deleted")
+	}
+	if q := s.inventory.(*fakeInventoryRepository).templates["template-b"].Quantity; q != 5 {
+		t.Errorf("template quantity changed to %d", q)
+	}
+}
+
+// prefixHasher stands in for a real hasher: it stores prefix+password.
+type prefixHasher struct {
+	prefix string
+}
+
+func (h prefixHasher) Hash(plain string) (string, error) {
+	return h.prefix + plain, nil
+}
+
+func (h prefixHasher) Compare(plain string, hashed string) error {
+	if hashed != h.prefix+plain {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+func (h prefixHasher) Recognizes(hashed string) bool {
+	return strings.HasPrefix(hashed, h.prefix)
+}
+
+type fakeUserRepository struct {
+	UserRepository
+	updated map[string]string
+	users   map[string]User
+}
+
+func (r *fakeUserRepository) GetUserById(ctx context.Context, id string) (User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (r *fakeUserRepository) UpdateUser(ctx context.Context, user User) error {
+	r.updated[user.ID] = user.Password
+	return nil
+}
+
+func TestVerifyPassword(t *testing.T) {
+	current := prefixHasher{prefix: "$argon2id$"}
+	previous := prefixHasher{prefix: "$2a$"}
+	cases := []struct {
+		name     string
+		stored   string
+		password string
+		err      error
+		rehashed bool
+	}{
+		{"current hasher", "$argon2id$secret", "secret", nil, false},
+		{"current hasher wrong password", "$argon2id$secret", "guess", ErrInvalidCredentials, false},
+		{"previous hasher", "$2a$secret", "secret", nil, true},
+		{"previous hasher wrong password", "$2a$secret", "guess", ErrInvalidCredentials, false},
+		{"hash used as password", "$2a$secret", "$2a$secret", ErrInvalidCredentials, false},
+		{"current hash used as password", "$argon2id$secret", "$argon2id$secret", ErrInvalidCredentials, false},
+		{"legacy plain text", "secret", "secret", nil, true},
+		{"legacy plain text wrong password", "secret", "guess", ErrInvalidCredentials, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			users := &fakeUserRepository{updated: map[string]string{}}
+			s := &adminService{users: users, hasher: current, hashers: []PasswordHasher{previous, current}}
+			err := s.verifyPassword(context.Background(), User{ID: "user", Password: c.stored}, c.password)
+			if err != c.err {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+			hash, rehashed := users.updated["user"]
+			if rehashed != c.rehashed {
+				t.Fatalf("rehashed = %v, want %v", rehashed, c.rehashed)
+			}
+			if rehashed && hash != "$argon2id$"+c.password {
+				t.Fatalf("rehashed to %q", hash)
+			}
+		})
+	}
+}
+
+// fakeRedemptionRepository runs check against a single voucher, as the real
+// repository does with the voucher row locked.
+type fakeRedemptionRepository struct {
+	RedemptionRepository
+	voucher    Voucher
+	enterprise string
+	logged     []Redemption
+}
+
+func (r *fakeRedemptionRepository) RedeemVoucher(ctx context.Context, code string, redemption Redemption, check func(v Voucher, enterpriseID string) error) (Voucher, error) {
+	if code != r.voucher.Code {
+		return Voucher{}, ErrVoucherNotFound
+	}
+	if err := check(r.voucher, r.enterprise); err != nil {
+		return r.voucher, err
+	}
+	r.voucher.Status = VoucherRedeemed
+	return r.voucher, nil
+}
+
+func (r *fakeRedemptionRepository) LogRedemption(ctx context.Context, redemption Redemption) error {
+	r.logged = append(r.logged, redemption)
+	return nil
+}
+
+func TestRedeemVoucherRule(t *testing.T) {
+	rule := &DiscountRule{Type: DiscountFixed, Amount: 500, Currency: "USD", MinSpend: 2000, Stores: []string{"store-1"}}
+	basket := func(currency string, price int64) *Basket {
+		return &Basket{Currency: currency, Items: []BasketItem{{SKU: "sku", Quantity: 1, UnitPrice: price}}}
+	}
+	cases := []struct {
+		name    string
+		rule    *DiscountRule
+		store   string
+		basket  *Basket
+		err     error
+		outcome string
+	}{
+		{"allowed store", rule, "store-1", basket("USD", 2000), nil, RedemptionRedeemed},
+		{"other store", rule, "store-2", nil, ErrVoucherWrongStore, RedemptionWrongStore},
+		{"no basket", rule, "store-1", nil, ErrVoucherBasketRequired, RedemptionBasketRequired},
+		{"no basket for min spend", &DiscountRule{Type: DiscountFixed, Amount: 500, MinSpend: 2000}, "", nil, ErrVoucherBasketRequired, RedemptionBasketRequired},
+		{"no basket for currency", &DiscountRule{Type: DiscountFixed, Amount: 500, Currency: "USD"}, "", nil, ErrVoucherBasketRequired, RedemptionBasketRequired},
+		{"no basket needed", &DiscountRule{Type: DiscountPercent, Percent: 10, Stores: []string{"store-1"}}, "store-1", nil, nil, RedemptionRedeemed},
+		{"other store with basket", rule, "store-2", basket("USD", 5000), ErrVoucherWrongStore, RedemptionWrongStore},
+		{"basket reaches min spend", rule, "store-1", basket("USD", 2000), nil, RedemptionRedeemed},
+		{"basket below min spend", rule, "store-1", basket("USD", 1999), ErrVoucherBelowMinSpend, RedemptionBelowMinSpend},
+		{"basket in other currency", rule, "store-1", basket("EUR", 5000), ErrVoucherWrongCurrency, RedemptionWrongCurrency},
+		{"no rule", nil, "store-2", basket("EUR", 1), nil, RedemptionRedeemed},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			redemptions := &fakeRedemptionRepository{
+				voucher: Voucher{
+					ID:          "voucher",
+					Code:        "CODE",
+					EventID:     "event",
+					Status:      VoucherActive,
+					ExpiredTime: time.Now().Add(time.Hour),
+					Rule:        c.rule,
+				},
+				enterprise: "enterprise",
+			}
+			s := &adminService{redemptions: redemptions, signer: fakeVoucherSigner{}}
+			ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "cashier", Role: auth.RoleEnterpriseStaff, EnterpriseID: "enterprise"})
+			_, err := s.RedeemVoucher(ctx, RedeemRequest{Code: "CODE", StoreID: c.store, Basket: c.basket})
+			if err != c.err {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+			if len(redemptions.logged) != 1 || redemptions.logged[0].Outcome != c.outcome {
+				t.Fatalf("logged %+v, want outcome %s", redemptions.logged, c.outcome)
+			}
+		})
+	}
+}
+
+func TestRedeemOwnedVoucher(t *testing.T) {
+	voucher := Voucher{
+		ID:          "voucher",
+		Code:        "CODE",
+		EventID:     "event",
+		OwnerID:     "owner",
+		Status:      VoucherActive,
+		ExpiredTime: time.Now().Add(time.Hour),
+	}
+	payload := func(owner string) string {
+		claims := NewVoucherClaims(voucher)
+		claims.OwnerID = owner
+		p, _ := fakeVoucherSigner{}.Sign(claims)
+		return p
+	}
+	cases := []struct {
+		name    string
+		req     RedeemRequest
+		err     error
+		outcome string
+	}{
+		{"typed code without owner", RedeemRequest{Code: "CODE"}, ErrVoucherOwnerRequired, RedemptionNotOwner},
+		{"typed code for another user", RedeemRequest{Code: "CODE", OwnerID: "someone"}, ErrVoucherNotOwned, RedemptionNotOwner},
+		{"typed code for the owner", RedeemRequest{Code: "CODE", OwnerID: "owner"}, nil, RedemptionRedeemed},
+		{"scanned code of the owner", RedeemRequest{Payload: payload("owner")}, nil, RedemptionRedeemed},
+		{"scanned code of a previous owner", RedeemRequest{Payload: payload("previous")}, ErrVoucherNotOwned, RedemptionNotOwner},
+		{"scanned code signed without owner", RedeemRequest{Payload: payload("")}, ErrVoucherNotOwned, RedemptionNotOwner},
+		{"scanned code for another user", RedeemRequest{Payload: payload("owner"), OwnerID: "someone"}, ErrVoucherNotOwned, RedemptionNotOwner},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			redemptions := &fakeRedemptionRepository{voucher: voucher, enterprise: "enterprise"}
+			s := &adminService{redemptions: redemptions, signer: fakeVoucherSigner{}}
+			ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "cashier", Role: auth.RoleEnterpriseStaff, EnterpriseID: "enterprise"})
+			c.req.StoreID = "store"
+			_, err := s.RedeemVoucher(ctx, c.req)
+			if err != c.err {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+			if len(redemptions.logged) != 1 || redemptions.logged[0].Outcome != c.outcome {
+				t.Fatalf("logged %+v, want outcome %s", redemptions.logged, c.outcome)
+			}
+		})
+	}
+}
+
+func TestUpdateVoucherInPlayersHands(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	cases := []struct {
+		name    string
+		voucher Voucher
+		err     error
+	}{
+		{"active", Voucher{Status: VoucherActive, ExpiredTime: future}, nil},
+		{"inactive", Voucher{Status: VoucherInactive, ExpiredTime: future}, nil},
+		{"redeemed", Voucher{Status: VoucherRedeemed, ExpiredTime: future}, ErrVoucherNotEditable},
+		{"expired status", Voucher{Status: VoucherExpired, ExpiredTime: future}, ErrVoucherNotEditable},
+		{"past expiry", Voucher{Status: VoucherActive, ExpiredTime: time.Now().Add(-time.Hour)}, ErrVoucherNotEditable},
+		{"owned", Voucher{Status: VoucherActive, ExpiredTime: future, OwnerID: "player"}, ErrVoucherNotEditable},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newTenantTestService()
+			c.voucher.ID, c.voucher.EventID, c.voucher.Code = "voucher-b", "event-b", "OLD"
+			s.voucher.(*fakeVoucherRepository).vouchers["voucher-b"] = c.voucher
+			ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "user-b", Role: auth.RoleEnterprise, EnterpriseID: "enterprise-b"})
+			err := s.UpdateVoucher(ctx, Voucher{ID: "voucher-b", EventID: "event-b", Code: "NEW"})
+			if err != c.err {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+			code := s.voucher.(*fakeVoucherRepository).vouchers["voucher-b"].Code
+			if c.err != nil && code != "OLD" {
+				t.Errorf("code was changed to %s", code)
+			}
+		})
+	}
+}
+
+func TestEditEvent(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+	event := func(status string, start time.Time) Event {
+		return Event{ID: "event", Name: "event", Status: status, StartTime: start, VoucherNum: 10, GameID: "game"}
+	}
+	cases := []struct {
+		name    string
+		current Event
+		edit    Event
+		err     error
+		status  string
+	}{
+		{"draft", event(EventDraft, future), Event{VoucherNum: 20, GameID: "other"}, nil, ""},
+		{"draft past start time", event(EventDraft, past), Event{VoucherNum: 20}, ErrEventLocked, ""},
+		{"draft past start time rename", event(EventDraft, past), Event{Name: "renamed"}, nil, ""},
+		{"pending review", event(EventPendingReview, future), Event{Name: "renamed"}, nil, ""},
+		{"approved goes back to review", event(EventApproved, future), Event{Name: "renamed"}, nil, EventPendingReview},
+		{"approved unchanged", event(EventApproved, future), Event{Name: "event", VoucherNum: 10}, nil, ""},
+		{"approved past start time", event(EventApproved, past), Event{GameID: "other"}, ErrEventLocked, ""},
+		{"running voucher count", event(EventRunning, past), Event{VoucherNum: 20}, ErrEventLocked, ""},
+		{"running game", event(EventRunning, future), Event{GameID: "other"}, ErrEventLocked, ""},
+		{"running same values", event(EventRunning, past), Event{VoucherNum: 10, GameID: "game"}, nil, ""},
+		{"running rename", event(EventRunning, past), Event{Name: "renamed"}, nil, ""},
+		{"ended", event(EventEnded, past), Event{Name: "renamed"}, ErrEventClosed, ""},
+		{"cancelled", event(EventCancelled, future), Event{Name: "renamed"}, ErrEventClosed, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			changes, err := editEvent(c.current, c.edit, now)
+			if err != c.err {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+			if changes.Status != c.status {
+				t.Fatalf("status = %q, want %q", changes.Status, c.status)
+			}
+		})
+	}
+}
+
+type fakeVoucherBatchRepository struct {
+	VoucherBatchRepository
+	attempt int
+	status  string
+	stored  int
+	// takeOverAt resumes the batch elsewhere once that many vouchers are
+	// stored.
+	takeOverAt int
+}
+
+func (r *fakeVoucherBatchRepository) UpdateBatch(ctx context.Context, id string, attempt int, status string, generated int, errMsg string) error {
+	if attempt != r.attempt {
+		return ErrBatchTakenOver
+	}
+	r.status = status
+	return nil
+}
+
+func (r *fakeVoucherBatchRepository) InsertBatchVouchers(ctx context.Context, batch VoucherBatch, codes []string) (int, error) {
+	r.stored += len(codes)
+	if r.takeOverAt != 0 && r.stored >= r.takeOverAt {
+		r.attempt++
+	}
+	return len(codes), nil
+}
+
+func newTestLogger(t *testing.T) log.Logger {
+	t.Helper()
+	logger, err := log.New(io.Discard, "error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logger
+}
+
+func TestRunBatchResumes(t *testing.T) {
+	batches := &fakeVoucherBatchRepository{attempt: 1, stored: 1500}
+	s := &adminService{log: newTestLogger(t), batches: batches}
+	batch := VoucherBatch{ID: "batch", Count: 2500, Generated: 1500, Attempt: 1, Length: 12, Charset: CharsetAlphanumeric, Checksum: ChecksumNone}
+
+	s.runBatch(context.Background(), batch)
+	if batches.stored != batch.Count {
+		t.Errorf("stored %d vouchers, want %d", batches.stored, batch.Count)
+	}
+	if batches.status != BatchCompleted {
+		t.Errorf("status = %q, want %q", batches.status, BatchCompleted)
+	}
+}
+
+func TestRunBatchStopsWhenTakenOver(t *testing.T) {
+	batches := &fakeVoucherBatchRepository{takeOverAt: 1000}
+	s := &adminService{log: newTestLogger(t), batches: batches}
+	batch := VoucherBatch{ID: "batch", Count: 5000, Length: 12, Charset: CharsetAlphanumeric, Checksum: ChecksumNone}
+
+	s.runBatch(context.Background(), batch)
+	if batches.stored != 1000 {
+		t.Errorf("stored %d vouchers, want the run to stop after 1000", batches.stored)
+	}
+	if batches.status != BatchRunning {
+		t.Errorf("status = %q, want %q", batches.status, BatchRunning)
+	}
+}
+
+// fakeAuthRepository holds a single refresh token, issued with or without
+// MFA.
+type fakeAuthRepository struct {
+	AuthRepository
+	userID    string
+	mfa       bool
+	refreshed bool
+	revoked   []string
+}
+
+func (r *fakeAuthRepository) RefreshAccessToken(ctx context.Context, refreshToken string, cfg TokenConfig, check func(userID string, mfa bool) error) (string, Token, error) {
+	if err := check(r.userID, r.mfa); err != nil {
+		return "", Token{}, err
+	}
+	r.refreshed = true
+	return r.userID, Token{AccessToken: "access", RefreshToken: "refresh"}, nil
+}
+
+func (r *fakeAuthRepository) RevokeAllAccessTokens(ctx context.Context, userID string) error {
+	r.revoked = append(r.revoked, userID)
+	return nil
+}
+
+type fakeMFARepository struct {
+	MFARepository
+	mfa      MFA
+	enforced bool
+}
+
+func (r *fakeMFARepository) GetMFA(ctx context.Context, userID string) (MFA, error) {
+	if r.mfa.UserID == "" {
+		return MFA{}, ErrMFANotEnrolled
+	}
+	return r.mfa, nil
+}
+
+func (r *fakeMFARepository) MFAEnforced(ctx context.Context, role string) (bool, error) {
+	return r.enforced, nil
+}
+
+type fakeTokenIssuer struct {
+	TokenIssuer
+}
+
+func (fakeTokenIssuer) Issue(p auth.Principal, ttl time.Duration) (string, error) {
+	return p.TokenID, nil
+}
+
+func TestRefreshTokenRequiresMFA(t *testing.T) {
+	enabled := time.Now()
+	cases := []struct {
+		name       string
+		role       string
+		sessionMFA bool
+		enrolled   bool
+		enforced   bool
+		err        error
+	}{
+		{"no MFA needed", auth.RoleEnterprise, false, false, false, nil},
+		{"session passed MFA", auth.RoleEnterprise, true, true, true, nil},
+		{"MFA turned on since login", auth.RoleEnterprise, false, true, false, ErrMFARequired},
+		{"MFA enforced since login", auth.RoleAdmin, false, false, true, ErrMFARequired},
+		{"role without MFA", auth.RoleEndUser, false, false, true, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tokens := &fakeAuthRepository{userID: "user", mfa: c.sessionMFA}
+			mfa := &fakeMFARepository{enforced: c.enforced}
+			if c.enrolled {
+				mfa.mfa = MFA{UserID: "user", EnabledAt: &enabled}
+			}
+			s := &adminService{
+				auth:   tokens,
+				mfa:    mfa,
+				users:  &fakeUserRepository{users: map[string]User{"user": {ID: "user", Role: c.role, Status: UserActive}}},
+				issuer: fakeTokenIssuer{},
+			}
+			_, err := s.RefreshToken(context.Background(), "refresh")
+			if err != c.err {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+			if tokens.refreshed != (c.err == nil) {
+				t.Fatalf("refreshed = %v", tokens.refreshed)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenRequiresActiveUser(t *testing.T) {
+	cases := []struct {
+		status string
+		err    error
+	}{
+		{UserActive, nil},
+		{UserInactive, ErrAccountInactive},
+		{UserPendingVerification, ErrAccountNotVerified},
+	}
+	for _, c := range cases {
+		t.Run(c.status, func(t *testing.T) {
+			tokens := &fakeAuthRepository{userID: "user"}
+			s := &adminService{
+				auth:   tokens,
+				users:  &fakeUserRepository{users: map[string]User{"user": {ID: "user", Role: auth.RoleEndUser, Status: c.status}}},
+				issuer: fakeTokenIssuer{},
+			}
+			_, err := s.RefreshToken(context.Background(), "refresh")
+			if err != c.err {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+			if tokens.refreshed != (c.err == nil) {
+				t.Fatalf("refreshed = %v", tokens.refreshed)
+			}
+		})
+	}
+}
+
+func TestDeactivateUserRevokesTokens(t *testing.T) {
+	tokens := &fakeAuthRepository{}
+	s := &adminService{
+		auth:  tokens,
+		users: &fakeUserRepository{updated: map[string]string{}},
+	}
+	if err := s.DeactiveUser(context.Background(), "user-1"); err != nil {
+		t.Fatalf("DeactiveUser: %v", err)
+	}
+	if err := s.UpdateUser(context.Background(), User{ID: "user-2", Status: UserInactive}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if err := s.UpdateUser(context.Background(), User{ID: "user-3", Name: "renamed"}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if len(tokens.revoked) != 2 || tokens.revoked[0] != "user-1" || tokens.revoked[1] != "user-2" {
+		t.Errorf("revoked tokens of %v, want [user-1 user-2]", tokens.revoked)
+	}
+}
+
+type fakeTurnRepository struct {
+	TurnRepository
+	inviters map[string]string
+	credited []string
+}
+
+func (r *fakeTurnRepository) GetInviter(ctx context.Context, eventID string, code string) (string, error) {
+	inviter, ok := r.inviters[code]
+	if !ok {
+		return "", ErrInviteCodeNotFound
+	}
+	return inviter, nil
+}
+
+func (r *fakeTurnRepository) Credit(ctx context.Context, eventID string, userID string, amount int, reason string, ref string) (TurnEntry, error) {
+	r.credited = append(r.credited, userID)
+	return TurnEntry{UserID: userID, Amount: amount, Reason: reason, Ref: ref}, nil
+}
+
+func TestClaimInvite(t *testing.T) {
+	cases := []struct {
+		name    string
+		code    string
+		err     error
+		inviter string
+	}{
+		{"issued code", "ALICE234", nil, "alice"},
+		{"lower case", "alice234", nil, "alice"},
+		{"unknown code", "NOBODY23", ErrInviteCodeNotFound, ""},
+		{"own code", "PLAYER23", ErrSelfTurnRequest, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			turns := &fakeTurnRepository{inviters: map[string]string{"ALICE234": "alice", "PLAYER23": "player"}}
+			s := &adminService{
+				event: &fakeEventRepository{events: map[string]Event{"event": {ID: "event", Status: EventRunning}}},
+				turns: turns,
+			}
+			ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "player", Role: auth.RoleEndUser})
+			err := s.ClaimInvite(ctx, "event", c.code)
+			if err != c.err {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+			if c.inviter == "" && len(turns.credited) != 0 {
+				t.Fatalf("credited %v", turns.credited)
+			}
+			if c.inviter != "" && (len(turns.credited) != 1 || turns.credited[0] != c.inviter) {
+				t.Fatalf("credited %v, want %s", turns.credited, c.inviter)
+			}
+		})
+	}
+}
+
+type fakeGameRepository struct {
+	GameRepository
+	games map[string]Game
+}
+
+func (r *fakeGameRepository) GetGameById(ctx context.Context, id string) (Game, error) {
+	return r.games[id], nil
+}
+
+// fakeTransferRepository accepts a single offer of a voucher of eventID.
+type fakeTransferRepository struct {
+	TransferRepository
+	eventID  string
+	accepted bool
+}
+
+func (r *fakeTransferRepository) AcceptTransfer(ctx context.Context, id string, tokenHash string, recipientID string, check func(eventID string) error) (Transfer, error) {
+	if err := check(r.eventID); err != nil {
+		return Transfer{}, err
+	}
+	r.accepted = true
+	return Transfer{ID: id, Status: TransferAccepted, ToUserID: recipientID}, nil
+}
+
+func TestAcceptTransferRechecksExchange(t *testing.T) {
+	for _, allowed := range []bool{true, false} {
+		transfers := &fakeTransferRepository{eventID: "event"}
+		s := &adminService{
+			event:     &fakeEventRepository{events: map[string]Event{"event": {ID: "event", GameID: "game"}}},
+			games:     &fakeGameRepository{games: map[string]Game{"game": {ID: "game", ExchangeAllow: allowed}}},
+			transfers: transfers,
+		}
+		ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: "recipient", Role: auth.RoleEndUser})
+		_, err := s.AcceptTransfer(ctx, "transfer")
+		want := error(nil)
+		if !allowed {
+			want = ErrExchangeNotAllowed
+		}
+		if err != want {
+			t.Fatalf("exchange allowed %v: expected %v, got %v", allowed, want, err)
+		}
+		if transfers.accepted != allowed {
+			t.Fatalf("exchange allowed %v: accepted = %v", allowed, transfers.accepted)
+		}
+	}
+}
+
+func (r *fakeUserRepository) GetUserByContact(ctx context.Context, contact string) (User, error) {
+	for _, user := range r.users {
+		if user.Email == contact || user.Phone == contact {
+			return user, nil
+		}
+	}
+	return User{}, ErrUserNotFound
+}
+
+type fakeOneTimeCodeRepository struct {
+	OneTimeCodeRepository
+	err error
+}
+
+func (r *fakeOneTimeCodeRepository) CreateCode(ctx context.Context, code OneTimeCode, ttl time.Duration, resendAfter time.Duration, maxPerHour int) (OneTimeCode, error) {
+	return code, r.err
+}
+
+type fakeNotifier struct {
+	sent []Message
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, msg Message) error {
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+func TestForgotPasswordHidesThrottling(t *testing.T) {
+	users := &fakeUserRepository{users: map[string]User{
+		"user-1": {ID: "user-1", Email: "known@example.com"},
+	}}
+	cases := []struct {
+		name    string
+		contact string
+		err     error
+		sent    int
+	}{
+		{"unknown contact", "unknown@example.com", nil, 0},
+		{"known contact", "known@example.com", nil, 1},
+		{"throttled contact", "known@example.com", ErrCodeThrottled, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			notifier := &fakeNotifier{}
+			s := &adminService{
+				users:    users,
+				codes:    &fakeOneTimeCodeRepository{err: c.err},
+				notifier: notifier,
+			}
+			if err := s.ForgotPassword(context.Background(), c.contact, ChannelEmail); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if len(notifier.sent) != c.sent {
+				t.Errorf("expected %d messages, got %d", c.sent, len(notifier.sent))
+			}
+		})
+	}
+}
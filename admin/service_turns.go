@@ -0,0 +1,228 @@
+package admin
+
+import (
+	"context"
+	"strings"
+
+	"github.com/resrrdttrt/VOU/pkg/auth"
+)
+
+type turnService interface {
+	GetMyTurns(ctx context.Context, eventID string) (TurnBalance, error)
+	GetMyTurnHistory(ctx context.Context, eventID string) ([]TurnEntry, error)
+	SpendTurn(ctx context.Context, eventID string, ref string) (TurnEntry, error)
+	ShareEvent(ctx context.Context, eventID string) (TurnEntry, error)
+	GetInviteCode(ctx context.Context, eventID string) (InviteCode, error)
+	ClaimInvite(ctx context.Context, eventID string, code string) error
+	RequestTurn(ctx context.Context, eventID string, friend string) (TurnRequest, error)
+	GetMyTurnRequests(ctx context.Context, eventID string) ([]TurnRequest, error)
+	AcceptTurnRequest(ctx context.Context, id string) (TurnRequest, error)
+	DeclineTurnRequest(ctx context.Context, id string) error
+	CancelTurnRequest(ctx context.Context, id string) error
+}
+
+// GetMyTurns returns the caller's balance in the event, granting the day's
+// free turns first if they are due.
+func (s *adminService) GetMyTurns(ctx context.Context, eventID string) (TurnBalance, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return TurnBalance{}, auth.ErrUnauthenticated
+	}
+	event, err := s.event.GetEvent(ctx, eventID)
+	if err != nil {
+		return TurnBalance{}, err
+	}
+	balance, err := s.turns.GetBalance(ctx, event.ID, p.UserID)
+	if err != nil {
+		return TurnBalance{}, err
+	}
+	return TurnBalance{EventID: event.ID, Balance: balance, DailyTurns: event.PlayTurns}, nil
+}
+
+func (s *adminService) GetMyTurnHistory(ctx context.Context, eventID string) ([]TurnEntry, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	return s.turns.GetEntries(ctx, eventID, p.UserID)
+}
+
+// SpendTurn debits one of the caller's turns for a play of the event's game.
+// ref identifies the play to the client.
+func (s *adminService) SpendTurn(ctx context.Context, eventID string, ref string) (TurnEntry, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return TurnEntry{}, auth.ErrUnauthenticated
+	}
+	event, err := s.runningEvent(ctx, eventID)
+	if err != nil {
+		return TurnEntry{}, err
+	}
+	return s.turns.Spend(ctx, event.ID, p.UserID, TurnPlay, ref)
+}
+
+// ShareEvent credits the caller's share bonus, once a day.
+func (s *adminService) ShareEvent(ctx context.Context, eventID string) (TurnEntry, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return TurnEntry{}, auth.ErrUnauthenticated
+	}
+	event, err := s.runningEvent(ctx, eventID)
+	if err != nil {
+		return TurnEntry{}, err
+	}
+	return s.turns.Credit(ctx, event.ID, p.UserID, ShareBonus, TurnShare, "")
+}
+
+// GetInviteCode returns the caller's invite code in the event, issuing one
+// the first time.
+func (s *adminService) GetInviteCode(ctx context.Context, eventID string) (InviteCode, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return InviteCode{}, auth.ErrUnauthenticated
+	}
+	event, err := s.event.GetEvent(ctx, eventID)
+	if err != nil {
+		return InviteCode{}, err
+	}
+	format := CodeFormat{Length: InviteCodeLength, Charset: CharsetAlphanumeric, Checksum: ChecksumNone}
+	code, err := format.Generate()
+	if err != nil {
+		return InviteCode{}, err
+	}
+	code, err = s.turns.SaveInviteCode(ctx, event.ID, p.UserID, code)
+	if err != nil {
+		return InviteCode{}, err
+	}
+	return InviteCode{EventID: event.ID, Code: code}, nil
+}
+
+// ClaimInvite credits the invite bonus to the player who issued code to the
+// caller. Each player can name one inviter per event, and only with a code
+// the inviter handed out, so nobody can be credited without inviting.
+func (s *adminService) ClaimInvite(ctx context.Context, eventID string, code string) error {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+	event, err := s.runningEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	inviter, err := s.turns.GetInviter(ctx, event.ID, strings.ToUpper(code))
+	if err != nil {
+		return err
+	}
+	if inviter == p.UserID {
+		return ErrSelfTurnRequest
+	}
+	_, err = s.turns.Credit(ctx, event.ID, inviter, InviteBonus, TurnInvite, p.UserID)
+	return err
+}
+
+// RequestTurn asks a friend, named by username, email or phone, to give the
+// caller one of their turns in the event.
+func (s *adminService) RequestTurn(ctx context.Context, eventID string, friend string) (TurnRequest, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return TurnRequest{}, auth.ErrUnauthenticated
+	}
+	event, err := s.runningEvent(ctx, eventID)
+	if err != nil {
+		return TurnRequest{}, err
+	}
+	user, err := s.friend(ctx, friend, p.UserID)
+	if err != nil {
+		return TurnRequest{}, err
+	}
+	return s.turns.CreateTurnRequest(ctx, TurnRequest{
+		EventID:    event.ID,
+		FromUserID: p.UserID,
+		ToUserID:   user.ID,
+	})
+}
+
+// GetMyTurnRequests lists the open requests the caller sent or received in
+// the event.
+func (s *adminService) GetMyTurnRequests(ctx context.Context, eventID string) ([]TurnRequest, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	return s.turns.GetPendingTurnRequestsByUser(ctx, eventID, p.UserID)
+}
+
+// AcceptTurnRequest lets the friend asked give one of their turns.
+func (s *adminService) AcceptTurnRequest(ctx context.Context, id string) (TurnRequest, error) {
+	request, err := s.myTurnRequest(ctx, id, func(r TurnRequest, userID string) bool { return r.ToUserID == userID })
+	if err != nil {
+		return TurnRequest{}, err
+	}
+	if _, err := s.runningEvent(ctx, request.EventID); err != nil {
+		return TurnRequest{}, err
+	}
+	return s.turns.AcceptTurnRequest(ctx, request.ID)
+}
+
+// DeclineTurnRequest lets the friend asked turn the request down.
+func (s *adminService) DeclineTurnRequest(ctx context.Context, id string) error {
+	request, err := s.myTurnRequest(ctx, id, func(r TurnRequest, userID string) bool { return r.ToUserID == userID })
+	if err != nil {
+		return err
+	}
+	return s.turns.CloseTurnRequest(ctx, request.ID, TurnRequestDeclined)
+}
+
+// CancelTurnRequest lets the requester withdraw a request.
+func (s *adminService) CancelTurnRequest(ctx context.Context, id string) error {
+	request, err := s.myTurnRequest(ctx, id, func(r TurnRequest, userID string) bool { return r.FromUserID == userID })
+	if err != nil {
+		return err
+	}
+	return s.turns.CloseTurnRequest(ctx, request.ID, TurnRequestCancelled)
+}
+
+// myTurnRequest returns the request if party says the caller may act on it,
+// the same way myTransfer does for transfers.
+func (s *adminService) myTurnRequest(ctx context.Context, id string, party func(r TurnRequest, userID string) bool) (TurnRequest, error) {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return TurnRequest{}, auth.ErrUnauthenticated
+	}
+	request, err := s.turns.GetTurnRequest(ctx, id)
+	if err != nil {
+		return TurnRequest{}, err
+	}
+	if !party(request, p.UserID) {
+		return TurnRequest{}, ErrTurnRequestNotFound
+	}
+	return request, nil
+}
+
+// friend returns the active player named by contact, who must not be the
+// caller.
+func (s *adminService) friend(ctx context.Context, contact string, callerID string) (User, error) {
+	user, err := s.users.GetUserByContact(ctx, contact)
+	if err != nil {
+		return User{}, err
+	}
+	if user.Role != auth.RoleEndUser || user.Status != "active" {
+		return User{}, ErrUserNotFound
+	}
+	if user.ID == callerID {
+		return User{}, ErrSelfTurnRequest
+	}
+	return user, nil
+}
+
+// runningEvent returns the event if it is running.
+func (s *adminService) runningEvent(ctx context.Context, eventID string) (Event, error) {
+	event, err := s.event.GetEvent(ctx, eventID)
+	if err != nil {
+		return Event{}, err
+	}
+	if event.Status != EventRunning {
+		return Event{}, ErrEventNotRunning
+	}
+	return event, nil
+}
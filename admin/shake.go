@@ -0,0 +1,296 @@
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+// GameTypeShake is the game type whose events hand out prizes by lucky draw:
+// each shake of the phone draws one entry of the event's prize table.
+const GameTypeShake = "shake"
+
+// Kinds of prize a draw can land on.
+const (
+	PrizeVoucher = "voucher"
+	PrizeItem    = "item"
+	PrizeNothing = "nothing"
+)
+
+// Bounds of a prize table.
+const (
+	MaxPrizes      = 50
+	MaxPrizeWeight = 1000000
+	MaxPlayTurns   = 1000
+)
+
+// IsShakeGame reports whether a game of type t hands out prizes by draw.
+func IsShakeGame(t string) bool {
+	return strings.EqualFold(t, GameTypeShake)
+}
+
+// Prize is an entry of an event's prize table. A draw lands on a prize with
+// a chance of its Weight over the total weight of the entries still in
+// stock. Stock counts down from InitialStock as the prize is won; "nothing"
+// entries have no stock and never run out.
+type Prize struct {
+	ID           string    `db:"id" json:"id,omitempty"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at,omitempty"`
+	EventID      string    `db:"event_id" json:"event_id,omitempty"`
+	Kind         string    `db:"kind" json:"kind"`
+	Name         string    `db:"name" json:"name"`
+	TemplateID   string    `db:"template_id" json:"template_id,omitempty"`
+	ItemCode     string    `db:"item_code" json:"item_code,omitempty"`
+	Weight       int       `db:"weight" json:"weight"`
+	InitialStock int       `db:"initial_stock" json:"initial_stock"`
+	Stock        int       `db:"stock" json:"stock"`
+}
+
+// InStock reports whether the prize can still be drawn.
+func (p Prize) InStock() bool {
+	return p.Kind == PrizeNothing || p.Stock > 0
+}
+
+func errInvalidPrize(reason string) error {
+	return errors.Wrap(errors.ErrMalformedEntity, errors.New("invalid prize: "+reason))
+}
+
+// Validate checks that the prize can be drawn and awarded.
+func (p Prize) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errInvalidPrize("name is required")
+	}
+	if p.Weight < 1 || p.Weight > MaxPrizeWeight {
+		return errInvalidPrize(fmt.Sprintf("weight must be between 1 and %d", MaxPrizeWeight))
+	}
+	switch p.Kind {
+	case PrizeVoucher:
+		if p.TemplateID == "" || p.ItemCode != "" {
+			return errInvalidPrize("a voucher prize needs template_id and no item_code")
+		}
+	case PrizeItem:
+		if strings.TrimSpace(p.ItemCode) == "" || p.TemplateID != "" {
+			return errInvalidPrize("an item prize needs item_code and no template_id")
+		}
+	case PrizeNothing:
+		if p.TemplateID != "" || p.ItemCode != "" || p.InitialStock != 0 {
+			return errInvalidPrize("a nothing prize has no template_id, item_code or stock")
+		}
+		return nil
+	default:
+		return errInvalidPrize("kind must be voucher, item or nothing")
+	}
+	if p.InitialStock < 0 {
+		return errInvalidPrize("stock cannot be negative")
+	}
+	return nil
+}
+
+// PrizeTable is what an event of a shake game hands out, and how many free
+// turns each player gets a day.
+type PrizeTable struct {
+	EventID string  `json:"event_id"`
+	Turns   int     `json:"turns_per_day"`
+	Prizes  []Prize `json:"prizes"`
+}
+
+// DrawCandidate is an entry that was in stock when a draw was made.
+type DrawCandidate struct {
+	PrizeID string `json:"prize_id"`
+	Weight  int64  `json:"weight"`
+}
+
+// DrawCandidates is stored as JSONB, in the order the draw walked them.
+type DrawCandidates []DrawCandidate
+
+func (c DrawCandidates) Value() (driver.Value, error) {
+	if c == nil {
+		c = DrawCandidates{}
+	}
+	return json.Marshal(c)
+}
+
+func (c *DrawCandidates) Scan(src interface{}) error {
+	return scanJSON(src, c)
+}
+
+// TotalWeight is the upper bound, exclusive, of a roll over c.
+func (c DrawCandidates) TotalWeight() int64 {
+	var total int64
+	for _, cand := range c {
+		total += cand.Weight
+	}
+	return total
+}
+
+// Select returns the prize a roll in [0, TotalWeight) lands on: the first
+// candidate whose cumulative weight exceeds the roll.
+func (c DrawCandidates) Select(roll int64) (string, bool) {
+	if roll < 0 {
+		return "", false
+	}
+	var cum int64
+	for _, cand := range c {
+		cum += cand.Weight
+		if roll < cum {
+			return cand.PrizeID, true
+		}
+	}
+	return "", false
+}
+
+// Roll returns a uniform random number in [0, total) from the operating
+// system's CSPRNG, so no one can predict or steer a draw.
+func Roll(total int64) (int64, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(total))
+	if err != nil {
+		return 0, err
+	}
+	return n.Int64(), nil
+}
+
+// Draw is an entry of an event's draw log. Entries are numbered by Seq and
+// chained by Hash, so the log can be replayed: every outcome must follow from
+// its Roll over its Candidates, and no entry can be changed, dropped or
+// reordered without breaking the chain.
+type Draw struct {
+	ID          string         `db:"id" json:"id"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+	EventID     string         `db:"event_id" json:"event_id"`
+	Seq         int64          `db:"seq" json:"seq"`
+	UserID      string         `db:"user_id" json:"user_id"`
+	PrizeID     string         `db:"prize_id" json:"prize_id"`
+	Kind        string         `db:"kind" json:"kind"`
+	Name        string         `db:"name" json:"name"`
+	VoucherID   string         `db:"voucher_id" json:"voucher_id,omitempty"`
+	ItemCode    string         `db:"item_code" json:"item_code,omitempty"`
+	Roll        int64          `db:"roll" json:"roll"`
+	TotalWeight int64          `db:"total_weight" json:"total_weight"`
+	Candidates  DrawCandidates `db:"candidates" json:"candidates"`
+	PrevHash    string         `db:"prev_hash" json:"prev_hash"`
+	Hash        string         `db:"hash" json:"hash"`
+}
+
+// ComputeHash returns the SHA-256 of the draw's fields and the hash of the
+// draw before it, hex encoded.
+func (d Draw) ComputeHash() string {
+	candidates, _ := json.Marshal(d.Candidates)
+	h := sha256.New()
+	for _, field := range []string{
+		d.PrevHash,
+		d.EventID,
+		strconv.FormatInt(d.Seq, 10),
+		d.UserID,
+		d.PrizeID,
+		d.Kind,
+		d.VoucherID,
+		d.ItemCode,
+		strconv.FormatInt(d.Roll, 10),
+		strconv.FormatInt(d.TotalWeight, 10),
+		string(candidates),
+	} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ShakeResult is what a player gets back from a shake.
+type ShakeResult struct {
+	Draw      Draw `json:"draw"`
+	TurnsLeft int  `json:"turns_left"`
+}
+
+// ShakeState is a player's standing in a shake event.
+type ShakeState struct {
+	EventID   string `json:"event_id"`
+	Turns     int    `json:"turns_per_day"`
+	TurnsLeft int    `json:"turns_left"`
+	Draws     []Draw `json:"draws"`
+}
+
+// DrawAudit is the result of replaying an event's draw log. When Valid is
+// false, FailedSeq is the first entry that does not check out and Reason
+// says why.
+type DrawAudit struct {
+	EventID   string         `json:"event_id"`
+	Draws     int            `json:"draws"`
+	Valid     bool           `json:"valid"`
+	FailedSeq int64          `json:"failed_seq,omitempty"`
+	Reason    string         `json:"reason,omitempty"`
+	HeadHash  string         `json:"head_hash,omitempty"`
+	Wins      map[string]int `json:"wins"`
+}
+
+// ReplayDraws replays draws, in Seq order, against the prize table they were
+// made from.
+func ReplayDraws(eventID string, prizes []Prize, draws []Draw) DrawAudit {
+	audit := DrawAudit{EventID: eventID, Draws: len(draws), Valid: true, Wins: map[string]int{}}
+	byID := map[string]Prize{}
+	for _, p := range prizes {
+		byID[p.ID] = p
+	}
+	fail := func(d Draw, reason string) DrawAudit {
+		audit.Valid = false
+		audit.FailedSeq = d.Seq
+		audit.Reason = reason
+		return audit
+	}
+	prev := ""
+	for i, d := range draws {
+		if d.Seq != int64(i+1) {
+			return fail(d, fmt.Sprintf("expected seq %d", i+1))
+		}
+		if d.PrevHash != prev {
+			return fail(d, "prev_hash does not match the draw before")
+		}
+		if d.ComputeHash() != d.Hash {
+			return fail(d, "hash does not match the draw")
+		}
+		if d.TotalWeight != d.Candidates.TotalWeight() || d.Roll < 0 || d.Roll >= d.TotalWeight {
+			return fail(d, "roll is outside the candidates' weight")
+		}
+		if id, ok := d.Candidates.Select(d.Roll); !ok || id != d.PrizeID {
+			return fail(d, "roll does not land on the prize won")
+		}
+		p, ok := byID[d.PrizeID]
+		if !ok || p.Kind != d.Kind {
+			return fail(d, "prize is not in the table")
+		}
+		audit.Wins[d.PrizeID]++
+		if p.Kind != PrizeNothing && audit.Wins[d.PrizeID] > p.InitialStock {
+			return fail(d, "prize was won more times than it was stocked")
+		}
+		prev = d.Hash
+	}
+	audit.HeadHash = prev
+	return audit
+}
+
+type DrawRepository interface {
+	GetPrizes(ctx context.Context, eventID string) ([]Prize, error)
+	// ReplacePrizeTable swaps the event's prize table for prizes and sets how
+	// many free turns each player gets a day. It fails with ErrPrizeTableLocked once the
+	// event is running or over.
+	ReplacePrizeTable(ctx context.Context, eventID string, turns int, prizes []Prize) error
+	// Draw spends one of the player's turns and draws from the prizes in
+	// stock, all in one transaction that also takes the prize out of stock,
+	// issues the voucher or item won and appends the draw to the log. roll
+	// must return a uniform random number in [0, total). Draws of an event
+	// are made one at a time.
+	Draw(ctx context.Context, eventID string, userID string, voucherCode string, roll func(total int64) (int64, error)) (Draw, error)
+	// GetDraws returns the event's draw log in Seq order.
+	GetDraws(ctx context.Context, eventID string) ([]Draw, error)
+	GetDrawsByUser(ctx context.Context, eventID string, userID string) ([]Draw, error)
+}
@@ -0,0 +1,71 @@
+// Package smtp provides a notifier implementation that sends email through
+// an SMTP server.
+package smtp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+var errSendMail = errors.New("Send mail failed")
+
+var _ admin.Notifier = (*smtpNotifier)(nil)
+
+// Config locates the SMTP server. Username may be left empty for servers
+// that accept mail without authentication.
+type Config struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type smtpNotifier struct {
+	cfg Config
+}
+
+// New instantiates an SMTP-based notifier. It only delivers to the email
+// channel.
+func New(cfg Config) admin.Notifier {
+	return &smtpNotifier{cfg: cfg}
+}
+
+func (n *smtpNotifier) Notify(_ context.Context, msg admin.Message) error {
+	if msg.Channel != admin.ChannelEmail {
+		return admin.ErrChannelNotSupported
+	}
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+	}
+	addr := net.JoinHostPort(n.cfg.Host, n.cfg.Port)
+	if err := smtp.SendMail(addr, auth, n.cfg.From, []string{msg.To}, n.compose(msg)); err != nil {
+		return errors.Wrap(errSendMail, err)
+	}
+	return nil
+}
+
+func (n *smtpNotifier) compose(msg admin.Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", header(n.cfg.From))
+	fmt.Fprintf(&b, "To: %s\r\n", header(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// header keeps a value on its header line.
+func header(v string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(v)
+}
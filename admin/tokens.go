@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"github.com/resrrdttrt/VOU/pkg/auth"
+)
+
+// TokenIssuer mints access tokens and resolves them back to the principal
+// they were issued to.
+type TokenIssuer interface {
+	// Issue returns the access token handed to the client for p.
+	Issue(p auth.Principal, ttl time.Duration) (string, error)
+
+	// Parse verifies the access token and returns the principal it carries.
+	Parse(ctx context.Context, accessToken string) (auth.Principal, error)
+}
+
+// RevocationSyncer is implemented by TokenIssuers that verify access tokens
+// without looking them up in the access_tokens table. They keep the revoked
+// ones in memory instead, so a revocation takes effect on a replica once it
+// synced.
+type RevocationSyncer interface {
+	// SyncRevoked reloads the revoked access tokens that have not expired yet
+	// and returns how many there are.
+	SyncRevoked(ctx context.Context) (int64, error)
+}
+
+var _ TokenIssuer = (*opaqueIssuer)(nil)
+
+type opaqueIssuer struct {
+	auth AuthRepository
+}
+
+// NewOpaqueIssuer returns a TokenIssuer that hands out the random token stored
+// in the access_tokens table and looks it up on every request.
+func NewOpaqueIssuer(auth AuthRepository) TokenIssuer {
+	return &opaqueIssuer{auth: auth}
+}
+
+func (oi *opaqueIssuer) Issue(p auth.Principal, _ time.Duration) (string, error) {
+	return p.TokenID, nil
+}
+
+func (oi *opaqueIssuer) Parse(ctx context.Context, accessToken string) (auth.Principal, error) {
+	return oi.auth.GetPrincipalByAccessToken(ctx, accessToken)
+}
@@ -0,0 +1,73 @@
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// Transfer statuses. A transfer starts pending and ends in exactly one of
+// the other states.
+const (
+	TransferPending   = "pending"
+	TransferAccepted  = "accepted"
+	TransferDeclined  = "declined"
+	TransferCancelled = "cancelled"
+	TransferExpired   = "expired"
+)
+
+// TransferTTL is how long a gift offer stays open.
+const TransferTTL = 72 * time.Hour
+
+// Transfer is an offer to move a voucher from one player to another. Offers
+// made to a named player carry ToUserID; offers shared as a claim link carry
+// the hash of the link token instead and get ToUserID when claimed.
+type Transfer struct {
+	ID             string     `db:"id" json:"id"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+	VoucherID      string     `db:"voucher_id" json:"voucher_id"`
+	FromUserID     string     `db:"from_user_id" json:"from_user_id"`
+	ToUserID       string     `db:"to_user_id" json:"to_user_id,omitempty"`
+	ClaimTokenHash string     `db:"claim_token_hash" json:"-"`
+	Status         string     `db:"status" json:"status"`
+	ExpiresAt      time.Time  `db:"expires_at" json:"expires_at"`
+	AcceptedAt     *time.Time `db:"accepted_at" json:"accepted_at,omitempty"`
+	// ClaimToken is only set in the response to creating a link offer; the
+	// service keeps nothing but its hash.
+	ClaimToken string `db:"-" json:"claim_token,omitempty"`
+}
+
+// GenerateClaimToken returns a random token for a claim link.
+func GenerateClaimToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashClaimToken returns what is stored for a claim link token.
+func HashClaimToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type TransferRepository interface {
+	// CreateTransfer stores a pending offer. It fails with ErrTransferPending
+	// when the voucher already has an open offer.
+	CreateTransfer(ctx context.Context, transfer Transfer) (Transfer, error)
+	GetTransfer(ctx context.Context, id string) (Transfer, error)
+	// AcceptTransfer moves the voucher to recipientID and closes the offer in
+	// one transaction. The offer is found by ID when it names recipientID, or
+	// by claim token hash when it is a link. Nothing changes unless check
+	// accepts the event of the voucher once the offer is locked.
+	AcceptTransfer(ctx context.Context, id string, tokenHash string, recipientID string, check func(eventID string) error) (Transfer, error)
+	// CloseTransfer moves a pending offer to status.
+	CloseTransfer(ctx context.Context, id string, status string) error
+	GetPendingTransfersByUser(ctx context.Context, userID string) ([]Transfer, error)
+	GetTransfersByVoucher(ctx context.Context, voucherID string) ([]Transfer, error)
+}
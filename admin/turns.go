@@ -0,0 +1,114 @@
+package admin
+
+import (
+	"context"
+	"time"
+)
+
+// Reasons turns are credited or debited for.
+const (
+	// TurnDaily tops the balance up to the event's free turns of the day, the
+	// first time a player's balance is read on a day the event runs. Unused
+	// free turns do not pile up.
+	TurnDaily   = "daily"
+	TurnShare   = "share"
+	TurnInvite  = "invite"
+	TurnGiftIn  = "gift_in"
+	TurnGiftOut = "gift_out"
+	TurnPlay    = "play"
+)
+
+const (
+	// ShareBonus is credited for sharing an event, once a day.
+	ShareBonus = 1
+	// InviteBonus is credited to a player for each friend who claims their
+	// invite code.
+	InviteBonus = 3
+	// InviteCodeLength is the length of the invite codes handed to players.
+	InviteCodeLength = 8
+)
+
+// Turn request statuses. A request starts pending and ends in exactly one of
+// the other states.
+const (
+	TurnRequestPending   = "pending"
+	TurnRequestAccepted  = "accepted"
+	TurnRequestDeclined  = "declined"
+	TurnRequestCancelled = "cancelled"
+)
+
+// TurnEntry is an entry of the turns ledger of a player in an event. The
+// ledger is append-only: Amount is positive for credits and negative for
+// debits, and Balance is the player's balance once the entry is applied.
+type TurnEntry struct {
+	Seq       int64     `db:"seq" json:"seq"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	Day       time.Time `db:"day" json:"day"`
+	EventID   string    `db:"event_id" json:"event_id"`
+	GameID    string    `db:"game_id" json:"game_id"`
+	UserID    string    `db:"user_id" json:"user_id"`
+	Amount    int       `db:"amount" json:"amount"`
+	Balance   int       `db:"balance" json:"balance"`
+	Reason    string    `db:"reason" json:"reason"`
+	Ref       string    `db:"ref" json:"ref,omitempty"`
+}
+
+// TurnBalance is how many turns a player has left in an event.
+type TurnBalance struct {
+	EventID    string `json:"event_id"`
+	Balance    int    `json:"balance"`
+	DailyTurns int    `json:"daily_turns"`
+}
+
+// InviteCode is the code a player shares with friends so they can name the
+// player as their inviter in the event.
+type InviteCode struct {
+	EventID string `json:"event_id"`
+	Code    string `json:"code"`
+}
+
+// TurnRequest asks a friend to give the requester one of their turns in an
+// event.
+type TurnRequest struct {
+	ID         string    `db:"id" json:"id"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
+	EventID    string    `db:"event_id" json:"event_id"`
+	FromUserID string    `db:"from_user_id" json:"from_user_id"`
+	ToUserID   string    `db:"to_user_id" json:"to_user_id"`
+	Status     string    `db:"status" json:"status"`
+}
+
+// TurnRepository keeps the turns ledger. Every change to a player's balance
+// in an event is made under a lock on that balance, so concurrent plays see
+// and write consistent balances.
+type TurnRepository interface {
+	// GetBalance grants the day's free turns if due and returns the balance.
+	GetBalance(ctx context.Context, eventID string, userID string) (int, error)
+	GetEntries(ctx context.Context, eventID string, userID string) ([]TurnEntry, error)
+	// Credit appends a credit. It fails with ErrTurnsAlreadyCredited when a
+	// share was already credited that day, or an invitee already named an
+	// inviter in the event.
+	Credit(ctx context.Context, eventID string, userID string, amount int, reason string, ref string) (TurnEntry, error)
+	// Spend debits one turn. It fails with ErrNoTurnsLeft when the balance is
+	// empty.
+	Spend(ctx context.Context, eventID string, userID string, reason string, ref string) (TurnEntry, error)
+
+	// SaveInviteCode returns the player's invite code in the event, storing
+	// code first if they have none yet.
+	SaveInviteCode(ctx context.Context, eventID string, userID string, code string) (string, error)
+	// GetInviter returns the player who was issued code in the event. It
+	// fails with ErrInviteCodeNotFound.
+	GetInviter(ctx context.Context, eventID string, code string) (string, error)
+
+	// CreateTurnRequest stores a pending request. It fails with
+	// ErrTurnRequestPending when the same friend was already asked.
+	CreateTurnRequest(ctx context.Context, request TurnRequest) (TurnRequest, error)
+	GetTurnRequest(ctx context.Context, id string) (TurnRequest, error)
+	GetPendingTurnRequestsByUser(ctx context.Context, eventID string, userID string) ([]TurnRequest, error)
+	// AcceptTurnRequest moves one turn from the friend to the requester and
+	// closes the request in one transaction.
+	AcceptTurnRequest(ctx context.Context, id string) (TurnRequest, error)
+	// CloseTurnRequest moves a pending request to status.
+	CloseTurnRequest(ctx context.Context, id string, status string) error
+}
@@ -5,23 +5,53 @@ import (
 	"time"
 )
 
+// User statuses. Players who sign themselves up stay pending until they
+// prove they own the email address or phone they gave.
+const (
+	UserActive              = "active"
+	UserInactive            = "inactive"
+	UserPendingVerification = "pending_verification"
+)
+
 type User struct {
-	ID        string    `db:"id" json:"id,omitempty"`
-	Name      string    `db:"name" json:"name"`
-	Username  string    `db:"username" json:"username"`
-	Password  string    `db:"password" json:"password"`
-	Email     string    `db:"email" json:"email"`
-	Phone     string    `db:"phone" json:"phone"`
-	Role      string    `db:"role" json:"role,omitempty"`
-	CreatedAt time.Time `db:"created_at" json:"created_at,omitempty"`
-	UpdatedAt time.Time `db:"updated_at" json:"updated_at,omitempty"`
-	Status    string    `db:"status" json:"status,omitempty"`
+	ID           string    `db:"id" json:"id,omitempty"`
+	Name         string    `db:"name" json:"name"`
+	Username     string    `db:"username" json:"username"`
+	Password     string    `db:"password" json:"-"`
+	Email        string    `db:"email" json:"email"`
+	Phone        string    `db:"phone" json:"phone"`
+	Role         string    `db:"role" json:"role,omitempty"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at,omitempty"`
+	Status       string    `db:"status" json:"status,omitempty"`
+	EnterpriseID string    `db:"enterprise_id" json:"enterprise_id,omitempty"`
+}
+
+// Enterprise returns the ID of the enterprise the user acts for, if any.
+// Enterprise accounts act for themselves; staff accounts carry EnterpriseID.
+func (u User) Enterprise() string {
+	if u.EnterpriseID != "" {
+		return u.EnterpriseID
+	}
+	if u.Role == "enterprise" {
+		return u.ID
+	}
+	return ""
 }
 
 type UserRepository interface {
 	GetAllUsers(ctx context.Context) ([]User, error)
 	GetUserById(ctx context.Context, id string) (User, error)
-	CreateUser(ctx context.Context, user User) error
+	GetUserByUsername(ctx context.Context, username string) (User, error)
+	// GetUserByContact finds a user by username, email or phone, preferring
+	// a username match. It returns ErrUserNotFound when none matches.
+	GetUserByContact(ctx context.Context, contact string) (User, error)
+	// CreateUser returns the ID of the new user. It fails with ErrUserExists
+	// when the username, email or phone is taken.
+	CreateUser(ctx context.Context, user User) (string, error)
 	UpdateUser(ctx context.Context, user User) error
 	DeleteUser(ctx context.Context, id string) error
+	// DeletePendingUsers deletes the accounts that were created more than
+	// ttl ago and never verified, along with their codes.
+	DeletePendingUsers(ctx context.Context, ttl time.Duration) (int64, error)
 }
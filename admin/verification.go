@@ -0,0 +1,92 @@
+package admin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Purposes a one-time code is sent for.
+const (
+	PurposeVerify        = "verify"
+	PurposePasswordReset = "password_reset"
+	PurposeMFAChallenge  = "mfa_challenge"
+)
+
+const (
+	// OTPLength is the number of digits of a one-time code.
+	OTPLength = 6
+	// OTPTTL is how long a code can be used for.
+	OTPTTL = 10 * time.Minute
+	// OTPResendInterval is how long a user must wait before another code is
+	// sent, and OTPMaxPerHour how many can be sent in an hour.
+	OTPResendInterval = time.Minute
+	OTPMaxPerHour     = 5
+	// OTPMaxAttempts is how many wrong guesses a code survives.
+	OTPMaxAttempts = 5
+	// PasswordResetTTL is how long a password reset token can be used for.
+	PasswordResetTTL = 15 * time.Minute
+	// PendingUserTTL is how long an account that was never verified holds on
+	// to its username, email and phone before it is deleted.
+	PendingUserTTL = 24 * time.Hour
+)
+
+// OneTimeCode is a code sent to a user to prove they own an email address or
+// phone. Only its hash is stored, and sending a new code for the same
+// purpose replaces the old one.
+type OneTimeCode struct {
+	ID        string     `db:"id" json:"id"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UserID    string     `db:"user_id" json:"user_id"`
+	Purpose   string     `db:"purpose" json:"purpose"`
+	Channel   string     `db:"channel" json:"channel"`
+	CodeHash  string     `db:"code_hash" json:"-"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	Attempts  int        `db:"attempts" json:"attempts"`
+	UsedAt    *time.Time `db:"used_at" json:"used_at,omitempty"`
+}
+
+// GenerateOTP returns a random numeric code of OTPLength digits.
+func GenerateOTP() (string, error) {
+	return CodeFormat{Length: OTPLength, Charset: CharsetNumeric, Checksum: ChecksumNone}.Generate()
+}
+
+// HashOTP returns what is stored for a code sent to userID. Salting with the
+// user keeps equal codes of different users apart.
+func HashOTP(userID string, code string) string {
+	sum := sha256.Sum256([]byte(userID + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+// Registration is what a player gets back from signing up or asking for
+// another code.
+type Registration struct {
+	UserID      string    `json:"user_id"`
+	Status      string    `json:"status"`
+	Channel     string    `json:"channel"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	ResendAfter time.Time `json:"resend_after"`
+}
+
+type OneTimeCodeRepository interface {
+	// CreateCode stores code, valid for ttl, replacing any code of the same
+	// user and purpose that was not used yet. It fails with ErrCodeThrottled when the
+	// last code went out less than resendAfter ago, or maxPerHour codes went
+	// out in the last hour.
+	CreateCode(ctx context.Context, code OneTimeCode, ttl time.Duration, resendAfter time.Duration, maxPerHour int) (OneTimeCode, error)
+	// UseCode consumes the user's live code for purpose if its hash is hash.
+	// Every wrong guess counts against the code, which stops working after
+	// maxAttempts of them. It fails with ErrInvalidCode.
+	UseCode(ctx context.Context, userID string, purpose string, hash string, maxAttempts int) error
+	// UseToken consumes the live code for purpose whose hash is hash and
+	// returns its user. Tokens are long enough not to be guessed, so they are
+	// looked up by hash alone. It fails with ErrInvalidCode.
+	UseToken(ctx context.Context, purpose string, hash string) (string, error)
+	// GetToken returns the live code for purpose whose hash is hash, without
+	// using it. It fails with ErrInvalidCode, also once the code has had
+	// maxAttempts failures.
+	GetToken(ctx context.Context, purpose string, hash string, maxAttempts int) (OneTimeCode, error)
+	// FailCode counts a failed attempt against the code.
+	FailCode(ctx context.Context, id string) error
+}
@@ -5,25 +5,75 @@ import (
 	"time"
 )
 
-type Voucher struct {
-	ID          string    `db:"id" json:"id,omitempty"`
-	Code        string    `db:"code" json:"code"`
-	Qrcode      string    `db:"qrcode" json:"qrcode"`
-	Images      string    `db:"images" json:"images"`
-	Value       int       `db:"value" json:"value"`
-	Description string    `db:"description" json:"description"`
+// Voucher statuses. Vouchers issued from a template start out active and
+// become redeemed once used at a store, or expired once past ExpiredTime.
+const (
+	VoucherActive   = "active"
+	VoucherInactive = "inactive"
+	VoucherRedeemed = "redeemed"
+	VoucherExpired  = "expired"
+)
+
+// Kinds of expiry reminders sent to voucher owners.
+const (
+	Reminder3Days = "3d"
+	Reminder24h   = "24h"
+)
+
+// ExpiryReminder tells the owner of a voucher that it is about to expire.
+// Reminders are stored as an outbox other services consume; each kind is
+// emitted at most once per voucher.
+type ExpiryReminder struct {
+	ID          string    `db:"id" json:"id"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	VoucherID   string    `db:"voucher_id" json:"voucher_id"`
+	OwnerID     string    `db:"owner_id" json:"owner_id"`
+	Kind        string    `db:"kind" json:"kind"`
 	ExpiredTime time.Time `db:"expired_time" json:"expired_time"`
-	Status      string    `db:"status" json:"status"`
-	EventID     string    `db:"event_id" json:"event_id"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at,omitempty"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updated_at,omitempty"`
+}
+
+type Voucher struct {
+	ID          string        `db:"id" json:"id,omitempty"`
+	Code        string        `db:"code" json:"code"`
+	Qrcode      string        `db:"qrcode" json:"qrcode"`
+	Images      string        `db:"images" json:"images"`
+	Value       int           `db:"value" json:"value"`
+	Description string        `db:"description" json:"description"`
+	ExpiredTime time.Time     `db:"expired_time" json:"expired_time"`
+	Status      string        `db:"status" json:"status"`
+	EventID     string        `db:"event_id" json:"event_id"`
+	TemplateID  string        `db:"template_id" json:"template_id,omitempty"`
+	OwnerID     string        `db:"owner_id" json:"owner_id,omitempty"`
+	BatchID     string        `db:"batch_id" json:"batch_id,omitempty"`
+	RedeemedAt  *time.Time    `db:"redeemed_at" json:"redeemed_at,omitempty"`
+	RedeemedBy  string        `db:"redeemed_by" json:"redeemed_by,omitempty"`
+	StoreID     string        `db:"store_id" json:"store_id,omitempty"`
+	Rule        *DiscountRule `db:"rule" json:"rule,omitempty"`
+	CreatedAt   time.Time     `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt   time.Time     `db:"updated_at" json:"updated_at,omitempty"`
 }
 
 type VoucherRepository interface {
 	// GetAllVouchers(ctx context.Context) ([]Voucher, error)
 	GetAllVouchersByEventID(ctx context.Context, eventID string) ([]Voucher, error)
 	GetVoucherByID(ctx context.Context, id string, eventID string) (Voucher, error)
+	// GetVoucher looks a voucher up by ID alone. It returns ErrVoucherNotFound
+	// when there is none.
+	GetVoucher(ctx context.Context, id string) (Voucher, error)
+	// GetOwnedVouchers returns up to q.Limit vouchers of q.OwnerID ordered by
+	// expired_time and id, starting after (q.AfterExpiry, q.AfterID) if set.
+	GetOwnedVouchers(ctx context.Context, q OwnedVoucherQuery) ([]Voucher, error)
+	// CreateVoucher fails with ErrInventoryExceeded when the event has no
+	// room left in its voucher_num.
 	CreateVoucher(ctx context.Context, voucher Voucher) error
+	// UpdateVoucher fails with ErrVoucherNotEditable when the voucher is
+	// redeemed, expired or owned by a player.
 	UpdateVoucher(ctx context.Context, voucher Voucher) error
 	DeleteVoucher(ctx context.Context, id string, eventID string) error
+	// ExpireVouchers moves up to limit active vouchers past their expiry to
+	// expired and returns how many it moved.
+	ExpireVouchers(ctx context.Context, limit int) (int64, error)
+	// QueueExpiryReminders emits a reminder of kind for up to limit owned,
+	// active vouchers expiring between from and to that have not had one yet.
+	QueueExpiryReminders(ctx context.Context, kind string, from time.Time, to time.Time, limit int) ([]ExpiryReminder, error)
 }
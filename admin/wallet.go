@@ -0,0 +1,73 @@
+package admin
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+const (
+	// DefWalletLimit and MaxWalletLimit bound a page of the wallet.
+	DefWalletLimit = 50
+	MaxWalletLimit = 200
+)
+
+// ErrInvalidCursor indicates a wallet cursor that was not issued by the
+// service.
+var ErrInvalidCursor = errors.Wrap(errors.ErrMalformedEntity, errors.New("invalid cursor"))
+
+// WalletFilter narrows down the vouchers a user owns. Zero values match
+// everything.
+type WalletFilter struct {
+	Status        string
+	EventID       string
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+	Cursor        string
+	Limit         int
+}
+
+// WalletPage is one page of a user's vouchers, soonest expiring first. Next
+// is the cursor of the following page, empty on the last one.
+type WalletPage struct {
+	Vouchers []Voucher `json:"vouchers"`
+	Next     string    `json:"next,omitempty"`
+}
+
+// OwnedVoucherQuery is what a wallet page is read with. Pages are keyset
+// paginated on (expired_time, id) so deep pages cost as much as the first.
+type OwnedVoucherQuery struct {
+	OwnerID       string
+	Status        string
+	EventID       string
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+	AfterExpiry   time.Time
+	AfterID       string
+	Limit         int
+}
+
+// encodeWalletCursor returns the cursor of the page after v.
+func encodeWalletCursor(v Voucher) string {
+	raw := strconv.FormatInt(v.ExpiredTime.UnixNano(), 10) + "|" + v.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeWalletCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return time.Unix(0, nanos).UTC(), parts[1], nil
+}
@@ -1,17 +1,34 @@
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/resrrdttrt/VOU/admin"
 	thhttpapi "github.com/resrrdttrt/VOU/admin/api/http"
+	"github.com/resrrdttrt/VOU/admin/argon2"
+	"github.com/resrrdttrt/VOU/admin/bcrypt"
+	vsigner "github.com/resrrdttrt/VOU/admin/ed25519"
+	"github.com/resrrdttrt/VOU/admin/file"
+	"github.com/resrrdttrt/VOU/admin/jwt"
+	"github.com/resrrdttrt/VOU/admin/memory"
 	"github.com/resrrdttrt/VOU/admin/postgres"
+	"github.com/resrrdttrt/VOU/admin/qrcode"
+	"github.com/resrrdttrt/VOU/admin/smtp"
+	"github.com/resrrdttrt/VOU/pkg/auth"
 	"github.com/resrrdttrt/VOU/pkg/common"
 	"github.com/resrrdttrt/VOU/pkg/db"
 	"github.com/resrrdttrt/VOU/pkg/logger"
@@ -34,6 +51,39 @@ const (
 	DefSSLKey      = ""
 	DefSSLRootCert = ""
 
+	DefPasswordHasher = "bcrypt"
+	DefBcryptCost     = "10"
+
+	DefAccessTokenTTL     = "15m"
+	DefRefreshTokenTTL    = "720h"
+	DefTokenPurgeInterval = "1h"
+	DefRevocationSync     = "10s"
+	DefTokenBackend       = "opaque"
+	DefJWTKeys            = ""
+	DefJWTActiveKID       = ""
+	DefPolicyFile         = ""
+	DefEventTickInterval  = "1m"
+	DefVoucherSigningKey  = ""
+	DefVoucherSweep       = "1m"
+	DefLiveQuizTick       = "5s"
+	DefReplicaURL         = ""
+
+	DefNotifier     = "log"
+	DefNotifierFile = "notifications.log"
+	DefSMTPHost     = "localhost"
+	DefSMTPPort     = "25"
+	DefSMTPUser     = ""
+	DefSMTPPass     = ""
+	DefSMTPFrom     = "no-reply@vou.local"
+
+	// jwtMaxAccessTTL caps the lifetime of JWT access tokens, which are
+	// verified without a database lookup.
+	jwtMaxAccessTTL = time.Hour
+
+	// schedulerLockKey is the Postgres advisory lock that elects the replica
+	// running the voucher scheduler.
+	schedulerLockKey = 7250001
+
 	MongoHost    = "localhost"
 	MongoUser    = "root"
 	MongoPass    = "1"
@@ -42,9 +92,26 @@ const (
 )
 
 type config struct {
-	logLevel string
-	dbConfig postgres.Config
-	httpPort string
+	logLevel       string
+	dbConfig       postgres.Config
+	httpPort       string
+	passwordHasher string
+	bcryptCost     int
+	tokens         admin.TokenConfig
+	tokenPurge     time.Duration
+	revocationSync time.Duration
+	tokenBackend   string
+	jwtKeys        string
+	jwtActiveKID   string
+	policyFile     string
+	eventTick      time.Duration
+	signingKey     string
+	voucherSweep   time.Duration
+	liveQuizTick   time.Duration
+	replicaURL     string
+	notifier       string
+	notifierFile   string
+	smtp           smtp.Config
 }
 
 func loadConfig() config {
@@ -62,11 +129,66 @@ func loadConfig() config {
 		SSLRootCert: common.Env("DB_ROOTCERT", DefSSLRootCert),
 	}
 
+	bcryptCost, err := strconv.Atoi(common.Env("BCRYPT_COST", DefBcryptCost))
+	if err != nil {
+		log.Fatalf("Invalid BCRYPT_COST: %s", err)
+	}
+
+	tokens := admin.TokenConfig{
+		AccessTTL:  envDuration("ACCESS_TOKEN_TTL", DefAccessTokenTTL),
+		RefreshTTL: envDuration("REFRESH_TOKEN_TTL", DefRefreshTokenTTL),
+	}
+
+	// Other replicas forward live quiz traffic to this URL, which defaults
+	// to the host name and HTTP port.
+	httpPort := common.Env("HTTP_PORT", DefHTTPPort)
+	replicaURL := common.Env("REPLICA_URL", DefReplicaURL)
+	if replicaURL == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			log.Fatalf("Failed to resolve REPLICA_URL: %s", err)
+		}
+		replicaURL = fmt.Sprintf("http://%s:%s", hostname, httpPort)
+	}
+
+	smtpConfig := smtp.Config{
+		Host:     common.Env("SMTP_HOST", DefSMTPHost),
+		Port:     common.Env("SMTP_PORT", DefSMTPPort),
+		Username: common.Env("SMTP_USER", DefSMTPUser),
+		Password: common.Env("SMTP_PASS", DefSMTPPass),
+		From:     common.Env("SMTP_FROM", DefSMTPFrom),
+	}
+
 	return config{
-		logLevel: common.Env("LOG_LEVEL", DefLogLevel),
-		dbConfig: dbConfig,
-		httpPort: common.Env("HTTP_PORT", DefHTTPPort),
+		logLevel:       common.Env("LOG_LEVEL", DefLogLevel),
+		dbConfig:       dbConfig,
+		httpPort:       httpPort,
+		passwordHasher: common.Env("PASSWORD_HASHER", DefPasswordHasher),
+		bcryptCost:     bcryptCost,
+		tokens:         tokens,
+		tokenPurge:     envDuration("TOKEN_PURGE_INTERVAL", DefTokenPurgeInterval),
+		revocationSync: envDuration("REVOCATION_SYNC_INTERVAL", DefRevocationSync),
+		tokenBackend:   common.Env("TOKEN_BACKEND", DefTokenBackend),
+		jwtKeys:        common.Env("JWT_KEYS", DefJWTKeys),
+		jwtActiveKID:   common.Env("JWT_ACTIVE_KID", DefJWTActiveKID),
+		policyFile:     common.Env("RBAC_POLICY_FILE", DefPolicyFile),
+		eventTick:      envDuration("EVENT_TICK_INTERVAL", DefEventTickInterval),
+		signingKey:     common.Env("VOUCHER_SIGNING_KEY", DefVoucherSigningKey),
+		voucherSweep:   envDuration("VOUCHER_SWEEP_INTERVAL", DefVoucherSweep),
+		liveQuizTick:   envDuration("LIVE_QUIZ_TICK_INTERVAL", DefLiveQuizTick),
+		replicaURL:     replicaURL,
+		notifier:       common.Env("NOTIFIER", DefNotifier),
+		notifierFile:   common.Env("NOTIFIER_FILE", DefNotifierFile),
+		smtp:           smtpConfig,
+	}
+}
+
+func envDuration(key, fallback string) time.Duration {
+	d, err := time.ParseDuration(common.Env(key, fallback))
+	if err != nil {
+		log.Fatalf("Invalid %s: %s", key, err)
 	}
+	return d
 }
 
 func main() {
@@ -91,9 +213,31 @@ func main() {
 	// commonMongo := db.NewMongoTransactions(mongoDriver)
 	// svc := newService(logging, rdb, wdb, commonMongo)
 
-	svc := newService(logging, rdb, wdb)
+	svc := newService(cfg, logging, rdb, wdb)
+	policy := loadPolicy(cfg, logging)
 	errs := make(chan error)
-	go startHTTPServer(thhttpapi.MakeHandler(svc), cfg, logging, make(chan error))
+	// JWT access tokens are checked against a denylist each replica reloads
+	// on its own; load it before serving so no revoked token slips through.
+	if _, err := svc.SyncRevokedTokens(context.Background()); err != nil {
+		logging.Error(fmt.Sprintf("Failed to load revoked access tokens: %s", err))
+		os.Exit(1)
+	}
+	go runPeriodically("purge access tokens", cfg.tokenPurge, svc.PurgeAccessTokens, logging)
+	go runPeriodically("sync revoked access tokens", cfg.revocationSync, svc.SyncRevokedTokens, logging)
+	go runPeriodically("advance event statuses", cfg.eventTick, svc.AdvanceEvents, logging)
+	leader := postgres.NewLeader(wdb, schedulerLockKey)
+	defer leader.Resign(context.Background())
+	go runPeriodically("expire vouchers", cfg.voucherSweep, leaderOnly(leader, svc.ExpireVouchers), logging)
+	go runPeriodically("send expiry reminders", cfg.voucherSweep, leaderOnly(leader, svc.SendExpiryReminders), logging)
+	go runPeriodically("recover voucher batches", cfg.voucherSweep, leaderOnly(leader, svc.RecoverVoucherBatches), logging)
+	go runPeriodically("purge unverified users", cfg.tokenPurge, leaderOnly(leader, svc.PurgeUnverifiedUsers), logging)
+	go runPeriodically("freeze final standings", cfg.eventTick, leaderOnly(leader, svc.FreezeStandings), logging)
+	// Live quiz sessions are kept in memory by the replica that opened them;
+	// the others forward players to it, and sessions whose replica died are
+	// recovered.
+	go runPeriodically("open live quizzes", cfg.liveQuizTick, leaderOnly(leader, svc.OpenLiveQuizzes), logging)
+	go runPeriodically("recover live quizzes", cfg.liveQuizTick, leaderOnly(leader, svc.RecoverLiveQuizzes), logging)
+	go startHTTPServer(thhttpapi.MakeHandler(svc, policy), cfg, logging, make(chan error))
 	go func() {
 		c := make(chan os.Signal, 1)
 		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
@@ -104,17 +248,213 @@ func main() {
 
 }
 
-func newService(logger logger.Logger, rdb *sqlx.DB, wdb *sqlx.DB) admin.Service {
+func newService(cfg config, logger logger.Logger, rdb *sqlx.DB, wdb *sqlx.DB) admin.Service {
 	database := db.NewReadWrite(rdb, wdb)
-	userRepo := postgres.NewUserRepository(database, logger)
-	gameRepo := postgres.NewGameRepository(database, logger)
-	statisticRepo := postgres.NewStatisticRepository(database, logger)
 	authRepo := postgres.NewAuthRepository(database, logger)
-	enterpriseRepo := postgres.NewEnterpriseRepository(database, logger)
-	eventRepo := postgres.NewEventRepository(database, logger)
-	voucherRepo := postgres.NewVoucherRepository(database, logger)
-	svc := admin.NewAdminService(logger, userRepo, gameRepo, statisticRepo, authRepo, enterpriseRepo, eventRepo, voucherRepo)
-	return svc
+	deps := admin.Deps{
+		Users:       postgres.NewUserRepository(database, logger),
+		Games:       postgres.NewGameRepository(database, logger),
+		Statistic:   postgres.NewStatisticRepository(database, logger),
+		Auth:        authRepo,
+		Enterprise:  postgres.NewEnterpriseRepository(database, logger),
+		Event:       postgres.NewEventRepository(database, logger),
+		Voucher:     postgres.NewVoucherRepository(database, logger),
+		Inventory:   postgres.NewInventoryRepository(database, logger),
+		Batches:     postgres.NewVoucherBatchRepository(database, logger),
+		Signer:      newVoucherSigner(cfg, logger),
+		QR:          qrcode.New(),
+		Redemptions: postgres.NewRedemptionRepository(database, logger),
+		Transfers:   postgres.NewTransferRepository(database, logger),
+		Quiz:        postgres.NewQuizRepository(database, logger),
+		Live:        memory.NewLiveSessionStore(),
+		Hub:         memory.NewLiveHub(),
+		Draws:       postgres.NewDrawRepository(database, logger),
+		Turns:       postgres.NewTurnRepository(database, logger),
+		Items:       postgres.NewItemRepository(database, logger),
+		Scores:      postgres.NewLeaderboardRepository(database, logger),
+		Codes:       postgres.NewOneTimeCodeRepository(database, logger),
+		Notifier:    newNotifier(cfg, logger),
+		MFA:         postgres.NewMFARepository(database, logger),
+		Hasher:      newHasher(cfg, logger),
+		Hashers:     []admin.PasswordHasher{bcrypt.New(cfg.bcryptCost), argon2.New(argon2.DefTime, argon2.DefMemory, argon2.DefThreads)},
+		Tokens:      cfg.tokens,
+		Issuer:      newIssuer(cfg, authRepo, logger),
+		Replica:     cfg.replicaURL,
+	}
+	return admin.NewAdminService(logger, deps)
+}
+
+func loadPolicy(cfg config, logger logger.Logger) auth.Policy {
+	if cfg.policyFile == "" {
+		return auth.DefaultPolicy()
+	}
+	policy, err := auth.LoadPolicy(cfg.policyFile)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to load RBAC policy: %s", err))
+		os.Exit(1)
+	}
+	return policy
+}
+
+func newIssuer(cfg config, authRepo admin.AuthRepository, logger logger.Logger) admin.TokenIssuer {
+	switch cfg.tokenBackend {
+	case "opaque":
+		return admin.NewOpaqueIssuer(authRepo)
+	case "jwt":
+		// A revoked JWT keeps working on a replica until its denylist syncs,
+		// and the denylist holds every token revoked within one lifetime.
+		if cfg.tokens.AccessTTL > jwtMaxAccessTTL {
+			logger.Error(fmt.Sprintf("ACCESS_TOKEN_TTL must not exceed %s with JWT access tokens", jwtMaxAccessTTL))
+			os.Exit(1)
+		}
+		keys, err := parseJWTKeys(cfg.jwtKeys)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to load JWT keys: %s", err))
+			os.Exit(1)
+		}
+		issuer, err := jwt.New(keys, cfg.jwtActiveKID, authRepo)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to create JWT issuer: %s", err))
+			os.Exit(1)
+		}
+		return issuer
+	default:
+		logger.Error(fmt.Sprintf("Unknown token backend: %s", cfg.tokenBackend))
+		os.Exit(1)
+	}
+	return nil
+}
+
+// parseJWTKeys parses JWT_KEYS, a comma separated list of kid:alg:value
+// entries. The value is the shared secret for HS256 and the path to a PEM
+// encoded RSA private key for RS256.
+func parseJWTKeys(raw string) ([]jwt.Key, error) {
+	var keys []jwt.Key
+	for _, entry := range strings.Split(raw, ",") {
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("malformed key entry %q", entry)
+		}
+		key := jwt.Key{ID: parts[0], Alg: parts[1]}
+		switch key.Alg {
+		case jwt.HS256:
+			key.Secret = []byte(parts[2])
+		case jwt.RS256:
+			data, err := os.ReadFile(parts[2])
+			if err != nil {
+				return nil, err
+			}
+			block, _ := pem.Decode(data)
+			if block == nil {
+				return nil, fmt.Errorf("no PEM data in %s", parts[2])
+			}
+			pk, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				parsed, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+				rsaKey, ok := parsed.(*rsa.PrivateKey)
+				if err8 != nil || !ok {
+					return nil, err
+				}
+				pk = rsaKey
+			}
+			key.PrivateKey = pk
+		default:
+			return nil, fmt.Errorf("unsupported algorithm %q for key %s", key.Alg, key.ID)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// newVoucherSigner loads the Ed25519 key from the PKCS8 PEM file named by
+// VOUCHER_SIGNING_KEY. The key is required: every replica must sign with the
+// same key, and QR codes must keep verifying across restarts.
+func newVoucherSigner(cfg config, logger logger.Logger) admin.VoucherSigner {
+	if cfg.signingKey == "" {
+		logger.Error("VOUCHER_SIGNING_KEY is not set")
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(cfg.signingKey)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to read voucher signing key: %s", err))
+		os.Exit(1)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		logger.Error(fmt.Sprintf("No PEM data in %s", cfg.signingKey))
+		os.Exit(1)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	key, ok := parsed.(ed25519.PrivateKey)
+	if err != nil || !ok {
+		logger.Error(fmt.Sprintf("%s is not a PKCS8 Ed25519 private key", cfg.signingKey))
+		os.Exit(1)
+	}
+	return vsigner.New(key)
+}
+
+func newHasher(cfg config, logger logger.Logger) admin.PasswordHasher {
+	switch cfg.passwordHasher {
+	case "bcrypt":
+		return bcrypt.New(cfg.bcryptCost)
+	case "argon2id":
+		return argon2.New(argon2.DefTime, argon2.DefMemory, argon2.DefThreads)
+	default:
+		logger.Error(fmt.Sprintf("Unknown password hasher: %s", cfg.passwordHasher))
+		os.Exit(1)
+	}
+	return nil
+}
+
+// newNotifier picks how codes reach users. "log" prints them with the
+// service output and "file" appends them to NOTIFIER_FILE; both are meant for
+// local runs.
+func newNotifier(cfg config, logger logger.Logger) admin.Notifier {
+	switch cfg.notifier {
+	case "log":
+		return file.New(os.Stdout)
+	case "file":
+		f, err := os.OpenFile(cfg.notifierFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to open notifier file: %s", err))
+			os.Exit(1)
+		}
+		return file.New(f)
+	case "smtp":
+		return smtp.New(cfg.smtp)
+	default:
+		logger.Error(fmt.Sprintf("Unknown notifier: %s", cfg.notifier))
+		os.Exit(1)
+	}
+	return nil
+}
+
+// runPeriodically calls job every interval. Jobs report how many rows they
+// touched.
+func runPeriodically(name string, interval time.Duration, job func(context.Context) (int64, error), logger logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		n, err := job(context.Background())
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to %s: %s", name, err))
+			continue
+		}
+		logger.Debug(fmt.Sprintf("%s: %d rows affected", name, n))
+	}
+}
+
+// leaderOnly wraps job so it only runs on the replica elected by leader.
+func leaderOnly(leader *postgres.Leader, job func(context.Context) (int64, error)) func(context.Context) (int64, error) {
+	return func(ctx context.Context) (int64, error) {
+		if !leader.IsLeader(ctx) {
+			return 0, nil
+		}
+		return job(ctx)
+	}
 }
 
 func startHTTPServer(handler http.Handler, cfg config, logger logger.Logger, errs chan error) {
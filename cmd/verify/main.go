@@ -0,0 +1,58 @@
+// Command verify checks a scanned voucher QR payload offline, using only the
+// public key published by the admin service.
+//
+//	verify -key <base64 public key> <payload>
+//
+// The payload is read from stdin when no argument is given. The command
+// prints the voucher claims and exits non-zero when the payload is forged,
+// modified or expired.
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	vsigner "github.com/resrrdttrt/VOU/admin/ed25519"
+)
+
+func main() {
+	key := flag.String("key", os.Getenv("VOUCHER_PUBLIC_KEY"), "base64 encoded Ed25519 public key")
+	flag.Parse()
+
+	pub, err := base64.StdEncoding.DecodeString(*key)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		fail("invalid public key")
+	}
+
+	payload := flag.Arg(0)
+	if payload == "" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			fail("missing payload")
+		}
+		payload = strings.TrimSpace(line)
+	}
+
+	claims, err := vsigner.NewVerifier(ed25519.PublicKey(pub)).Verify(payload)
+	if err != nil {
+		fail(err.Error())
+	}
+	out, _ := json.MarshalIndent(claims, "", "  ")
+	fmt.Println(string(out))
+	if claims.Expired(time.Now()) {
+		fail("voucher expired at " + time.Unix(claims.ExpiresAt, 0).UTC().Format(time.RFC3339))
+	}
+	fmt.Println("valid")
+}
+
+func fail(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(1)
+}
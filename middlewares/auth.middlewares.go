@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/resrrdttrt/VOU/admin"
+	"github.com/resrrdttrt/VOU/pkg/auth"
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+// Authenticate resolves the Authorization header to a principal and attaches
+// it to the request context.
+func Authenticate(svc admin.Service, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessToken := r.Header.Get("Authorization")
+		if accessToken == "" {
+			writeError(w, http.StatusUnauthorized, "Authorization header is required")
+			return
+		}
+		p, err := svc.Identify(r.Context(), accessToken)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		r = r.WithContext(auth.WithPrincipal(r.Context(), p))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WebSocketToken lets WebSocket handshakes carry the access token in the
+// `access_token` query parameter, since browsers cannot set headers on them.
+// Other requests must still use the Authorization header.
+func WebSocketToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			if token := r.URL.Query().Get("access_token"); token != "" {
+				r.Header.Set("Authorization", token)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Authorize lets the request through only if the principal's role is granted
+// perm by policy. It must run behind Authenticate.
+func Authorize(policy auth.Policy, perm auth.Permission, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := auth.PrincipalFrom(r.Context())
+		if !ok {
+			writeError(w, http.StatusUnauthorized, errors.ErrUnauthorized.Msg())
+			return
+		}
+		if !policy.Allows(p.Role, perm) {
+			writeError(w, http.StatusForbidden, errors.ErrForbidden.Msg())
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(errors.ErrorBody{Message: msg, Code: code})
+}
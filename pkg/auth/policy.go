@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Permission names an action on a resource, e.g. `events:write`.
+type Permission string
+
+// Wildcard grants every permission.
+const Wildcard Permission = "*"
+
+const (
+	UsersRead   Permission = "users:read"
+	UsersWrite  Permission = "users:write"
+	UsersDelete Permission = "users:delete"
+
+	GamesRead   Permission = "games:read"
+	GamesWrite  Permission = "games:write"
+	GamesDelete Permission = "games:delete"
+
+	StatisticsRead Permission = "statistics:read"
+
+	EnterpriseRead  Permission = "enterprise:read"
+	EnterpriseWrite Permission = "enterprise:write"
+
+	EventsRead   Permission = "events:read"
+	EventsWrite  Permission = "events:write"
+	EventsReview Permission = "events:review"
+
+	VouchersRead   Permission = "vouchers:read"
+	VouchersWrite  Permission = "vouchers:write"
+	VouchersDelete Permission = "vouchers:delete"
+	VouchersRedeem Permission = "vouchers:redeem"
+
+	WalletRead  Permission = "wallet:read"
+	WalletWrite Permission = "wallet:write"
+
+	QuizRead  Permission = "quiz:read"
+	QuizWrite Permission = "quiz:write"
+	QuizPlay  Permission = "quiz:play"
+
+	ShakePlay Permission = "shake:play"
+
+	TurnsRead  Permission = "turns:read"
+	TurnsWrite Permission = "turns:write"
+
+	ItemsRead  Permission = "items:read"
+	ItemsWrite Permission = "items:write"
+
+	LeaderboardRead Permission = "leaderboard:read"
+
+	AccountMFA Permission = "account:mfa"
+
+	SecurityRead  Permission = "security:read"
+	SecurityWrite Permission = "security:write"
+)
+
+// Roles known to the system.
+const (
+	RoleAdmin           = "admin"
+	RoleEnterprise      = "enterprise"
+	RoleEnterpriseStaff = "enterprise_staff"
+	RoleEndUser         = "end_user"
+)
+
+// Policy maps a role to the permissions it is granted.
+type Policy map[string][]Permission
+
+// DefaultPolicy is used when no policy file is configured.
+func DefaultPolicy() Policy {
+	return Policy{
+		RoleAdmin: {Wildcard},
+		RoleEnterprise: {
+			GamesRead,
+			EnterpriseRead, EnterpriseWrite,
+			EventsRead, EventsWrite,
+			VouchersRead, VouchersWrite, VouchersDelete, VouchersRedeem,
+			QuizRead, QuizWrite,
+			AccountMFA,
+		},
+		RoleEnterpriseStaff: {
+			GamesRead,
+			EnterpriseRead,
+			EventsRead,
+			VouchersRead, VouchersRedeem,
+			QuizRead,
+			AccountMFA,
+		},
+		RoleEndUser: {
+			WalletRead, WalletWrite,
+			QuizPlay,
+			ShakePlay,
+			TurnsRead, TurnsWrite,
+			ItemsRead, ItemsWrite,
+			LeaderboardRead,
+		},
+	}
+}
+
+// LoadPolicy reads a policy from a JSON file of the form
+// {"role": ["resource:action", ...]}.
+func LoadPolicy(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Allows reports whether role is granted perm.
+func (p Policy) Allows(role string, perm Permission) bool {
+	for _, granted := range p[role] {
+		if granted == perm || granted == Wildcard {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+// Package auth carries the authenticated caller through request contexts.
+package auth
+
+import (
+	"context"
+
+	"github.com/resrrdttrt/VOU/pkg/errors"
+)
+
+// ErrUnauthenticated indicates that no principal is attached to the context.
+var ErrUnauthenticated = errors.Wrap(errors.ErrUnauthorized, errors.New("request is not authenticated"))
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID       string
+	Role         string
+	EnterpriseID string
+	// TokenID identifies the stored token row and is used for revocation.
+	TokenID string
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal attached to ctx, if any.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	if !ok || p.UserID == "" {
+		return Principal{}, false
+	}
+	return p, true
+}
@@ -35,6 +35,9 @@ var (
 	// ErrConflict indicates that entity already exists.
 	ErrConflict = Make("entity already exists", 2004)
 
+	// ErrTooManyRequests indicates a client that must wait before trying again.
+	ErrTooManyRequests = Make("too many requests", 2005)
+
 	ErrIdentifyProject = Make("Failed to identify project", 2055)
 
 	ErrInvalidId = Make("Invalid ID", 1001)
@@ -2,5 +2,5 @@ INSERT INTO users (name, username, password, email, phone, role, status)
 VALUES ('TungDuong', 'tungduong', 'securepassword', 'tungduong@example.com', '123-456-7890', 'admin', 'active');
 
 
-INSERT INTO access_tokens (token, user_id)
-VALUES ('1', 'b64b13cf-c5fe-411e-9761-39e5d8232dea');
+INSERT INTO access_tokens (token, user_id, expires_at, refresh_token, refresh_expires_at)
+VALUES ('1', 'b64b13cf-c5fe-411e-9761-39e5d8232dea', NOW() + INTERVAL '30 days', '1-refresh', NOW() + INTERVAL '30 days');